@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
-	"strconv"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/tucnak/telebot"
@@ -15,20 +20,39 @@ import (
 	"expense_accounting_bot/pkg/repository"
 )
 
+// Время на завершение обработки обновлений при остановке; должно превышать таймаут long polling
+const shutdownTimeout = 20 * time.Second
+
+// Параметры подключения к SQLite: обработчики пишут в базу одновременно, а SQLite допускает одну запись за раз,
+// поэтому соединение ждет снятия блокировки, а транзакции сразу захватывают запись и не упираются в SQLITE_BUSY
+// при переходе от чтения к записи
+const sqliteParams = "_pragma=busy_timeout(5000)&_txlock=immediate"
+
 func main() {
 	// Загружаем конфигурацию
 	cfg := config.LoadConfig()
 
 	// Инициализируем логгер
-	logPath := "./bot.log" // Путь к файлу для логов
-	err := logger.InitLogger(logPath)
+	logCfg := logger.Config{
+		Path:       cfg.LogPath,
+		Level:      cfg.LogLevel,
+		Format:     cfg.LogFormat,
+		MaxSizeMB:  cfg.LogMaxSizeMB,
+		MaxAgeDays: cfg.LogMaxAgeDays,
+		MaxBackups: cfg.LogMaxBackups,
+		Compress:   true,
+	}
+	if cfg.LogBuffered {
+		logCfg.BufferSize = 64 << 10
+	}
+	err := logger.InitLogger(logCfg)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer logger.L.Close()
 
 	// Подключаемся к SQLite
-	db, err := sql.Open("sqlite", cfg.DatabasePath)
+	db, err := sql.Open("sqlite", sqliteDSN(cfg.DatabasePath))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -41,29 +65,104 @@ func main() {
 	}
 
 	// Инициализация бота
-	err = bot.InitStringValues()
+	err = bot.InitStringValues(config.StringValues(), cfg.StringsDir)
 	if err != nil {
 		log.Fatal("Ошибка при инициализации бота:", err)
 	}
+	if cfg.StringsDir != "" {
+		go reloadStringValues(cfg.StringsDir)
+	}
 
 	// Инициализация бота
+	// С публичным адресом обновления принимаются через webhook, иначе через long polling
+	var source telegram.UpdateSource = telegram.NewPoller(10 * time.Second)
+	var webhook *telegram.Webhook
+	if cfg.WebhookURL != "" {
+		webhook, err = telegram.NewWebhook(cfg.WebhookListen, cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookTLSCert, cfg.WebhookTLSKey)
+		if err != nil {
+			log.Fatal("Ошибка при настройке webhook: ", err)
+		}
+		source = webhook
+	}
+
+	// telebot отправляет запросы через http.Post, поэтому лимиты Telegram соблюдаются на транспорте по умолчанию
+	http.DefaultTransport = telegram.NewLimitedTransport(http.DefaultTransport)
+
 	b, err := telebot.NewBot(telebot.Settings{
 		Token:  cfg.TelegramToken,
-		Poller: &telebot.LongPoller{Timeout: 10 * time.Second},
+		Poller: source,
 	})
 	if err != nil {
 		log.Fatal("Ошибка при создании бота: ", err)
 	}
 
-	adminID, err := strconv.Atoi(cfg.AdminID)
-	if err != nil || adminID == 0 {
-		logger.L.Error("Не удалось получить adminID", err)
+	// Без установленного webhook бот не получит ни одного обновления, поэтому запускать его нет смысла
+	if webhook != nil {
+		if err = webhook.Register(b); err != nil {
+			log.Fatal("Ошибка при установке webhook: ", err)
+		}
+	}
+
+	// Владельцы из конфигурации получают роль при каждом запуске, остальные роли хранятся в базе
+	if len(cfg.OwnerIDs) == 0 {
+		logger.L.Warning("ADMIN_ID не задан, команды администрирования доступны только уже назначенным ролям")
+	}
+	for _, ownerID := range cfg.OwnerIDs {
+		if err = repo.SetUserRole(ownerID, repository.UserRoleOwner, 0); err != nil {
+			log.Fatalf("Ошибка при назначении владельца %d: %v", ownerID, err)
+		}
 	}
 
 	// Создаем объект нашего бота с логгером
-	expenseBot := telegram.NewExpenseBot(b, repo, adminID)
+	registration := telegram.Registration{Policy: cfg.RegistrationPolicy, Allowed: cfg.AllowedUserIDs}
+	expenseBot := telegram.NewExpenseBot(b, repo, registration)
+	source.OnChosenInlineResult(expenseBot.HandleChosenInlineResult)
+	source.OnUserLanguage(expenseBot.HandleUserLanguage)
+	source.OnUpdate(expenseBot.AcceptUpdate)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// Запускаем бота
-	logger.L.Info("Запуск бота...")
-	expenseBot.Start()
+	logger.L.Info("Запуск бота...", "registration", cfg.RegistrationPolicy)
+	go expenseBot.Start()
+
+	select {
+	case <-ctx.Done():
+		logger.L.Info("Получен сигнал остановки, завершаем обработку обновлений...")
+	case <-expenseBot.Done():
+		logger.L.Warning("Бот перестал получать обновления")
+	}
+
+	// После остановки отложенные вызовы закрывают базу и дописывают лог
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err = expenseBot.Stop(shutdownCtx); err != nil {
+		logger.L.Error("Ошибка при остановке бота:", err)
+	}
+	logger.L.Info("Бот остановлен")
+}
+
+// reloadStringValues перечитывает переопределенные строки бота по SIGHUP; строки с ошибками
+// не применяются, и бот продолжает работать с прежними
+func reloadStringValues(dir string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		if err := bot.ReloadStringValues(); err != nil {
+			logger.L.Error("Ошибка при перечитывании строк бота:", err, "dir", dir)
+			continue
+		}
+		logger.L.Info("Строки бота перечитаны", "dir", dir)
+	}
+}
+
+// sqliteDSN добавляет параметры подключения к пути базы, сохраняя уже указанные в нем
+func sqliteDSN(path string) string {
+	if strings.Contains(path, "?") {
+		return path + "&" + sqliteParams
+	}
+
+	return path + "?" + sqliteParams
 }
@@ -1,17 +1,68 @@
 package config
 
 import (
+	"embed"
+	"io/fs"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
+// Строки бота по умолчанию встроены в бинарник, чтобы он не зависел от рабочего каталога
+//
+//go:embed string_values
+var stringValues embed.FS
+
+// StringValues возвращает встроенные строки бота: по подкаталогу на каждый язык
+func StringValues() fs.FS {
+	values, err := fs.Sub(stringValues, "string_values")
+	if err != nil {
+		panic(err)
+	}
+
+	return values
+}
+
 // Config структура для хранения конфигурации
 type Config struct {
 	TelegramToken string
 	DatabasePath  string
-	AdminID       string
+	// Владельцы бота из ADMIN_ID (через запятую); остальные роли назначаются командой /grant
+	OwnerIDs []int
+
+	// Кто может зарегистрироваться: open — все, invite — по приглашению, allowlist — только AllowedUserIDs
+	RegistrationPolicy string
+	// Пользователи, которым разрешена регистрация в режиме allowlist
+	AllowedUserIDs []int
+
+	// Каталог, файлы которого переопределяют встроенные строки бота; перечитывается по SIGHUP
+	StringsDir string
+
+	// Публичный адрес webhook; если не задан, бот работает через long polling
+	WebhookURL string
+	// Адрес, на котором HTTP-сервер принимает обновления от Telegram
+	WebhookListen string
+	// Секрет, который Telegram передает в заголовке X-Telegram-Bot-Api-Secret-Token
+	WebhookSecret string
+	// Сертификат и ключ, если TLS завершается самим ботом, а не прокси
+	WebhookTLSCert string
+	WebhookTLSKey  string
+
+	// Файл лога и минимальный уровень записей: debug, info, warn, error
+	LogPath  string
+	LogLevel string
+	// Формат записей лога: text или json
+	LogFormat string
+	// Размер файла лога, после которого он переносится в архив, МБ
+	LogMaxSizeMB int
+	// Сколько дней и сколько штук хранить архивы логов
+	LogMaxAgeDays int
+	LogMaxBackups int
+	// Буферизовать запись логов; буфер сбрасывается раз в секунду и при остановке
+	LogBuffered bool
 }
 
 // LoadConfig загружает конфигурацию из .env файла и переменных окружения
@@ -27,7 +78,26 @@ func LoadConfig() *Config {
 	cfg := &Config{
 		TelegramToken: os.Getenv("TELEGRAM_TOKEN"),
 		DatabasePath:  os.Getenv("DATABASE_PATH"),
-		AdminID:       os.Getenv("ADMIN_ID"),
+		OwnerIDs:      envIDs("ADMIN_ID"),
+
+		RegistrationPolicy: strings.ToLower(os.Getenv("REGISTRATION_POLICY")),
+		AllowedUserIDs:     envIDs("ALLOWED_USERS"),
+
+		StringsDir: os.Getenv("STRINGS_DIR"),
+
+		WebhookURL:     os.Getenv("WEBHOOK_URL"),
+		WebhookListen:  os.Getenv("WEBHOOK_LISTEN"),
+		WebhookSecret:  os.Getenv("WEBHOOK_SECRET"),
+		WebhookTLSCert: os.Getenv("WEBHOOK_TLS_CERT"),
+		WebhookTLSKey:  os.Getenv("WEBHOOK_TLS_KEY"),
+
+		LogPath:       os.Getenv("LOG_PATH"),
+		LogLevel:      os.Getenv("LOG_LEVEL"),
+		LogFormat:     os.Getenv("LOG_FORMAT"),
+		LogMaxSizeMB:  envInt("LOG_MAX_SIZE_MB", 10),
+		LogMaxAgeDays: envInt("LOG_MAX_AGE_DAYS", 30),
+		LogMaxBackups: envInt("LOG_MAX_BACKUPS", 10),
+		LogBuffered:   envBool("LOG_BUFFERED", true),
 	}
 
 	if cfg.TelegramToken == "" {
@@ -36,9 +106,79 @@ func LoadConfig() *Config {
 	if cfg.DatabasePath == "" {
 		cfg.DatabasePath = "expenses.db"
 	}
-	if cfg.AdminID == "" {
-		cfg.AdminID = "718626004"
+	switch cfg.RegistrationPolicy {
+	case "":
+		cfg.RegistrationPolicy = "open"
+	case "open", "invite", "allowlist":
+	default:
+		log.Fatalf("REGISTRATION_POLICY должна быть open, invite или allowlist: %q", cfg.RegistrationPolicy)
+	}
+	if cfg.LogPath == "" {
+		cfg.LogPath = "./bot.log"
+	}
+	// На Railway публичный домен сервиса известен из окружения
+	if cfg.WebhookURL == "" && os.Getenv("RAILWAY_PUBLIC_DOMAIN") != "" {
+		cfg.WebhookURL = "https://" + os.Getenv("RAILWAY_PUBLIC_DOMAIN") + "/webhook"
+	}
+	if cfg.WebhookListen == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "3000"
+		}
+		cfg.WebhookListen = ":" + port
+	}
+	if (cfg.WebhookTLSCert == "") != (cfg.WebhookTLSKey == "") {
+		log.Fatal("WEBHOOK_TLS_CERT и WEBHOOK_TLS_KEY должны быть заданы вместе")
 	}
 
 	return cfg
 }
+
+// envInt читает целое неотрицательное число из переменной окружения
+func envInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Fatalf("%s должна быть неотрицательным числом: %q", key, value)
+	}
+
+	return n
+}
+
+// envBool читает флаг из переменной окружения: true/false, 1/0, yes/no
+func envBool(key string, def bool) bool {
+	switch strings.ToLower(os.Getenv(key)) {
+	case "":
+		return def
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	}
+
+	log.Fatalf("%s должна быть true или false: %q", key, os.Getenv(key))
+	return def
+}
+
+// envIDs читает список ID пользователей через запятую из переменной окружения
+func envIDs(key string) []int {
+	var ids []int
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			log.Fatalf("%s должна содержать ID пользователей через запятую: %q", key, value)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
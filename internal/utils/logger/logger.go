@@ -2,78 +2,159 @@ package logger
 
 import (
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
-	"sync"
+	"strings"
+	"time"
 )
 
-// Logger структура для логирования в файл
+// Config настройки логгера
+type Config struct {
+	// Путь к файлу лога; архивы создаются рядом с ним
+	Path string
+	// Минимальный уровень записей: debug, info, warn, error
+	Level string
+	// Формат записей: text или json
+	Format string
+	// Размер файла, после которого он переносится в архив; 0 — без ротации
+	MaxSizeMB int
+	// Сколько дней хранить архивы; 0 — без ограничения
+	MaxAgeDays int
+	// Сколько архивов хранить; 0 — без ограничения
+	MaxBackups int
+	// Сжимать ли архивы gzip
+	Compress bool
+	// Размер буфера записи; 0 — каждая запись сразу пишется в файл
+	BufferSize int
+	// Как часто буфер сбрасывается на диск
+	FlushInterval time.Duration
+}
+
+// Logger структурированный логгер с записью в файл
 type Logger struct {
-	file *os.File
-	mu   sync.Mutex
+	log *slog.Logger
+	out *rotatingWriter
 }
 
 var L *Logger
 
-// InitLogger инициализирует новый логгер с записью в файл
-func InitLogger(filepath string) error {
-	file, err := os.OpenFile(filepath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+// InitLogger инициализирует логгер с записью в файл по настройкам cfg
+func InitLogger(cfg Config) error {
+	if cfg.Path == "" {
+		cfg.Path = "./bot.log"
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+
+	level, err := ParseLevel(cfg.Level)
 	if err != nil {
 		return err
 	}
 
-	log.SetOutput(file) // Перенаправляем стандартный вывод логов в файл
+	out, err := newRotatingWriter(cfg)
+	if err != nil {
+		return err
+	}
 
-	L = &Logger{file: file}
+	handler, err := newHandler(out, cfg.Format, level)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	L = &Logger{log: slog.New(handler), out: out}
+
+	// Записи стандартного пакета log тоже попадают в этот файл в том же формате
+	slog.SetDefault(L.log)
 
 	return nil
 }
 
+// ParseLevel разбирает уровень логирования: debug, info, warn или warning, error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return slog.LevelInfo, nil
+	case "warning":
+		return slog.LevelWarn, nil
+	}
+
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return level, fmt.Errorf("неизвестный уровень логирования %q", s)
+	}
+
+	return level, nil
+}
+
+func newHandler(w io.Writer, format string, level slog.Level) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		return slog.NewTextHandler(w, opts), nil
+	case "json":
+		return slog.NewJSONHandler(w, opts), nil
+	}
+
+	return nil, fmt.Errorf("неизвестный формат логов %q", format)
+}
+
+// Path возвращает путь к текущему файлу лога
+func (l *Logger) Path() string {
+	return l.out.path
+}
+
+// With возвращает логгер, добавляющий поля args ко всем записям
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{log: l.log.With(args...), out: l.out}
+}
+
+// Debug записывает отладочное сообщение с полями args: ключ, значение, ...
+func (l *Logger) Debug(msg string, args ...any) {
+	l.log.Debug(msg, args...)
+}
+
 // Info записывает информационное сообщение
-func (l *Logger) Info(msg string) {
-	go func() {
-		l.printLog("INFO: ", msg)
-	}()
+func (l *Logger) Info(msg string, args ...any) {
+	l.log.Info(msg, args...)
 }
 
-// Warning записывает сообщение об ошибке
-func (l *Logger) Warning(msg string) {
-	go func() {
-		l.printLog("WARNING: ", msg)
-	}()
+// Warning записывает предупреждение
+func (l *Logger) Warning(msg string, args ...any) {
+	l.log.Warn(msg, args...)
 }
 
 // Error записывает сообщение об ошибке
-func (l *Logger) Error(message string, err error) {
-	msg := fmt.Sprintf("%s %v", message, err)
-	go func() {
-		l.printLog("ERROR: ", msg)
-	}()
+func (l *Logger) Error(message string, err error, args ...any) {
+	message = strings.TrimSuffix(strings.TrimSpace(message), ":")
+	l.log.Error(message, append([]any{"error", err}, args...)...)
 }
 
-// Error ошибка отправки сообщение ботом
-func (l *Logger) ErrorSendMessage(err error) {
-	msg := fmt.Sprintf("Не удалось отправить сообщение %v", err)
-	go func() {
-		l.printLog("ERROR: ", msg)
-	}()
+// ErrorSendMessage ошибка отправки сообщения ботом
+func (l *Logger) ErrorSendMessage(err error, args ...any) {
+	l.Error("Не удалось отправить сообщение", err, args...)
 }
 
-// Error ошибка изменения сообщение ботом
-func (l *Logger) ErrorEditMessage(err error) {
-	msg := fmt.Sprintf("Не удалось изменить сообщение %v", err)
-	go func() {
-		l.printLog("ERROR: ", msg)
-	}()
+// ErrorEditMessage ошибка изменения сообщения ботом
+func (l *Logger) ErrorEditMessage(err error, args ...any) {
+	l.Error("Не удалось изменить сообщение", err, args...)
 }
 
-func (l *Logger) printLog(grade string, msg string) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	log.Println(grade, msg)
+// Flush записывает буфер логов на диск
+func (l *Logger) Flush() error {
+	return l.out.Flush()
 }
 
-// Close закрывает файл логов
+// Close записывает оставшиеся сообщения и закрывает файл логов
 func (l *Logger) Close() error {
-	return l.file.Close()
+	return l.out.Close()
+}
+
+// printFallback выводит ошибку самого логгера, которую некуда записать, кроме stderr
+func printFallback(message string, err error) {
+	fmt.Fprintln(os.Stderr, message, err)
 }
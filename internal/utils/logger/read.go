@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// Filter условия отбора записей лога
+type Filter struct {
+	// Минимальный уровень записи
+	Level slog.Level
+	// Записи раньше этого времени пропускаются; нулевое значение — без ограничения
+	Since time.Time
+	// Подстрока, которую должна содержать запись, без учета регистра
+	Grep string
+}
+
+// Формат записей стандартного log до перехода на структурированный лог: "2006/01/02 15:04:05 INFO:  ..."
+const legacyTimeFormat = "2006/01/02 15:04:05"
+
+// Tail возвращает последние записи лога path и его архивов, подходящие под filter,
+// общим размером не более limit байт. truncated сообщает, что часть записей не поместилась
+func Tail(path string, filter Filter, limit int) (lines []string, truncated bool, err error) {
+	backups, err := RotatedFiles(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	files := []string{path}
+	for _, backup := range backups {
+		// Архив создан при ротации, поэтому все его записи старше времени в имени
+		if !filter.Since.IsZero() && backup.Time.Before(filter.Since) {
+			break
+		}
+		files = append(files, backup.Path)
+	}
+
+	grep := strings.ToLower(filter.Grep)
+
+	// Файлы перебираются от новых к старым, от каждого берутся последние записи, которые помещаются в остаток лимита
+	var chunks [][]string
+	for _, file := range files {
+		matched, size, dropped, err := tailMatching(file, filter, grep, limit)
+		if err != nil {
+			return nil, false, err
+		}
+
+		chunks = append(chunks, matched)
+		limit -= size
+		if dropped {
+			truncated = true
+			break
+		}
+	}
+
+	for i := len(chunks) - 1; i >= 0; i-- {
+		lines = append(lines, chunks[i]...)
+	}
+
+	return lines, truncated, nil
+}
+
+// tailMatching читает файл лога, сжатый или нет, и возвращает последние записи, подходящие под filter,
+// общим размером не более limit байт. Файл читается построчно, в памяти остаются только эти записи;
+// dropped сообщает, что более ранние подходящие записи не поместились
+func tailMatching(path string, filter Filter, grep string, limit int) (lines []string, size int, dropped bool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, false, nil
+		}
+		return nil, 0, false, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !matches(line, filter, grep) {
+			continue
+		}
+
+		lines = append(lines, line)
+		size += len(line) + 1
+		for size > limit {
+			size -= len(lines[0]) + 1
+			lines = lines[1:]
+			dropped = true
+		}
+	}
+
+	return lines, size, dropped, scanner.Err()
+}
+
+// matches проверяет, подходит ли строка лога под filter
+func matches(line string, filter Filter, grep string) bool {
+	if line == "" {
+		return false
+	}
+	if grep != "" && !strings.Contains(strings.ToLower(line), grep) {
+		return false
+	}
+
+	when, level, ok := parseLine(line)
+	if !ok {
+		// Нераспознанные строки, например продолжения многострочных сообщений, отбираются только по тексту
+		return filter.Level <= slog.LevelInfo && filter.Since.IsZero()
+	}
+
+	return level >= filter.Level && (filter.Since.IsZero() || !when.Before(filter.Since))
+}
+
+// parseLine определяет время и уровень записи в формате json, text или старом формате log
+func parseLine(line string) (time.Time, slog.Level, bool) {
+	var level slog.Level
+
+	if strings.HasPrefix(line, "{") {
+		var entry struct {
+			Time  time.Time `json:"time"`
+			Level string    `json:"level"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return time.Time{}, level, false
+		}
+		if err := level.UnmarshalText([]byte(entry.Level)); err != nil {
+			return time.Time{}, level, false
+		}
+		return entry.Time, level, true
+	}
+
+	if strings.HasPrefix(line, "time=") {
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) < 2 || !strings.HasPrefix(fields[1], "level=") {
+			return time.Time{}, level, false
+		}
+
+		when, err := time.Parse(time.RFC3339Nano, strings.TrimPrefix(fields[0], "time="))
+		if err != nil {
+			return time.Time{}, level, false
+		}
+		if err = level.UnmarshalText([]byte(strings.TrimPrefix(fields[1], "level="))); err != nil {
+			return time.Time{}, level, false
+		}
+		return when, level, true
+	}
+
+	if len(line) > len(legacyTimeFormat) {
+		when, err := time.ParseInLocation(legacyTimeFormat, line[:len(legacyTimeFormat)], time.Local)
+		if err != nil {
+			return time.Time{}, level, false
+		}
+
+		grade, _, _ := strings.Cut(strings.TrimSpace(line[len(legacyTimeFormat):]), " ")
+		level, err = ParseLevel(strings.TrimSuffix(grade, ":"))
+		if err != nil {
+			level = slog.LevelInfo
+		}
+		return when, level, true
+	}
+
+	return time.Time{}, level, false
+}
+
+// WriteGzip записывает строки лога в w, сжимая их gzip
+func WriteGzip(w io.Writer, lines []string) error {
+	gz := gzip.NewWriter(w)
+	for _, line := range lines {
+		if _, err := io.WriteString(gz, line+"\n"); err != nil {
+			return err
+		}
+	}
+
+	return gz.Close()
+}
@@ -0,0 +1,175 @@
+package logger
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeLog создает файл лога с записями, архив с суффиксом .gz сжимается
+func writeLog(t *testing.T, path string, lines ...string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if filepath.Ext(path) == ".gz" {
+		if err = WriteGzip(file, lines); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	for _, line := range lines {
+		if _, err = file.WriteString(line + "\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// tailFixture лог с текущим файлом, несжатым и сжатым архивами в форматах json, text и старого log
+func tailFixture(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot.log")
+
+	writeLog(t, filepath.Join(dir, "bot-20240801-100000.000.log.gz"),
+		"2024/08/01 09:00:00 INFO:  Запуск бота",
+		"2024/08/01 09:30:00 ERROR: Ошибка базы",
+		"продолжение сообщения",
+	)
+	writeLog(t, filepath.Join(dir, "bot-20240802-100000.000.log"),
+		`time=2024-08-02T09:00:00Z level=INFO msg="Команда /add"`,
+		`time=2024-08-02T09:30:00Z level=WARN msg="Медленная обработка"`,
+	)
+	writeLog(t, path,
+		`{"time":"2024-08-03T09:00:00Z","level":"DEBUG","msg":"Обновление"}`,
+		"",
+		`{"time":"2024-08-03T09:30:00Z","level":"ERROR","msg":"Ошибка отправки","error":"timeout"}`,
+	)
+
+	return path
+}
+
+func TestTail(t *testing.T) {
+	path := tailFixture(t)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{
+			"все записи от старых к новым", Filter{Level: slog.LevelDebug},
+			[]string{
+				"2024/08/01 09:00:00 INFO:  Запуск бота",
+				"2024/08/01 09:30:00 ERROR: Ошибка базы",
+				"продолжение сообщения",
+				`time=2024-08-02T09:00:00Z level=INFO msg="Команда /add"`,
+				`time=2024-08-02T09:30:00Z level=WARN msg="Медленная обработка"`,
+				`{"time":"2024-08-03T09:00:00Z","level":"DEBUG","msg":"Обновление"}`,
+				`{"time":"2024-08-03T09:30:00Z","level":"ERROR","msg":"Ошибка отправки","error":"timeout"}`,
+			},
+		},
+		{
+			"по уровню", Filter{Level: slog.LevelWarn},
+			[]string{
+				"2024/08/01 09:30:00 ERROR: Ошибка базы",
+				`time=2024-08-02T09:30:00Z level=WARN msg="Медленная обработка"`,
+				`{"time":"2024-08-03T09:30:00Z","level":"ERROR","msg":"Ошибка отправки","error":"timeout"}`,
+			},
+		},
+		{
+			"по тексту без учета регистра", Filter{Level: slog.LevelDebug, Grep: "ОШИБКА"},
+			[]string{
+				"2024/08/01 09:30:00 ERROR: Ошибка базы",
+				`{"time":"2024-08-03T09:30:00Z","level":"ERROR","msg":"Ошибка отправки","error":"timeout"}`,
+			},
+		},
+		{
+			// Архив первого дня старше начала периода и не читается
+			"по времени", Filter{Level: slog.LevelDebug, Since: time.Date(2024, 8, 2, 9, 15, 0, 0, time.UTC)},
+			[]string{
+				`time=2024-08-02T09:30:00Z level=WARN msg="Медленная обработка"`,
+				`{"time":"2024-08-03T09:00:00Z","level":"DEBUG","msg":"Обновление"}`,
+				`{"time":"2024-08-03T09:30:00Z","level":"ERROR","msg":"Ошибка отправки","error":"timeout"}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, truncated, err := Tail(path, tt.filter, 1<<20)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if truncated {
+				t.Error("записи обрезаны при большом лимите")
+			}
+			if !reflect.DeepEqual(lines, tt.want) {
+				t.Errorf("Tail = %q, ожидалось %q", lines, tt.want)
+			}
+		})
+	}
+}
+
+func TestTailLimit(t *testing.T) {
+	path := tailFixture(t)
+
+	newest := []string{
+		`time=2024-08-02T09:30:00Z level=WARN msg="Медленная обработка"`,
+		`{"time":"2024-08-03T09:00:00Z","level":"DEBUG","msg":"Обновление"}`,
+		`{"time":"2024-08-03T09:30:00Z","level":"ERROR","msg":"Ошибка отправки","error":"timeout"}`,
+	}
+	size := 0
+	for _, line := range newest {
+		size += len(line) + 1
+	}
+
+	// Лимит обрывает записи внутри архива: остаются самые новые, которые помещаются целиком
+	for _, limit := range []int{size, size + len(`time=2024-08-02T09:00:00Z level=INFO msg="Команда /add"`)} {
+		lines, truncated, err := Tail(path, Filter{Level: slog.LevelDebug}, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !truncated {
+			t.Errorf("лимит %d: записи не отмечены как обрезанные", limit)
+		}
+		if !reflect.DeepEqual(lines, newest) {
+			t.Errorf("лимит %d: Tail = %q, ожидалось %q", limit, lines, newest)
+		}
+	}
+
+	// Запись больше лимита не возвращается частично
+	lines, truncated, err := Tail(path, Filter{Level: slog.LevelDebug}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !truncated || len(lines) != 0 {
+		t.Errorf("Tail = %q, %v, ожидалось без записей с обрезкой", lines, truncated)
+	}
+}
+
+func TestTailMissingFile(t *testing.T) {
+	lines, truncated, err := Tail(filepath.Join(t.TempDir(), "bot.log"), Filter{}, 1<<20)
+	if err != nil || truncated || len(lines) != 0 {
+		t.Errorf("Tail = %q, %v, %v, ожидался пустой результат", lines, truncated, err)
+	}
+}
+
+func TestTailCorruptArchive(t *testing.T) {
+	path := tailFixture(t)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "bot-20240801-100000.000.log.gz"), []byte("not gzip"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Tail(path, Filter{Level: slog.LevelDebug}, 1<<20); err == nil {
+		t.Error("поврежденный архив прочитан без ошибки")
+	}
+}
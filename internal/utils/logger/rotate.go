@@ -0,0 +1,339 @@
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Формат времени в имени архивного файла: bot-20240801-150405.000.log
+const backupTimeFormat = "20060102-150405.000"
+
+// rotatingWriter пишет в файл лога, при превышении размера переносит его в архив
+// и удаляет архивы старше maxAge или сверх maxBackups
+type rotatingWriter struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+	// Текущее время; подменяется в тестах
+	now func() time.Time
+
+	file *os.File
+	size int64
+	// Буфер записи, nil для синхронной записи
+	buf *bufio.Writer
+
+	// Фоновая запись буфера и обработка архивов
+	background sync.WaitGroup
+	stop       chan struct{}
+
+	// Архивы, ожидающие сжатия и удаления старых; обрабатываются по одному в archiveLoop,
+	// чтобы удаление не затронуло архив, который еще сжимается
+	archiveMu sync.Mutex
+	archives  []archiveJob
+	archived  chan struct{}
+}
+
+// archiveJob архив после ротации и время ротации, от которого отсчитывается возраст архивов
+type archiveJob struct {
+	path string
+	time time.Time
+}
+
+func newRotatingWriter(cfg Config) (*rotatingWriter, error) {
+	w := &rotatingWriter{
+		path:       cfg.Path,
+		maxSize:    int64(cfg.MaxSizeMB) << 20,
+		maxAge:     time.Duration(cfg.MaxAgeDays) * 24 * time.Hour,
+		maxBackups: cfg.MaxBackups,
+		compress:   cfg.Compress,
+		now:        time.Now,
+		stop:       make(chan struct{}),
+		archived:   make(chan struct{}, 1),
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	w.background.Add(1)
+	go w.archiveLoop()
+
+	if cfg.BufferSize > 0 {
+		w.buf = bufio.NewWriterSize(w.file, cfg.BufferSize)
+		w.background.Add(1)
+		go w.flushEvery(cfg.FlushInterval)
+	}
+
+	return w, nil
+}
+
+func (w *rotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+
+	return nil
+}
+
+// Write записывает одну запись лога целиком, при необходимости предварительно ротируя файл
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		// Если файл не удалось перенести, запись продолжается в прежний
+		if err := w.rotate(); err != nil {
+			printFallback("Ошибка при ротации лога:", err)
+		}
+	}
+
+	var out io.Writer = w.file
+	if w.buf != nil {
+		out = w.buf
+	}
+
+	n, err := out.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// rotate переносит текущий файл в архив и открывает новый
+func (w *rotatingWriter) rotate() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	now := w.now()
+	ext := filepath.Ext(w.path)
+	backup := strings.TrimSuffix(w.path, ext) + "-" + now.Format(backupTimeFormat) + ext
+	renameErr := os.Rename(w.path, backup)
+
+	// После неудачного переноса снова открывается прежний файл, иначе запись пойдет в закрытый
+	if err := w.open(); err != nil {
+		return errors.Join(renameErr, err)
+	}
+	if w.buf != nil {
+		w.buf.Reset(w.file)
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+
+	w.archiveMu.Lock()
+	w.archives = append(w.archives, archiveJob{path: backup, time: now})
+	w.archiveMu.Unlock()
+
+	select {
+	case w.archived <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// archiveLoop сжимает архивы и удаляет старые в порядке ротации; перед завершением обрабатывает оставшиеся
+func (w *rotatingWriter) archiveLoop() {
+	defer w.background.Done()
+
+	for {
+		select {
+		case <-w.archived:
+			w.processArchives()
+		case <-w.stop:
+			w.processArchives()
+			return
+		}
+	}
+}
+
+func (w *rotatingWriter) processArchives() {
+	for {
+		w.archiveMu.Lock()
+		if len(w.archives) == 0 {
+			w.archiveMu.Unlock()
+			return
+		}
+		job := w.archives[0]
+		w.archives = w.archives[1:]
+		w.archiveMu.Unlock()
+
+		// Пока архив ждал в очереди, его могла удалить очистка после предыдущей ротации
+		if w.compress {
+			if err := compressFile(job.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				printFallback("Ошибка при сжатии архива лога:", err)
+			}
+		}
+		if err := w.removeOld(job.time); err != nil {
+			printFallback("Ошибка при удалении старых архивов лога:", err)
+		}
+	}
+}
+
+// removeOld удаляет архивы старше maxAge на момент now и сверх maxBackups, начиная с самых старых
+func (w *rotatingWriter) removeOld(now time.Time) error {
+	backups, err := RotatedFiles(w.path)
+	if err != nil {
+		return err
+	}
+
+	for i, backup := range backups {
+		tooMany := w.maxBackups > 0 && i >= w.maxBackups
+		tooOld := w.maxAge > 0 && now.Sub(backup.Time) > w.maxAge
+		if tooMany || tooOld {
+			if err = os.Remove(backup.Path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *rotatingWriter) flushEvery(interval time.Duration) {
+	defer w.background.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if err := w.flush(); err != nil {
+				printFallback("Ошибка при записи лога:", err)
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+func (w *rotatingWriter) flush() error {
+	if w.buf == nil {
+		return nil
+	}
+
+	return w.buf.Flush()
+}
+
+// Flush записывает буфер на диск
+func (w *rotatingWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	return w.file.Sync()
+}
+
+// Close записывает буфер, дожидается фоновых операций и закрывает файл
+func (w *rotatingWriter) Close() error {
+	close(w.stop)
+	flushErr := w.Flush()
+	w.background.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	return flushErr
+}
+
+// RotatedFile архивный файл лога
+type RotatedFile struct {
+	Path string
+	Time time.Time
+}
+
+// RotatedFiles возвращает архивы лога path, от новых к старым
+func RotatedFiles(path string) ([]RotatedFile, error) {
+	ext := filepath.Ext(path)
+	prefix := filepath.Base(strings.TrimSuffix(path, ext)) + "-"
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []RotatedFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		created, err := time.ParseInLocation(backupTimeFormat, strings.TrimPrefix(stamp, prefix), time.Local)
+		if err != nil {
+			continue
+		}
+
+		files = append(files, RotatedFile{Path: filepath.Join(filepath.Dir(path), name), Time: created})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Time.After(files[j].Time)
+	})
+
+	return files, nil
+}
+
+// compressFile сжимает файл в path.gz и удаляет исходный
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
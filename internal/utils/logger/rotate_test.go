@@ -0,0 +1,301 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clock выдает моменты времени, каждый следующий на секунду позже
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.t = c.t.Add(time.Second)
+	return c.t
+}
+
+func newTestWriter(t *testing.T, maxSize int64, compress bool) (*rotatingWriter, *clock) {
+	t.Helper()
+
+	w, err := newRotatingWriter(Config{Path: filepath.Join(t.TempDir(), "bot.log"), Compress: compress})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &clock{t: time.Date(2024, 8, 1, 15, 4, 5, 0, time.Local)}
+	w.now = c.now
+	w.maxSize = maxSize
+
+	return w, c
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestRotate(t *testing.T) {
+	w, _ := newTestWriter(t, 10, false)
+
+	for _, record := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := w.Write([]byte(record)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, w.path); got != "third\n" {
+		t.Errorf("текущий файл = %q, ожидалась последняя запись", got)
+	}
+
+	backups, err := RotatedFiles(w.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name    string
+		content string
+	}{
+		{"bot-20240801-150407.000.log", "second\n"},
+		{"bot-20240801-150406.000.log", "first\n"},
+	}
+	if len(backups) != len(want) {
+		t.Fatalf("архивов %d, ожидалось %d: %+v", len(backups), len(want), backups)
+	}
+	for i, backup := range backups {
+		if filepath.Base(backup.Path) != want[i].name {
+			t.Errorf("архив %d = %s, ожидался %s", i, filepath.Base(backup.Path), want[i].name)
+		}
+		if got := readFile(t, backup.Path); got != want[i].content {
+			t.Errorf("архив %s = %q, ожидалось %q", want[i].name, got, want[i].content)
+		}
+	}
+}
+
+func TestRotateSkipsOversizedFirstRecord(t *testing.T) {
+	w, _ := newTestWriter(t, 4, false)
+
+	// Запись больше лимита в пустой файл пишется без ротации, иначе файл ротировался бы пустым
+	if _, err := w.Write([]byte("long record\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := RotatedFiles(w.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 0 {
+		t.Errorf("ожидалось без архивов, получено %+v", backups)
+	}
+}
+
+func TestRotateRenameFailure(t *testing.T) {
+	w, _ := newTestWriter(t, 10, false)
+
+	// Каталог на месте первого архива не дает перенести файл
+	blocked := strings.TrimSuffix(w.path, ".log") + "-20240801-150406.000.log"
+	if err := os.MkdirAll(filepath.Join(blocked, "busy"), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, record := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := w.Write([]byte(record)); err != nil {
+			t.Fatalf("запись %q: %v", record, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Вторая запись остается в прежнем файле, третья ротирует его со следующей попытки
+	if got := readFile(t, w.path); got != "third\n" {
+		t.Errorf("текущий файл = %q, ожидалась последняя запись", got)
+	}
+	backup := strings.TrimSuffix(w.path, ".log") + "-20240801-150407.000.log"
+	if got := readFile(t, backup); got != "first\nsecond\n" {
+		t.Errorf("архив = %q, ожидались записи до неудачной ротации", got)
+	}
+}
+
+func TestRotateCompressKeepsBackups(t *testing.T) {
+	const maxBackups = 3
+
+	w, _ := newTestWriter(t, 10, true)
+	w.maxBackups = maxBackups
+
+	// Каждая запись ротирует файл; сжатие и удаление старых архивов не должны мешать друг другу
+	for i := 0; i < 20; i++ {
+		if _, err := w.Write([]byte("record " + strings.Repeat("x", i) + "\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := RotatedFiles(w.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != maxBackups {
+		t.Fatalf("архивов %d, ожидалось %d: %+v", len(backups), maxBackups, backups)
+	}
+	for i, backup := range backups {
+		if !strings.HasSuffix(backup.Path, ".gz") {
+			t.Errorf("архив %s не сжат", backup.Path)
+		}
+		// Остаются самые новые архивы: записи 16, 17 и 18
+		want := "record " + strings.Repeat("x", 18-i) + "\n"
+		if got := readGzip(t, backup.Path); got != want {
+			t.Errorf("архив %s = %q, ожидалось %q", filepath.Base(backup.Path), got, want)
+		}
+	}
+}
+
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestRotateCompress(t *testing.T) {
+	w, _ := newTestWriter(t, 10, true)
+
+	for _, record := range []string{"compressed\n", "current\n"} {
+		if _, err := w.Write([]byte(record)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Close дожидается фонового сжатия
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backup := strings.TrimSuffix(w.path, ".log") + "-20240801-150406.000.log"
+	if _, err := os.Stat(backup); !os.IsNotExist(err) {
+		t.Errorf("несжатый архив не удален: %v", err)
+	}
+
+	file, err := os.Open(backup + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "compressed\n" {
+		t.Errorf("содержимое архива = %q", data)
+	}
+
+	backups, err := RotatedFiles(w.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || backups[0].Path != backup+".gz" {
+		t.Errorf("RotatedFiles = %+v, ожидался %s.gz", backups, backup)
+	}
+}
+
+func TestRemoveOld(t *testing.T) {
+	now := time.Date(2024, 8, 10, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name       string
+		maxAge     time.Duration
+		maxBackups int
+		want       []string
+	}{
+		{"без ограничений", 0, 0, []string{"0h", "1d", "3d.gz", "5d"}},
+		{"по возрасту", 2 * 24 * time.Hour, 0, []string{"0h", "1d"}},
+		{"по количеству", 0, 3, []string{"0h", "1d", "3d.gz"}},
+		{"по возрасту и количеству", 4 * 24 * time.Hour, 1, []string{"0h"}},
+	}
+
+	// Архивы с возрастом относительно now; один из них уже сжат
+	ages := map[string]time.Duration{
+		"0h":    0,
+		"1d":    24 * time.Hour,
+		"3d.gz": 3 * 24 * time.Hour,
+		"5d":    5 * 24 * time.Hour,
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "bot.log")
+
+			names := make(map[string]string, len(ages))
+			for label, age := range ages {
+				name := "bot-" + now.Add(-age).Format(backupTimeFormat) + ".log"
+				if strings.HasSuffix(label, ".gz") {
+					name += ".gz"
+				}
+				names[name] = label
+				if err := os.WriteFile(filepath.Join(dir, name), nil, 0666); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Посторонние файлы рядом с логом не трогаются
+			if err := os.WriteFile(filepath.Join(dir, "bot-notes.log"), nil, 0666); err != nil {
+				t.Fatal(err)
+			}
+
+			w := &rotatingWriter{path: path, maxAge: tt.maxAge, maxBackups: tt.maxBackups}
+			if err := w.removeOld(now); err != nil {
+				t.Fatal(err)
+			}
+
+			backups, err := RotatedFiles(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, backup := range backups {
+				got = append(got, names[filepath.Base(backup.Path)])
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("остались %v, ожидались %v", got, tt.want)
+			}
+			if _, err = os.Stat(filepath.Join(dir, "bot-notes.log")); err != nil {
+				t.Errorf("посторонний файл удален: %v", err)
+			}
+		})
+	}
+}
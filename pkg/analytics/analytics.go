@@ -0,0 +1,100 @@
+package analytics
+
+import (
+	"sort"
+	"time"
+
+	"expense_accounting_bot/pkg/repository"
+)
+
+// Report показатели расходов за период
+type Report struct {
+	Total float64
+	Count int
+	// Сколько дней периода уже прошло, включая сегодняшний
+	Days          int
+	AveragePerDay float64
+	// Крупнейшие расходы по убыванию суммы
+	Largest []repository.Expense
+	// День недели с наибольшей суммой расходов
+	BusiestWeekday      time.Weekday
+	BusiestWeekdayTotal float64
+	// Доли категорий по убыванию суммы
+	Shares []Share
+}
+
+// Share сумма категории и ее доля в расходах за период, %
+type Share struct {
+	Category string
+	Amount   float64
+	Percent  float64
+}
+
+// Analyze считает показатели по расходам за период [start, end]; дни после now в среднем не учитываются.
+// В Largest попадает не больше top расходов
+func Analyze(expenses []repository.Expense, start, end, now time.Time, top int) Report {
+	report := Report{Count: len(expenses), Days: ElapsedDays(start, end, now)}
+
+	categories := make(map[string]float64)
+	var weekdays [7]float64
+	for _, expense := range expenses {
+		report.Total += expense.Amount
+		categories[expense.Category] += expense.Amount
+		weekdays[expense.Date.In(now.Location()).Weekday()] += expense.Amount
+	}
+	report.AveragePerDay = report.Total / float64(report.Days)
+
+	for day, sum := range weekdays {
+		if sum > report.BusiestWeekdayTotal {
+			report.BusiestWeekday, report.BusiestWeekdayTotal = time.Weekday(day), sum
+		}
+	}
+
+	for category, sum := range categories {
+		share := Share{Category: category, Amount: sum}
+		if report.Total > 0 {
+			share.Percent = sum / report.Total * 100
+		}
+		report.Shares = append(report.Shares, share)
+	}
+	sort.Slice(report.Shares, func(i, j int) bool {
+		if report.Shares[i].Amount != report.Shares[j].Amount {
+			return report.Shares[i].Amount > report.Shares[j].Amount
+		}
+		return report.Shares[i].Category < report.Shares[j].Category
+	})
+
+	report.Largest = append([]repository.Expense(nil), expenses...)
+	sort.SliceStable(report.Largest, func(i, j int) bool {
+		return report.Largest[i].Amount > report.Largest[j].Amount
+	})
+	if len(report.Largest) > top {
+		report.Largest = report.Largest[:top]
+	}
+
+	return report
+}
+
+// Projection прогноз расходов за весь период [start, end], если тратить в прежнем темпе
+func Projection(spent float64, start, end, now time.Time) float64 {
+	return spent / float64(ElapsedDays(start, end, now)) * float64(ElapsedDays(start, end, end))
+}
+
+// ElapsedDays число календарных дней от start до now включительно, но не дальше end; не меньше одного
+func ElapsedDays(start, end, now time.Time) int {
+	if now.After(end) {
+		now = end
+	}
+
+	days := calendarDay(now).Sub(calendarDay(start)).Hours() / 24
+	// Округление сглаживает дни перехода на летнее время
+	if n := int(days+0.5) + 1; n > 1 {
+		return n
+	}
+
+	return 1
+}
+
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,178 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"expense_accounting_bot/pkg/repository"
+)
+
+// august границы августа 2024 и момент посреди периода, 10 августа
+func august(loc *time.Location) (start, end, now time.Time) {
+	start = time.Date(2024, 8, 1, 0, 0, 0, 0, loc)
+	end = time.Date(2024, 9, 1, 0, 0, 0, 0, loc).Add(-time.Nanosecond)
+	now = time.Date(2024, 8, 10, 12, 0, 0, 0, loc)
+
+	return start, end, now
+}
+
+func TestAnalyze(t *testing.T) {
+	start, end, now := august(time.UTC)
+	day := func(d int) time.Time {
+		return time.Date(2024, 8, d, 12, 0, 0, 0, time.UTC)
+	}
+	expenses := []repository.Expense{
+		{ID: 1, Date: day(5), Category: "groceries", Amount: 300},
+		{ID: 2, Date: day(5), Category: "restaurants", Amount: 200},
+		{ID: 3, Date: day(7), Category: "groceries", Amount: 100},
+		{ID: 4, Date: day(9), Category: "transport", Amount: 400},
+	}
+
+	report := Analyze(expenses, start, end, now, 3)
+
+	if report.Total != 1000 || report.Count != 4 || report.Days != 10 || report.AveragePerDay != 100 {
+		t.Errorf("итоги = %v, %d расходов, %d дней, %v в день, ожидалось 1000, 4, 10, 100",
+			report.Total, report.Count, report.Days, report.AveragePerDay)
+	}
+
+	// 5 августа 2024 — понедельник
+	if report.BusiestWeekday != time.Monday || report.BusiestWeekdayTotal != 500 {
+		t.Errorf("самый затратный день = %v (%v), ожидался понедельник (500)", report.BusiestWeekday, report.BusiestWeekdayTotal)
+	}
+
+	// Категории с равной суммой упорядочены по названию
+	wantShares := []Share{
+		{Category: "groceries", Amount: 400, Percent: 40},
+		{Category: "transport", Amount: 400, Percent: 40},
+		{Category: "restaurants", Amount: 200, Percent: 20},
+	}
+	if !reflect.DeepEqual(report.Shares, wantShares) {
+		t.Errorf("доли = %+v, ожидалось %+v", report.Shares, wantShares)
+	}
+
+	var largest []int64
+	for _, expense := range report.Largest {
+		largest = append(largest, expense.ID)
+	}
+	if !reflect.DeepEqual(largest, []int64{4, 1, 2}) {
+		t.Errorf("крупнейшие расходы = %v, ожидалось [4 1 2]", largest)
+	}
+
+	// Сортировка крупнейших расходов не меняет исходный срез
+	if expenses[0].ID != 1 || expenses[3].ID != 4 {
+		t.Errorf("исходные расходы переупорядочены: %+v", expenses)
+	}
+}
+
+func TestAnalyzeLargestTies(t *testing.T) {
+	start, end, now := august(time.UTC)
+	expenses := []repository.Expense{
+		{ID: 1, Date: now, Amount: 100},
+		{ID: 2, Date: now, Amount: 200},
+		{ID: 3, Date: now, Amount: 100},
+		{ID: 4, Date: now, Amount: 100},
+	}
+
+	// Равные суммы остаются в исходном порядке
+	report := Analyze(expenses, start, end, now, 3)
+	var largest []int64
+	for _, expense := range report.Largest {
+		largest = append(largest, expense.ID)
+	}
+	if !reflect.DeepEqual(largest, []int64{2, 1, 3}) {
+		t.Errorf("крупнейшие расходы = %v, ожидалось [2 1 3]", largest)
+	}
+}
+
+func TestAnalyzeEmpty(t *testing.T) {
+	start, end, now := august(time.UTC)
+
+	report := Analyze(nil, start, end, now, 3)
+	if report.Total != 0 || report.Count != 0 || report.AveragePerDay != 0 || report.BusiestWeekdayTotal != 0 ||
+		len(report.Shares) != 0 || len(report.Largest) != 0 {
+		t.Errorf("отчет без расходов = %+v", report)
+	}
+	if report.Days != 10 {
+		t.Errorf("дней = %d, ожидалось 10", report.Days)
+	}
+}
+
+func TestAnalyzeWeekdayInUserZone(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, end, now := august(moscow)
+
+	// 23:30 воскресенья по UTC — уже понедельник в Москве
+	expenses := []repository.Expense{{Date: time.Date(2024, 8, 4, 23, 30, 0, 0, time.UTC), Category: "groceries", Amount: 100}}
+	if report := Analyze(expenses, start, end, now, 1); report.BusiestWeekday != time.Monday {
+		t.Errorf("день недели = %v, ожидался понедельник", report.BusiestWeekday)
+	}
+}
+
+func TestElapsedDays(t *testing.T) {
+	start, end, _ := august(time.UTC)
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2024, month, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want int
+	}{
+		{"до начала периода", at(time.July, 20, 12), 1},
+		{"первый день", at(time.August, 1, 0), 1},
+		{"конец первого дня", at(time.August, 1, 23), 1},
+		{"середина периода", at(time.August, 10, 12), 10},
+		{"последний день", at(time.August, 31, 23), 31},
+		{"после окончания периода", at(time.October, 1, 0), 31},
+	}
+
+	for _, tt := range tests {
+		if got := ElapsedDays(start, end, tt.now); got != tt.want {
+			t.Errorf("%s: ElapsedDays = %d, ожидалось %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestElapsedDaysDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 31 марта 2024 в Берлине на час короче, 27 октября — на час длиннее
+	for _, month := range []time.Month{time.March, time.October} {
+		start := time.Date(2024, month, 1, 0, 0, 0, 0, berlin)
+		end := start.AddDate(0, 1, 0).Add(-time.Nanosecond)
+		if got := ElapsedDays(start, end, end); got != 31 {
+			t.Errorf("%v: ElapsedDays = %d, ожидалось 31", month, got)
+		}
+	}
+}
+
+func TestProjection(t *testing.T) {
+	start, end, now := august(time.UTC)
+
+	tests := []struct {
+		name  string
+		spent float64
+		now   time.Time
+		want  float64
+	}{
+		{"середина периода", 1000, now, 3100},
+		{"первый день", 50, start, 1550},
+		{"период закончился", 1000, end.Add(time.Hour), 1000},
+		{"без расходов", 0, now, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Projection(tt.spent, start, end, tt.now); got != tt.want {
+			t.Errorf("%s: Projection = %v, ожидалось %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// MinOutlierHistory сколько расходов категории нужно, чтобы судить о необычных суммах
+	MinOutlierHistory = 5
+	// Модифицированная z-оценка, начиная с которой сумма считается выбросом
+	outlierScore = 3.5
+	// Выброс должен быть еще и во столько раз больше медианы, иначе при маленьком разбросе
+	// необычными оказываются обычные покупки
+	outlierRatio = 2
+)
+
+// Median возвращает медиану значений, для пустого набора — 0
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
+
+// CheckOutlier проверяет, выбивается ли сумма вверх из истории категории. Оценка устойчива к прошлым
+// выбросам: модифицированная z-оценка считается по медиане и медианному абсолютному отклонению (MAD).
+// Возвращает медиану истории
+func CheckOutlier(amount float64, history []float64) (float64, bool) {
+	median := Median(history)
+	if len(history) < MinOutlierHistory || median <= 0 || amount < outlierRatio*median {
+		return median, false
+	}
+
+	deviations := make([]float64, len(history))
+	for i, value := range history {
+		deviations[i] = math.Abs(value - median)
+	}
+	mad := Median(deviations)
+	// Если большинство сумм одинаковы, разброс нулевой и выбросом считается все, что прошло проверку по медиане
+	if mad == 0 {
+		return median, true
+	}
+
+	return median, 0.6745*(amount-median)/mad > outlierScore
+}
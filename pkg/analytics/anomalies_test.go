@@ -0,0 +1,52 @@
+package analytics
+
+import "testing"
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   float64
+	}{
+		{nil, 0},
+		{[]float64{5}, 5},
+		{[]float64{3, 1, 2}, 2},
+		{[]float64{4, 1, 3, 2}, 2.5},
+	}
+
+	for _, tt := range tests {
+		if got := Median(tt.values); got != tt.want {
+			t.Errorf("Median(%v) = %v, ожидалось %v", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestCheckOutlier(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  float64
+		history []float64
+		median  float64
+		want    bool
+	}{
+		{"пустая история", 1000, nil, 0, false},
+		{"меньше MinOutlierHistory расходов", 10000, []float64{100, 100, 100, 100}, 100, false},
+		{"ровно MinOutlierHistory расходов", 10000, []float64{100, 100, 100, 100, 100}, 100, true},
+		{"MAD равен нулю, сумма вдвое больше медианы", 250, []float64{100, 100, 100, 100, 100}, 100, true},
+		{"MAD равен нулю, сумма меньше двух медиан", 150, []float64{100, 100, 100, 100, 100}, 100, false},
+		{"MAD равен нулю при разных суммах", 300, []float64{100, 100, 100, 50, 400}, 100, true},
+		{"нулевая медиана", 100, []float64{0, 0, 0, 0, 0}, 0, false},
+		{"небольшой разброс", 500, []float64{100, 110, 90, 105, 95}, 100, true},
+		{"меньше двух медиан при небольшом разбросе", 190, []float64{100, 110, 90, 105, 95}, 100, false},
+		{"большой разброс", 300, []float64{50, 100, 150, 200, 250}, 150, false},
+		{"выше большого разброса", 450, []float64{50, 100, 150, 200, 250}, 150, true},
+		{"прошлый выброс не сдвигает оценку", 300, []float64{100, 100, 105, 95, 10000, 100}, 100, true},
+		{"сумма ниже обычной", 10, []float64{100, 110, 90, 105, 95}, 100, false},
+	}
+
+	for _, tt := range tests {
+		median, outlier := CheckOutlier(tt.amount, tt.history)
+		if median != tt.median || outlier != tt.want {
+			t.Errorf("%s: CheckOutlier = %v, %v, ожидалось %v, %v", tt.name, median, outlier, tt.median, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package analytics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"expense_accounting_bot/pkg/repository"
+)
+
+// Recurring регулярный платеж, найденный в истории расходов
+type Recurring struct {
+	UserID   int
+	WalletID int64
+	Category string
+	Amount   float64
+	Note     string
+	Interval string
+	// День месяца последнего платежа
+	Day int
+	// Сколько раз платеж встретился
+	Count int
+	Last  time.Time
+	Next  time.Time
+}
+
+// MinRecurringCount сколько раз должен встретиться платеж, чтобы его можно было считать регулярным
+const MinRecurringCount = 2
+
+// Допустимые промежутки между платежами в днях и сколько платежей нужно, чтобы считать их регулярными
+var recurringIntervals = []struct {
+	interval string
+	minDays  float64
+	maxDays  float64
+	minCount int
+}{
+	{repository.IntervalWeek, 6, 8, 4},
+	{repository.IntervalMonth, 27, 33, 3},
+	{repository.IntervalYear, 355, 375, MinRecurringCount},
+}
+
+// Key ключ группы расходов платежа: одна и та же категория, заметка и сумма
+func (r Recurring) Key() string {
+	return recurringKey(r.Category, r.Note, r.Amount)
+}
+
+func recurringKey(category string, note string, amount float64) string {
+	return category + "|" + strings.ToLower(strings.TrimSpace(note)) + "|" + strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// DetectRecurring ищет регулярные платежи: расходы пользователя в кошельке объединяются по категории,
+// заметке и сумме, и группа считается регулярной, если все промежутки между расходами укладываются
+// в неделю, месяц или год, а последний платеж был не дальше одного такого промежутка от now
+func DetectRecurring(expenses []repository.Expense, now time.Time) []Recurring {
+	type groupKey struct {
+		userID   int
+		walletID int64
+		key      string
+	}
+
+	groups := make(map[groupKey][]repository.Expense)
+	var order []groupKey
+	for _, expense := range expenses {
+		key := groupKey{expense.UserID, expense.WalletID, recurringKey(expense.Category, expense.Note, expense.Amount)}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], expense)
+	}
+
+	var found []Recurring
+	for _, key := range order {
+		if recurring, ok := detectGroup(groups[key], now); ok {
+			found = append(found, recurring)
+		}
+	}
+
+	return found
+}
+
+// detectGroup проверяет промежутки между упорядоченными по дате расходами одной группы
+func detectGroup(expenses []repository.Expense, now time.Time) (Recurring, bool) {
+	last := expenses[len(expenses)-1]
+
+	for _, candidate := range recurringIntervals {
+		if len(expenses) < candidate.minCount {
+			continue
+		}
+
+		regular := true
+		for i := 1; i < len(expenses) && regular; i++ {
+			days := expenses[i].Date.Sub(expenses[i-1].Date).Hours() / 24
+			regular = days >= candidate.minDays && days <= candidate.maxDays
+		}
+		// Подписку, которую перестали оплачивать, не предлагаем
+		if !regular || now.Sub(last.Date).Hours()/24 > candidate.maxDays {
+			continue
+		}
+
+		return Recurring{
+			UserID:   last.UserID,
+			WalletID: last.WalletID,
+			Category: last.Category,
+			Amount:   last.Amount,
+			Note:     last.Note,
+			Interval: candidate.interval,
+			Day:      last.Date.Day(),
+			Count:    len(expenses),
+			Last:     last.Date,
+			Next:     NextOccurrence(last.Date, candidate.interval, last.Date.Day()),
+		}, true
+	}
+
+	return Recurring{}, false
+}
+
+// NextOccurrence возвращает дату платежа, следующего за t. Ежемесячные и ежегодные платежи приходятся
+// на день day, а в месяцах, где такого дня нет, — на последний день месяца
+func NextOccurrence(t time.Time, interval string, day int) time.Time {
+	months := 1
+	switch interval {
+	case repository.IntervalWeek:
+		return t.AddDate(0, 0, 7)
+	case repository.IntervalYear:
+		months = 12
+	}
+
+	month := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+	if lastDay := month.AddDate(0, 1, -1).Day(); day > lastDay {
+		day = lastDay
+	}
+
+	return time.Date(month.Year(), month.Month(), day, t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+}
@@ -0,0 +1,171 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"expense_accounting_bot/pkg/repository"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		t        time.Time
+		interval string
+		day      int
+		want     time.Time
+	}{
+		{"неделя", at(2024, 2, 26), repository.IntervalWeek, 26, at(2024, 3, 4)},
+		{"неделя через год", at(2024, 12, 30), repository.IntervalWeek, 30, at(2025, 1, 6)},
+		{"месяц", at(2024, 3, 15), repository.IntervalMonth, 15, at(2024, 4, 15)},
+		{"месяц через год", at(2024, 12, 31), repository.IntervalMonth, 31, at(2025, 1, 31)},
+		{"с 31-го на 30-е", at(2024, 3, 31), repository.IntervalMonth, 31, at(2024, 4, 30)},
+		{"с 31-го в високосный февраль", at(2024, 1, 31), repository.IntervalMonth, 31, at(2024, 2, 29)},
+		{"с 31-го в февраль", at(2023, 1, 31), repository.IntervalMonth, 31, at(2023, 2, 28)},
+		{"после укороченного месяца возвращается 31-е", at(2024, 2, 29), repository.IntervalMonth, 31, at(2024, 3, 31)},
+		{"год", at(2024, 5, 10), repository.IntervalYear, 10, at(2025, 5, 10)},
+		{"год с 29 февраля", at(2024, 2, 29), repository.IntervalYear, 29, at(2025, 2, 28)},
+	}
+
+	for _, tt := range tests {
+		if got := NextOccurrence(tt.t, tt.interval, tt.day); !got.Equal(tt.want) {
+			t.Errorf("%s: NextOccurrence = %v, ожидалось %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// payments расходы пользователя 1 в кошельке 1 с одной категорией, заметкой и суммой в даты dates
+func payments(note string, amount float64, dates ...time.Time) []repository.Expense {
+	expenses := make([]repository.Expense, len(dates))
+	for i, date := range dates {
+		expenses[i] = repository.Expense{UserID: 1, WalletID: 1, Category: "btn_subscriptions", Amount: amount, Note: note, Date: date}
+	}
+
+	return expenses
+}
+
+func TestDetectRecurring(t *testing.T) {
+	day := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+	}
+	now := day(2024, 4, 10)
+
+	tests := []struct {
+		name     string
+		expenses []repository.Expense
+		interval string
+		count    int
+		next     time.Time
+	}{
+		{
+			name:     "ежемесячный платеж",
+			expenses: payments("Netflix", 599, day(2024, 1, 5), day(2024, 2, 5), day(2024, 3, 5), day(2024, 4, 5)),
+			interval: repository.IntervalMonth, count: 4, next: day(2024, 5, 5),
+		},
+		{
+			name:     "два ежемесячных платежа",
+			expenses: payments("Netflix", 599, day(2024, 3, 5), day(2024, 4, 5)),
+		},
+		{
+			name:     "еженедельный платеж",
+			expenses: payments("бассейн", 500, day(2024, 3, 13), day(2024, 3, 20), day(2024, 3, 27), day(2024, 4, 3), day(2024, 4, 10)),
+			interval: repository.IntervalWeek, count: 5, next: day(2024, 4, 17),
+		},
+		{
+			name:     "три еженедельных платежа",
+			expenses: payments("бассейн", 500, day(2024, 3, 27), day(2024, 4, 3), day(2024, 4, 10)),
+		},
+		{
+			name:     "ежегодный платеж",
+			expenses: payments("домен", 1200, day(2023, 4, 1), day(2024, 4, 1)),
+			interval: repository.IntervalYear, count: 2, next: day(2025, 4, 1),
+		},
+		{
+			name:     "платеж 31-го числа",
+			expenses: payments("спортзал", 3000, day(2024, 1, 31), day(2024, 2, 29), day(2024, 3, 31)),
+			interval: repository.IntervalMonth, count: 3, next: day(2024, 4, 30),
+		},
+		{
+			name:     "неровные промежутки",
+			expenses: payments("такси", 700, day(2024, 1, 5), day(2024, 2, 20), day(2024, 3, 10), day(2024, 4, 5)),
+		},
+		{
+			name:     "один пропущенный месяц",
+			expenses: payments("Netflix", 599, day(2023, 12, 5), day(2024, 1, 5), day(2024, 3, 5), day(2024, 4, 5)),
+		},
+		{
+			name:     "подписку перестали оплачивать",
+			expenses: payments("Netflix", 599, day(2023, 11, 5), day(2023, 12, 5), day(2024, 1, 5), day(2024, 2, 5)),
+		},
+		{
+			name:     "еженедельный платеж прекратился",
+			expenses: payments("бассейн", 500, day(2024, 2, 7), day(2024, 2, 14), day(2024, 2, 21), day(2024, 2, 28)),
+		},
+		{
+			name:     "заметка без учета регистра и пробелов",
+			expenses: append(payments("Netflix", 599, day(2024, 2, 5)), payments(" netflix ", 599, day(2024, 3, 5), day(2024, 4, 5))...),
+			interval: repository.IntervalMonth, count: 3, next: day(2024, 5, 5),
+		},
+		{
+			name:     "разные суммы",
+			expenses: append(payments("Netflix", 599, day(2024, 2, 5), day(2024, 3, 5)), payments("Netflix", 799, day(2024, 4, 5))...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found := DetectRecurring(tt.expenses, now)
+			if tt.interval == "" {
+				if len(found) != 0 {
+					t.Errorf("найдены платежи %+v, ожидалось без них", found)
+				}
+				return
+			}
+
+			if len(found) != 1 {
+				t.Fatalf("найдено %d платежей, ожидался один: %+v", len(found), found)
+			}
+			last := tt.expenses[len(tt.expenses)-1]
+			got := found[0]
+			if got.Interval != tt.interval || got.Count != tt.count || !got.Next.Equal(tt.next) ||
+				!got.Last.Equal(last.Date) || got.Amount != last.Amount || got.Day != last.Date.Day() {
+				t.Errorf("найден %+v, ожидался интервал %s, %d платежей, следующий %v", got, tt.interval, tt.count, tt.next)
+			}
+		})
+	}
+}
+
+func TestDetectRecurringGroups(t *testing.T) {
+	now := time.Date(2024, 4, 10, 12, 0, 0, 0, time.UTC)
+	dates := []time.Time{
+		time.Date(2024, 2, 5, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 5, 12, 0, 0, 0, time.UTC),
+	}
+
+	// Одинаковые платежи разных пользователей и кошельков — разные подписки
+	var expenses []repository.Expense
+	for _, owner := range []struct {
+		userID   int
+		walletID int64
+	}{{1, 1}, {2, 1}, {1, 2}} {
+		for _, expense := range payments("Netflix", 599, dates...) {
+			expense.UserID, expense.WalletID = owner.userID, owner.walletID
+			expenses = append(expenses, expense)
+		}
+	}
+
+	found := DetectRecurring(expenses, now)
+	if len(found) != 3 {
+		t.Fatalf("найдено %d платежей, ожидалось 3: %+v", len(found), found)
+	}
+	for _, recurring := range found {
+		if recurring.Count != len(dates) {
+			t.Errorf("у %d/%d %d платежей, ожидалось %d", recurring.UserID, recurring.WalletID, recurring.Count, len(dates))
+		}
+	}
+}
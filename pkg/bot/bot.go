@@ -1,19 +1,21 @@
 package bot
 
 import (
-	"encoding/json"
-	"io"
-	"os"
 	"time"
 )
 
-var BtnTitlesList *BtnTitles
-var MessagesList *Messages
-var BtnCategoriesList = make(map[string]string, 8)
-var BtnPeriodsList = make(map[string]string, 6)
 var Categories = [10]string{"btn_groceries", "btn_beauty", "btn_health", "btn_restaurants", "btn_entertainment",
 	"btn_growth", "btn_trips", "btn_transport", "btn_business", "btn_other"}
-var Periods = [6]string{"period_day", "period_week", "period_month", "period_quarter", "period_halfyear", "period_year"}
+var Periods = [10]string{"period_day", "period_week", "period_month", "period_quarter", "period_halfyear", "period_year",
+	"period_last7", "period_last30", "period_last90", "period_last365"}
+
+// RollingPeriods скользящие периоды: сколько последних дней, включая сегодняшний, они охватывают
+var RollingPeriods = map[string]int{
+	"period_last7":   7,
+	"period_last30":  30,
+	"period_last90":  90,
+	"period_last365": 365,
+}
 
 // Bot интерфейс для бота, поддерживающий различные мессенджеры
 type Bot interface {
@@ -27,6 +29,28 @@ type BtnTitles struct {
 
 	BtnNewExpense string `json:"btn_new_expense"`
 	BtnMyExpenses string `json:"btn_my_expenses"`
+
+	BtnPrev       string `json:"btn_prev"`
+	BtnNext       string `json:"btn_next"`
+	BtnEditAmount string `json:"btn_edit_amount"`
+	BtnDelete     string `json:"btn_delete"`
+
+	BtnShowEntries string `json:"btn_show_entries"`
+
+	BtnWallets      string `json:"btn_wallets"`
+	BtnCreateWallet string `json:"btn_create_wallet"`
+	BtnInviteMember string `json:"btn_invite_member"`
+	BtnInviteViewer string `json:"btn_invite_viewer"`
+
+	BtnBroadcastSend   string `json:"btn_broadcast_send"`
+	BtnBroadcastCancel string `json:"btn_broadcast_cancel"`
+
+	BtnLanguageAuto string `json:"btn_language_auto"`
+
+	BtnKeep string `json:"btn_keep"`
+
+	BtnCreateRule string `json:"btn_create_rule"`
+	BtnDismiss    string `json:"btn_dismiss"`
 }
 
 type Messages struct {
@@ -43,164 +67,226 @@ type Messages struct {
 	Period         string `json:"period"`
 	ErrorReg       string `json:"error_reg"`
 	UserRegistered string `json:"user_registered"`
+	ImportDone     string `json:"import_done"`
+	ImportEmpty    string `json:"import_empty"`
+	ImportError    string `json:"import_error"`
+
+	ReceiptSelectCategory string `json:"receipt_select_category"`
+	ReceiptDuplicate      string `json:"receipt_duplicate"`
+	ReceiptError          string `json:"receipt_error"`
+
+	ExpenseNote string `json:"expense_note"`
+	ExpenseTags string `json:"expense_tags"`
+	TagsReport  string `json:"tags_report"`
+	TagReport   string `json:"tag_report"`
+	TagsEmpty   string `json:"tags_empty"`
+	TagUsage    string `json:"tag_usage"`
+
+	FindUsage       string `json:"find_usage"`
+	FindTitle       string `json:"find_title"`
+	ListPage        string `json:"list_page"`
+	ListEmpty       string `json:"list_empty"`
+	ExpenseCard     string `json:"expense_card"`
+	EnterNewAmount  string `json:"enter_new_amount"`
+	ExpenseUpdated  string `json:"expense_updated"`
+	ExpenseDeleted  string `json:"expense_deleted"`
+	ExpenseNotFound string `json:"expense_not_found"`
+
+	EntriesTitle         string `json:"entries_title"`
+	EntriesCategoryTitle string `json:"entries_category_title"`
+
+	PersonalWallet  string `json:"personal_wallet"`
+	RoleOwner       string `json:"role_owner"`
+	RoleMember      string `json:"role_member"`
+	RoleViewer      string `json:"role_viewer"`
+	WalletInfo      string `json:"wallet_info"`
+	WalletInvite    string `json:"wallet_invite"`
+	WalletCreated   string `json:"wallet_created"`
+	WalletJoined    string `json:"wallet_joined"`
+	WalletReport    string `json:"wallet_report"`
+	MembersReport   string `json:"members_report"`
+	EnterWalletName string `json:"enter_wallet_name"`
+	InviteNotFound  string `json:"invite_not_found"`
+	NoWalletRights  string `json:"no_wallet_rights"`
+
+	SplitUsage        string `json:"split_usage"`
+	SplitMixed        string `json:"split_mixed"`
+	SplitTooLarge     string `json:"split_too_large"`
+	SplitUserNotFound string `json:"split_user_not_found"`
+	SplitSelf         string `json:"split_self"`
+	SplitAdded        string `json:"split_added"`
+	SplitNotify       string `json:"split_notify"`
+	BalanceTitle      string `json:"balance_title"`
+	BalanceOwesYou    string `json:"balance_owes_you"`
+	BalanceYouOwe     string `json:"balance_you_owe"`
+	BalanceEmpty      string `json:"balance_empty"`
+	SettleUsage       string `json:"settle_usage"`
+	SettleDone        string `json:"settle_done"`
+	SettleNotify      string `json:"settle_notify"`
+	SettleNothing     string `json:"settle_nothing"`
+
+	GroupHelp          string `json:"group_help"`
+	GroupOnly          string `json:"group_only"`
+	GroupAdminOnly     string `json:"group_admin_only"`
+	GroupRegister      string `json:"group_register"`
+	GroupOwnerRequired string `json:"group_owner_required"`
+	AddUsage           string `json:"add_usage"`
+	ReportUsage        string `json:"report_usage"`
+	RenameUsage        string `json:"rename_usage"`
+	WalletRenamed      string `json:"wallet_renamed"`
+
+	InlineUsage        string `json:"inline_usage"`
+	InlineRegister     string `json:"inline_register"`
+	InlineNoRights     string `json:"inline_no_rights"`
+	LogsEmpty          string `json:"logs_empty"`
+	LogsCaption        string `json:"logs_caption"`
+	LogsTruncated      string `json:"logs_truncated"`
+	StatsTitle         string `json:"stats_title"`
+	StatsUsers         string `json:"stats_users"`
+	StatsActive        string `json:"stats_active"`
+	StatsExpensesByDay string `json:"stats_expenses_by_day"`
+	StatsRegistrations string `json:"stats_registrations"`
+	StatsTopCategories string `json:"stats_top_categories"`
+	StatsRetention     string `json:"stats_retention"`
+	StatsNoData        string `json:"stats_no_data"`
+	BroadcastUsage     string `json:"broadcast_usage"`
+	BroadcastPreview   string `json:"broadcast_preview"`
+	BroadcastStarted   string `json:"broadcast_started"`
+	BroadcastCanceled  string `json:"broadcast_canceled"`
+	BroadcastNotFound  string `json:"broadcast_not_found"`
+	BroadcastDone      string `json:"broadcast_done"`
+	AccessDenied       string `json:"access_denied"`
+	GrantUsage         string `json:"grant_usage"`
+	RevokeUsage        string `json:"revoke_usage"`
+	RoleSelf           string `json:"role_self"`
+	RoleForbidden      string `json:"role_forbidden"`
+	RoleChanged        string `json:"role_changed"`
+	RolesTitle         string `json:"roles_title"`
+	RolesEmpty         string `json:"roles_empty"`
+	AuditTitle         string `json:"audit_title"`
+	RegistrationClosed string `json:"registration_closed"`
+	InviteUsage        string `json:"invite_usage"`
+	InviteCreated      string `json:"invite_created"`
+	BanUsage           string `json:"ban_usage"`
+	UnbanUsage         string `json:"unban_usage"`
+	UserBanned         string `json:"user_banned"`
+	UserUnbanned       string `json:"user_unbanned"`
+	UserNotBanned      string `json:"user_not_banned"`
+	TooManyRequests    string `json:"too_many_requests"`
+
+	ExpensesByCategory string `json:"expenses_by_category"`
+	TotalAmount        string `json:"total_amount"`
+	UserCount          string `json:"user_count"`
+	LogsReadError      string `json:"logs_read_error"`
+
+	LanguageName    string `json:"language_name"`
+	LanguageSelect  string `json:"language_select"`
+	LanguageUsage   string `json:"language_usage"`
+	LanguageChanged string `json:"language_changed"`
+
+	// Название периода с диапазоном дат: "%s (%s)"
+	PeriodDates string `json:"period_dates"`
+
+	AnalyticsTitle      string `json:"analytics_title"`
+	AnalyticsAverage    string `json:"analytics_average"`
+	AnalyticsWeekday    string `json:"analytics_weekday"`
+	AnalyticsShares     string `json:"analytics_shares"`
+	AnalyticsShare      string `json:"analytics_share"`
+	AnalyticsLargest    string `json:"analytics_largest"`
+	AnalyticsProjection string `json:"analytics_projection"`
+
+	AnomalyOutlier   string `json:"anomaly_outlier"`
+	AnomalyDuplicate string `json:"anomaly_duplicate"`
+	AnomalyKept      string `json:"anomaly_kept"`
+
+	RecurringDetected  string `json:"recurring_detected"`
+	RecurringWeekly    string `json:"recurring_weekly"`
+	RecurringMonthly   string `json:"recurring_monthly"`
+	RecurringYearly    string `json:"recurring_yearly"`
+	RecurringCreated   string `json:"recurring_created"`
+	RecurringDismissed string `json:"recurring_dismissed"`
+	RecurringBooked    string `json:"recurring_booked"`
+	RecurringTitle     string `json:"recurring_title"`
+	RecurringLine      string `json:"recurring_line"`
+	RecurringEmpty     string `json:"recurring_empty"`
+	RecurringDeleted   string `json:"recurring_deleted"`
+	RecurringNotFound  string `json:"recurring_not_found"`
+
+	// Формы множественного числа
+	ExpensesCount      Plural `json:"expenses_count"`
+	RecipientsCount    Plural `json:"recipients_count"`
+	RegistrationsCount Plural `json:"registrations_count"`
+	// Названия дней недели начиная с воскресенья, как в time.Weekday
+	Weekdays          []string `json:"weekdays"`
+	WeekStartUsage    string   `json:"week_start_usage"`
+	WeekStartChanged  string   `json:"week_start_changed"`
+	MonthStartUsage   string   `json:"month_start_usage"`
+	MonthStartChanged string   `json:"month_start_changed"`
 }
 
-func InitStringValues() error {
-	// Загружаем заголовки кнопок
-	err := loadBtnTitles()
-	if err != nil {
-		return err
-	}
-
-	// Загружаем заголовки кнопок категории
-	err = loadBtnCategories()
-	if err != nil {
-		return err
-	}
-
-	// Загружаем заголовки кнопок периоды
-	err = loadBtnPeriods()
-	if err != nil {
-		return err
-	}
-
-	// Загружаем сообщения бота
-	err = loadMessages()
-	if err != nil {
-		return err
-	}
-
-	return nil
+// PeriodOptions границы периодов пользователя
+type PeriodOptions struct {
+	// День, с которого начинается неделя
+	WeekStart time.Weekday
+	// День месяца от 1 до MaxMonthStart, с которого начинается месяц: с зарплаты 10-го месяц идет с 10-го по 9-е.
+	// От него же отсчитываются квартал, полугодие и год
+	MonthStart int
 }
 
-func loadBtnTitles() error {
-	filePath := "./config/string_values/button_titles.json"
-
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+// MaxMonthStart последний день, с которого может начинаться месяц: такой день есть в любом месяце
+const MaxMonthStart = 28
 
-	byteValue, _ := io.ReadAll(file)
+// DefaultPeriodOptions календарные периоды с неделей с понедельника
+var DefaultPeriodOptions = PeriodOptions{WeekStart: time.Monday, MonthStart: 1}
 
-	json.Unmarshal(byteValue, &BtnTitlesList)
-
-	return nil
+// GetPeriodDates возвращает начало и конец периода, в который попадает now, в миллисекундах Unix
+func GetPeriodDates(period string, now time.Time, opts PeriodOptions) (int64, int64) {
+	start, end := PeriodBounds(period, now, opts)
+	return start.UnixMilli(), end.UnixMilli()
 }
 
-func loadBtnCategories() error {
-	filePath := "./config/string_values/buttons_categories.json"
-
-	// Открываем JSON файл
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
+// PeriodBounds возвращает начало периода и последнюю наносекунду перед началом следующего
+func PeriodBounds(period string, now time.Time, opts PeriodOptions) (time.Time, time.Time) {
+	monthStart := opts.MonthStart
+	if monthStart < 1 || monthStart > MaxMonthStart {
+		monthStart = 1
 	}
-	defer file.Close()
 
-	// Декодируем JSON в мапу
-	decoder := json.NewDecoder(file)
-	if err = decoder.Decode(&BtnCategoriesList); err != nil {
-		return err
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if days, ok := RollingPeriods[period]; ok {
+		return today.AddDate(0, 0, 1-days), today.AddDate(0, 0, 1).Add(-time.Nanosecond)
 	}
 
-	return nil
-}
-
-func loadBtnPeriods() error {
-	filePath := "./config/string_values/buttons_periods.json"
-
-	// Открываем JSON файл
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
+	// Месяц, в который попадает now: до дня начала месяца еще идет предыдущий
+	month := time.Date(now.Year(), now.Month(), monthStart, 0, 0, 0, 0, now.Location())
+	if now.Day() < monthStart {
+		month = month.AddDate(0, -1, 0)
 	}
-	defer file.Close()
-
-	// Декодируем JSON в мапу
-	decoder := json.NewDecoder(file)
-	if err = decoder.Decode(&BtnPeriodsList); err != nil {
-		return err
+	// Кварталы, полугодия и год состоят из таких месяцев и начинаются с месяцев, начинающихся в январе, апреле...
+	monthsFrom := func(months int) time.Time {
+		return month.AddDate(0, -(int(month.Month())-1)%months, 0)
 	}
 
-	return nil
-}
-
-func loadMessages() error {
-	filePath := "./config/string_values/messages.json"
-
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	byteValue, _ := io.ReadAll(file)
-
-	json.Unmarshal(byteValue, &MessagesList)
-
-	return nil
-}
-
-// Функция для расчета даты начала и конца периода
-func GetPeriodDates(period string) (int64, int64) {
-	now := time.Now()
-	var startDate, endDate time.Time
-
-	currYear := now.Year()
-	currMonth := now.Month()
-	currDay := now.Day()
-
+	var start time.Time
+	var months int
 	switch period {
 	case "period_day":
-		// Начало текущего дня
-		startDate = time.Date(currYear, currMonth, currDay, 0, 0, 0, 0, now.Location())
-		// Конец текущего дня
-		endDate = time.Date(currYear, currMonth, currDay, 23, 59, 59, int(time.Nanosecond*999999999), now.Location())
+		return today, today.AddDate(0, 0, 1).Add(-time.Nanosecond)
 	case "period_week":
-		// Получаем день недели (в Go воскресенье - 0, понедельник - 1, ... суббота - 6)
-		weekday := int(now.Weekday())
-		// Смещение для начала недели (если неделя начинается с понедельника)
-		offset := (weekday + 6) % 7 // Количество дней, на которые нужно сдвинуть назад, чтобы получить понедельник
-		// Начало недели (понедельник текущей недели)
-		startDate = now.AddDate(0, 0, -offset).Truncate(24 * time.Hour)
-		// Конец недели (воскресенье текущей недели)
-		endDate = startDate.AddDate(0, 0, 6).Add(time.Hour*23 + time.Minute*59 + time.Second*59 + time.Nanosecond*999999999)
+		offset := (int(now.Weekday()) - int(opts.WeekStart) + 7) % 7
+		start = today.AddDate(0, 0, -offset)
+		return start, start.AddDate(0, 0, 7).Add(-time.Nanosecond)
 	case "period_month":
-		// Начало текущего месяца
-		startDate = time.Date(currYear, currMonth, 1, 0, 0, 0, 0, now.Location())
-		// Конец текущего месяца
-		endDate = startDate.AddDate(0, 1, -1).Add(time.Hour*23 + time.Minute*59 + time.Second*59 + time.Nanosecond*999999999)
+		start, months = month, 1
 	case "period_quarter":
-		// Определяем начало квартала
-		switch now.Month() {
-		case time.January, time.February, time.March:
-			startDate = time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
-		case time.April, time.May, time.June:
-			startDate = time.Date(now.Year(), time.April, 1, 0, 0, 0, 0, now.Location())
-		case time.July, time.August, time.September:
-			startDate = time.Date(now.Year(), time.July, 1, 0, 0, 0, 0, now.Location())
-		case time.October, time.November, time.December:
-			startDate = time.Date(now.Year(), time.October, 1, 0, 0, 0, 0, now.Location())
-		}
-		// Конец квартала — добавляем 3 месяца к началу квартала и вычитаем 1 день
-		endDate = startDate.AddDate(0, 3, -1).Add(time.Hour*23 + time.Minute*59 + time.Second*59 + time.Nanosecond*999999999)
+		start, months = monthsFrom(3), 3
 	case "period_halfyear":
-		// Определяем начало полугодия
-		if now.Month() <= time.June { // Первое полугодие: Январь - Июнь
-			startDate = time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
-		} else { // Второе полугодие: Июль - Декабрь
-			startDate = time.Date(now.Year(), time.July, 1, 0, 0, 0, 0, now.Location())
-		}
-		// Конец полугодия: добавляем 6 месяцев к началу полугодия и вычитаем 1 день
-		endDate = startDate.AddDate(0, 6, -1).Add(time.Hour*23 + time.Minute*59 + time.Second*59 + time.Nanosecond*999999999)
+		start, months = monthsFrom(6), 6
 	case "period_year":
-		// Начало года: 1 января текущего года
-		startDate = time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
-		// Конец года: 31 декабря текущего года, конец дня
-		endDate = time.Date(now.Year(), time.December, 31, 23, 59, 59, int(time.Nanosecond*999999999), now.Location())
+		start, months = monthsFrom(12), 12
+	default:
+		return time.Time{}, time.Time{}
 	}
 
-	return startDate.UnixMilli(), endDate.UnixMilli()
+	return start, start.AddDate(0, months, 0).Add(-time.Nanosecond)
 }
@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// date полночь дня в часовом поясе loc
+func date(loc *time.Location, year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+func TestPeriodBoundsWeekStart(t *testing.T) {
+	// Среда, 7 августа 2024, посреди дня
+	now := time.Date(2024, 8, 7, 15, 30, 0, 0, time.UTC)
+	want := map[time.Weekday]time.Time{
+		time.Sunday:    date(time.UTC, 2024, 8, 4),
+		time.Monday:    date(time.UTC, 2024, 8, 5),
+		time.Tuesday:   date(time.UTC, 2024, 8, 6),
+		time.Wednesday: date(time.UTC, 2024, 8, 7),
+		time.Thursday:  date(time.UTC, 2024, 8, 1),
+		time.Friday:    date(time.UTC, 2024, 8, 2),
+		time.Saturday:  date(time.UTC, 2024, 8, 3),
+	}
+
+	for weekStart := time.Sunday; weekStart <= time.Saturday; weekStart++ {
+		start, end := PeriodBounds("period_week", now, PeriodOptions{WeekStart: weekStart, MonthStart: 1})
+		if !start.Equal(want[weekStart]) || !end.Equal(want[weekStart].AddDate(0, 0, 7).Add(-time.Nanosecond)) {
+			t.Errorf("неделя с %v: %v — %v, ожидалось начало %v", weekStart, start, end, want[weekStart])
+		}
+		if start.Weekday() != weekStart {
+			t.Errorf("неделя с %v начинается в %v", weekStart, start.Weekday())
+		}
+	}
+}
+
+func TestPeriodBounds(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(year int, month time.Month, day int) time.Time {
+		return date(time.UTC, year, month, day)
+	}
+	monday := func(monthStart int) PeriodOptions {
+		return PeriodOptions{WeekStart: time.Monday, MonthStart: monthStart}
+	}
+
+	tests := []struct {
+		name   string
+		period string
+		now    time.Time
+		opts   PeriodOptions
+		start  time.Time
+		// Начало следующего периода: конец периода на наносекунду раньше
+		next time.Time
+	}{
+		{"день", "period_day", utc(2024, 8, 7).Add(23 * time.Hour), monday(1), utc(2024, 8, 7), utc(2024, 8, 8)},
+		{"последние 7 дней", "period_last7", utc(2024, 8, 7), monday(1), utc(2024, 8, 1), utc(2024, 8, 8)},
+		{"последние 30 дней через год", "period_last30", utc(2024, 1, 10), monday(1), utc(2023, 12, 12), utc(2024, 1, 11)},
+
+		// День начала месяца
+		{"месяц с 1-го", "period_month", utc(2024, 8, 1), monday(1), utc(2024, 8, 1), utc(2024, 9, 1)},
+		{"месяц с 1-го в последний день", "period_month", utc(2024, 8, 31).Add(23 * time.Hour), monday(1), utc(2024, 8, 1), utc(2024, 9, 1)},
+		{"месяц с 10-го накануне", "period_month", utc(2024, 8, 9), monday(10), utc(2024, 7, 10), utc(2024, 8, 10)},
+		{"месяц с 10-го в день начала", "period_month", utc(2024, 8, 10), monday(10), utc(2024, 8, 10), utc(2024, 9, 10)},
+		{"месяц с 10-го на следующий день", "period_month", utc(2024, 8, 11), monday(10), utc(2024, 8, 10), utc(2024, 9, 10)},
+		{"месяц с 28-го накануне", "period_month", utc(2024, 3, 27), monday(28), utc(2024, 2, 28), utc(2024, 3, 28)},
+		{"месяц с 28-го в день начала", "period_month", utc(2024, 3, 28), monday(28), utc(2024, 3, 28), utc(2024, 4, 28)},
+		{"месяц с 28-го 31-го числа", "period_month", utc(2024, 3, 31), monday(28), utc(2024, 3, 28), utc(2024, 4, 28)},
+		{"неверный день начала месяца", "period_month", utc(2024, 8, 7), monday(31), utc(2024, 8, 1), utc(2024, 9, 1)},
+		{"нулевой день начала месяца", "period_month", utc(2024, 8, 7), monday(0), utc(2024, 8, 1), utc(2024, 9, 1)},
+
+		// Переход через январь и декабрь
+		{"месяц с 10-го в начале января", "period_month", utc(2024, 1, 5), monday(10), utc(2023, 12, 10), utc(2024, 1, 10)},
+		{"месяц с 10-го в конце декабря", "period_month", utc(2024, 12, 15), monday(10), utc(2024, 12, 10), utc(2025, 1, 10)},
+		{"квартал в январе", "period_quarter", utc(2024, 1, 15), monday(1), utc(2024, 1, 1), utc(2024, 4, 1)},
+		{"квартал в декабре", "period_quarter", utc(2024, 12, 31), monday(1), utc(2024, 10, 1), utc(2025, 1, 1)},
+		{"квартал с 10-го в начале января", "period_quarter", utc(2024, 1, 5), monday(10), utc(2023, 10, 10), utc(2024, 1, 10)},
+		{"квартал с 10-го в день начала", "period_quarter", utc(2024, 4, 10), monday(10), utc(2024, 4, 10), utc(2024, 7, 10)},
+		{"полугодие в январе", "period_halfyear", utc(2024, 1, 1), monday(1), utc(2024, 1, 1), utc(2024, 7, 1)},
+		{"полугодие в декабре", "period_halfyear", utc(2024, 12, 31), monday(1), utc(2024, 7, 1), utc(2025, 1, 1)},
+		{"полугодие с 10-го накануне", "period_halfyear", utc(2024, 7, 9), monday(10), utc(2024, 1, 10), utc(2024, 7, 10)},
+		{"полугодие с 10-го в начале января", "period_halfyear", utc(2025, 1, 9), monday(10), utc(2024, 7, 10), utc(2025, 1, 10)},
+		{"год в январе", "period_year", utc(2024, 1, 1), monday(1), utc(2024, 1, 1), utc(2025, 1, 1)},
+		{"год в декабре", "period_year", utc(2024, 12, 31), monday(1), utc(2024, 1, 1), utc(2025, 1, 1)},
+		{"год с 10-го в начале января", "period_year", utc(2025, 1, 9), monday(10), utc(2024, 1, 10), utc(2025, 1, 10)},
+		{"год с 10-го в день начала", "period_year", utc(2025, 1, 10), monday(10), utc(2025, 1, 10), utc(2026, 1, 10)},
+		{"неделя через Новый год", "period_week", utc(2025, 1, 1), monday(1), utc(2024, 12, 30), utc(2025, 1, 6)},
+
+		// Февраль и високосные годы
+		{"февраль високосного года", "period_month", utc(2024, 2, 15), monday(1), utc(2024, 2, 1), utc(2024, 3, 1)},
+		{"февраль невисокосного года", "period_month", utc(2023, 2, 15), monday(1), utc(2023, 2, 1), utc(2023, 3, 1)},
+		{"29 февраля с 28-го", "period_month", utc(2024, 2, 29), monday(28), utc(2024, 2, 28), utc(2024, 3, 28)},
+		{"27 февраля с 28-го", "period_month", utc(2023, 2, 27), monday(28), utc(2023, 1, 28), utc(2023, 2, 28)},
+		{"29 февраля", "period_day", utc(2024, 2, 29), monday(1), utc(2024, 2, 29), utc(2024, 3, 1)},
+		{"неделя через 29 февраля", "period_week", utc(2024, 3, 1), monday(1), utc(2024, 2, 26), utc(2024, 3, 4)},
+		{"последние 7 дней через 29 февраля", "period_last7", utc(2024, 3, 3), monday(1), utc(2024, 2, 26), utc(2024, 3, 4)},
+		{"квартал с 28-го в феврале", "period_quarter", utc(2023, 2, 28), monday(28), utc(2023, 1, 28), utc(2023, 4, 28)},
+
+		// Переход на летнее и зимнее время: в сутках 23 и 25 часов
+		{"день перехода на летнее время", "period_day", time.Date(2024, 3, 31, 12, 0, 0, 0, berlin), monday(1), date(berlin, 2024, 3, 31), date(berlin, 2024, 4, 1)},
+		{"день перехода на зимнее время", "period_day", time.Date(2024, 10, 27, 23, 30, 0, 0, berlin), monday(1), date(berlin, 2024, 10, 27), date(berlin, 2024, 10, 28)},
+		{"неделя с переходом на летнее время", "period_week", time.Date(2024, 3, 31, 12, 0, 0, 0, berlin), monday(1), date(berlin, 2024, 3, 25), date(berlin, 2024, 4, 1)},
+		{"месяц с переходом на зимнее время", "period_month", time.Date(2024, 10, 31, 12, 0, 0, 0, berlin), monday(1), date(berlin, 2024, 10, 1), date(berlin, 2024, 11, 1)},
+		{"последние 7 дней после перехода", "period_last7", time.Date(2024, 4, 1, 0, 30, 0, 0, berlin), monday(1), date(berlin, 2024, 3, 26), date(berlin, 2024, 4, 2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := PeriodBounds(tt.period, tt.now, tt.opts)
+			if !start.Equal(tt.start) {
+				t.Errorf("начало %v, ожидалось %v", start, tt.start)
+			}
+			if want := tt.next.Add(-time.Nanosecond); !end.Equal(want) {
+				t.Errorf("конец %v, ожидался %v", end, want)
+			}
+			if tt.now.Before(start) || tt.now.After(end) {
+				t.Errorf("%v не попадает в период %v — %v", tt.now, start, end)
+			}
+		})
+	}
+}
+
+func TestPeriodBoundsDSTLength(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		day  time.Time
+		want time.Duration
+	}{
+		{date(berlin, 2024, 3, 31), 23 * time.Hour},
+		{date(berlin, 2024, 10, 27), 25 * time.Hour},
+		{date(berlin, 2024, 8, 7), 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		start, end := PeriodBounds("period_day", tt.day.Add(time.Hour), DefaultPeriodOptions)
+		if got := end.Sub(start) + time.Nanosecond; got != tt.want {
+			t.Errorf("сутки %v длятся %v, ожидалось %v", tt.day.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestPeriodBoundsUnknown(t *testing.T) {
+	start, end := PeriodBounds("period_unknown", time.Now(), DefaultPeriodOptions)
+	if !start.IsZero() || !end.IsZero() {
+		t.Errorf("неизвестный период: %v — %v", start, end)
+	}
+}
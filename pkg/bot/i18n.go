@@ -0,0 +1,479 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLanguage язык по умолчанию: на нем отвечаем пользователям с неизвестным языком,
+// и под его встроенными названиями категории хранятся в базе
+const DefaultLanguage = "ru"
+
+// Plural формы слова с числом: "%d расход", "%d расхода", "%d расходов"
+type Plural map[string]string
+
+// Format правила форматирования чисел и дат языка
+type Format struct {
+	DecimalSeparator   string `json:"decimal_separator"`
+	ThousandsSeparator string `json:"thousands_separator"`
+	// Раскладки time.Format для даты, даты со временем и короткой даты без года
+	Date      string `json:"date"`
+	DateTime  string `json:"date_time"`
+	ShortDate string `json:"short_date"`
+	// Диапазон дат из двух дат: "%s – %s"
+	DateRange string `json:"date_range"`
+}
+
+// Locale строки и правила форматирования одного языка
+type Locale struct {
+	*Messages
+	*BtnTitles
+
+	Code       string
+	Categories map[string]string
+	Periods    map[string]string
+	Format     Format
+}
+
+// stringValues загруженные строки. После загрузки не меняются, при перечитывании заменяются целиком
+type stringValues struct {
+	locales map[string]*Locale
+	// Названия встроенных категорий, под которыми они хранятся в базе: встроенные строки
+	// языка по умолчанию, на которые переопределения не влияют
+	stored map[string]string
+}
+
+var values atomic.Pointer[stringValues]
+
+// stringSource каталог со строками: в нем по подкаталогу на каждый язык
+type stringSource struct {
+	name string
+	fsys fs.FS
+}
+
+// Откуда InitStringValues загрузила строки, чтобы ReloadStringValues перечитала их оттуда же
+var (
+	defaultStrings fs.FS
+	overrideDir    string
+)
+
+// Файлы, из которых состоит язык
+var localeFiles = []struct {
+	name  string
+	value func(l *Locale) any
+}{
+	{"messages.json", func(l *Locale) any { return &l.Messages }},
+	{"button_titles.json", func(l *Locale) any { return &l.BtnTitles }},
+	{"buttons_categories.json", func(l *Locale) any { return &l.Categories }},
+	{"buttons_periods.json", func(l *Locale) any { return &l.Periods }},
+	{"format.json", func(l *Locale) any { return &l.Format }},
+}
+
+// Плейсхолдер fmt: %s, %d, %.2f, %%
+var placeholderRx = regexp.MustCompile(`%[-+# 0]*\d*(?:\.\d+)?[a-zA-Z%]`)
+
+// InitStringValues загружает встроенные строки бота и переопределяющие их файлы из dir, если он задан.
+// Файлы в dir лежат так же, как встроенные: <язык>/messages.json и т.д., и могут содержать только часть строк
+func InitStringValues(defaults fs.FS, dir string) error {
+	defaultStrings = defaults
+	overrideDir = dir
+
+	return ReloadStringValues()
+}
+
+// ReloadStringValues перечитывает строки бота. Если в них ошибка, остаются загруженные ранее
+func ReloadStringValues() error {
+	sources := []stringSource{{name: "embedded", fsys: defaultStrings}}
+	if overrideDir != "" {
+		sources = append(sources, stringSource{name: overrideDir, fsys: os.DirFS(overrideDir)})
+	}
+
+	loaded, err := loadStringValues(sources)
+	if err != nil {
+		return err
+	}
+
+	values.Store(loaded)
+	return nil
+}
+
+// loadStringValues загружает все языки и проверяет, что в каждом есть все строки, нет лишних
+// и плейсхолдеры строк совпадают со встроенными строками языка по умолчанию
+func loadStringValues(sources []stringSource) (*stringValues, error) {
+	codes := make(map[string]bool)
+	for _, source := range sources {
+		entries, err := fs.ReadDir(source.fsys, ".")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source.name, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				codes[entry.Name()] = true
+			}
+		}
+	}
+
+	locales := make(map[string]*Locale, len(codes))
+	var problems []string
+	for code := range codes {
+		locale, localeProblems, err := loadLocale(sources, code)
+		if err != nil {
+			return nil, err
+		}
+		locales[code] = locale
+		problems = append(problems, localeProblems...)
+	}
+
+	if locales[DefaultLanguage] == nil {
+		return nil, fmt.Errorf("нет строк языка по умолчанию %s", DefaultLanguage)
+	}
+	if len(problems) > 0 {
+		return nil, stringsError(problems)
+	}
+
+	reference, referenceProblems, err := loadLocale(sources[:1], DefaultLanguage)
+	if err != nil {
+		return nil, err
+	}
+	if len(referenceProblems) > 0 {
+		return nil, stringsError(referenceProblems)
+	}
+
+	for _, locale := range locales {
+		problems = append(problems, checkPlaceholders(locale, reference)...)
+	}
+	if len(problems) > 0 {
+		return nil, stringsError(problems)
+	}
+
+	return &stringValues{locales: locales, stored: reference.Categories}, nil
+}
+
+func stringsError(problems []string) error {
+	sort.Strings(problems)
+	return fmt.Errorf("ошибки в строках бота: %s", strings.Join(problems, "; "))
+}
+
+// loadLocale читает файлы языка из всех источников, более поздние источники переопределяют строки ранних.
+// Возвращает список отсутствующих и неизвестных строк
+func loadLocale(sources []stringSource, code string) (*Locale, []string, error) {
+	locale := &Locale{Code: code}
+
+	var problems []string
+	for _, file := range localeFiles {
+		required := requiredKeys(file.name)
+		merged := make(map[string]json.RawMessage, len(required))
+		found := false
+		for _, source := range sources {
+			data, err := fs.ReadFile(source.fsys, path.Join(code, file.name))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+
+			var keys map[string]json.RawMessage
+			if err = json.Unmarshal(data, &keys); err != nil {
+				return nil, nil, fmt.Errorf("%s/%s/%s: %w", source.name, code, file.name, err)
+			}
+			for key, value := range keys {
+				if !slices.Contains(required, key) {
+					problems = append(problems, fmt.Sprintf("%s/%s/%s: неизвестная строка %s", source.name, code, file.name, key))
+				}
+				merged[key] = value
+			}
+			found = true
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s/%s: нет файла", code, file.name))
+			continue
+		}
+
+		for _, key := range required {
+			if _, ok := merged[key]; !ok {
+				problems = append(problems, fmt.Sprintf("%s/%s: нет строки %s", code, file.name, key))
+			}
+		}
+
+		data, err := json.Marshal(merged)
+		if err == nil {
+			err = json.Unmarshal(data, file.value(locale))
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s/%s: %w", code, file.name, err)
+		}
+	}
+	if locale.Messages == nil {
+		return locale, problems, nil
+	}
+
+	// У каждой формы множественного числа должны быть все формы, которые различает язык
+	messages := reflect.ValueOf(locale.Messages).Elem()
+	for i := 0; i < messages.NumField(); i++ {
+		plural, ok := messages.Field(i).Interface().(Plural)
+		if !ok {
+			continue
+		}
+		key := jsonKey(messages.Type().Field(i))
+		for _, form := range pluralForms(code) {
+			if plural[form] == "" {
+				problems = append(problems, fmt.Sprintf("%s/messages.json: нет строки %s.%s", code, key, form))
+			}
+		}
+	}
+	if len(locale.Weekdays) != 7 {
+		problems = append(problems, fmt.Sprintf("%s/messages.json: в weekdays должно быть 7 дней", code))
+	}
+
+	return locale, problems, nil
+}
+
+// checkPlaceholders сравнивает плейсхолдеры строк языка со строками reference: из-за лишнего
+// или пропущенного %s в сообщении пользователю вместо значения оказался бы %!s(MISSING)
+func checkPlaceholders(locale *Locale, reference *Locale) []string {
+	var problems []string
+	check := func(file string, key string, value string, want string) {
+		if got, expected := placeholders(value), placeholders(want); !slices.Equal(got, expected) {
+			problems = append(problems, fmt.Sprintf("%s/%s: в строке %s плейсхолдеры %v вместо %v",
+				locale.Code, file, key, got, expected))
+		}
+	}
+
+	for _, s := range []struct {
+		file      string
+		value     any
+		reference any
+	}{
+		{"messages.json", locale.Messages, reference.Messages},
+		{"button_titles.json", locale.BtnTitles, reference.BtnTitles},
+	} {
+		value, want := reflect.ValueOf(s.value).Elem(), reflect.ValueOf(s.reference).Elem()
+		for i := 0; i < value.NumField(); i++ {
+			key := jsonKey(value.Type().Field(i))
+			switch field := value.Field(i).Interface().(type) {
+			case string:
+				check(s.file, key, field, want.Field(i).String())
+			case Plural:
+				wantPlural := want.Field(i).Interface().(Plural)
+				for form, text := range field {
+					check(s.file, key+"."+form, text, wantPlural[pluralForms(reference.Code)[0]])
+				}
+			}
+		}
+	}
+	for _, key := range Categories {
+		check("buttons_categories.json", key, locale.Categories[key], reference.Categories[key])
+	}
+	for _, key := range Periods {
+		check("buttons_periods.json", key, locale.Periods[key], reference.Periods[key])
+	}
+
+	return problems
+}
+
+// placeholders возвращает плейсхолдеры fmt в строке по порядку, без экранированного %%
+func placeholders(s string) []string {
+	var verbs []string
+	for _, verb := range placeholderRx.FindAllString(s, -1) {
+		if verb != "%%" {
+			verbs = append(verbs, verb)
+		}
+	}
+
+	return verbs
+}
+
+// requiredKeys возвращает ключи, которые должны быть в файле языка
+func requiredKeys(file string) []string {
+	switch file {
+	case "messages.json":
+		return structKeys(reflect.TypeOf(Messages{}))
+	case "button_titles.json":
+		return structKeys(reflect.TypeOf(BtnTitles{}))
+	case "buttons_categories.json":
+		return Categories[:]
+	case "buttons_periods.json":
+		return Periods[:]
+	case "format.json":
+		return structKeys(reflect.TypeOf(Format{}))
+	}
+
+	return nil
+}
+
+func structKeys(t reflect.Type) []string {
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		keys = append(keys, jsonKey(t.Field(i)))
+	}
+
+	return keys
+}
+
+func jsonKey(field reflect.StructField) string {
+	key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return key
+}
+
+// Locales возвращает загруженные языки по коду
+func Locales() map[string]*Locale {
+	return values.Load().locales
+}
+
+// GetLocale возвращает язык по коду из Telegram ("en", "en-US"), для неизвестного — язык по умолчанию
+func GetLocale(code string) *Locale {
+	code, _, _ = strings.Cut(strings.ToLower(code), "-")
+	locales := Locales()
+	if locale, ok := locales[code]; ok {
+		return locale
+	}
+
+	return locales[DefaultLanguage]
+}
+
+// IsLanguage сообщает, что для языка есть строки
+func IsLanguage(code string) bool {
+	_, ok := Locales()[code]
+	return ok
+}
+
+// Languages возвращает коды загруженных языков по алфавиту
+func Languages() []string {
+	locales := Locales()
+	codes := make([]string, 0, len(locales))
+	for code := range locales {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	return codes
+}
+
+// pluralForms возвращает формы множественного числа, которые различает язык
+func pluralForms(code string) []string {
+	if code == "ru" {
+		return []string{"one", "few", "many"}
+	}
+
+	return []string{"one", "other"}
+}
+
+// pluralForm выбирает форму множественного числа для n
+func pluralForm(code string, n int) string {
+	if code == "ru" {
+		n = int(math.Abs(float64(n)))
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return "one"
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return "few"
+		}
+		return "many"
+	}
+
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+// Plural подставляет n в подходящую форму множественного числа
+func (l *Locale) Plural(p Plural, n int) string {
+	return fmt.Sprintf(p[pluralForm(l.Code, n)], n)
+}
+
+// Amount форматирует сумму с разделителями разрядов и двумя знаками после запятой: 1 234,50 или 1,234.50
+func (l *Locale) Amount(amount float64) string {
+	s := strconv.FormatFloat(math.Abs(amount), 'f', 2, 64)
+	whole, fraction, _ := strings.Cut(s, ".")
+
+	var sb strings.Builder
+	if amount < 0 {
+		sb.WriteString("-")
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			sb.WriteString(l.Format.ThousandsSeparator)
+		}
+		sb.WriteRune(digit)
+	}
+	sb.WriteString(l.Format.DecimalSeparator)
+	sb.WriteString(fraction)
+
+	return sb.String()
+}
+
+// Ratio форматирует отношение двух сумм с одним знаком после запятой: "4,2"
+func (l *Locale) Ratio(ratio float64) string {
+	return strings.Replace(strconv.FormatFloat(ratio, 'f', 1, 64), ".", l.Format.DecimalSeparator, 1)
+}
+
+// Date форматирует дату
+func (l *Locale) Date(t time.Time) string {
+	return t.Format(l.Format.Date)
+}
+
+// DateTime форматирует дату со временем
+func (l *Locale) DateTime(t time.Time) string {
+	return t.Format(l.Format.DateTime)
+}
+
+// ShortDate форматирует дату без года
+func (l *Locale) ShortDate(t time.Time) string {
+	return t.Format(l.Format.ShortDate)
+}
+
+// DateRange форматирует диапазон дат; диапазон из одного дня выводится одной датой
+func (l *Locale) DateRange(start, end time.Time) string {
+	if start.Year() == end.Year() && start.YearDay() == end.YearDay() {
+		return l.Date(start)
+	}
+
+	return fmt.Sprintf(l.Format.DateRange, l.Date(start), l.Date(end))
+}
+
+// CategoryTitle переводит название категории из базы: встроенные категории хранятся
+// под названиями языка по умолчанию, собственные категории пользователей не переводятся
+func (l *Locale) CategoryTitle(category string) string {
+	if key, ok := CategoryKey(category); ok {
+		return l.Categories[key]
+	}
+
+	return category
+}
+
+// PeriodTitle возвращает название периода по ключу
+func (l *Locale) PeriodTitle(key string) string {
+	return l.Periods[key]
+}
+
+// CategoryKey возвращает ключ встроенной категории по названию, под которым она хранится в базе
+func CategoryKey(category string) (string, bool) {
+	stored := values.Load().stored
+	for _, key := range Categories {
+		if stored[key] == category {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// StoredCategory возвращает название встроенной категории, под которым она хранится в базе
+func StoredCategory(key string) string {
+	return values.Load().stored[key]
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrEmptyText     = errors.New("пустое сообщение")
+	ErrInvalidAmount = errors.New("сумма должна быть больше нуля")
+	ErrAmountFormat  = errors.New("сумма должна быть числом")
+)
+
+// Сумма в десятичной записи: ParseFloat принял бы и "NaN", "Inf", "1e3" или "0x10"
+var decimalRx = regexp.MustCompile(`^\d+(?:[.,]\d+)?$`)
+
+// ExpenseInput разобранный ввод пользователя вида "1200 такси #командировка в аэропорт"
+type ExpenseInput struct {
+	Amount   float64
+	Category string
+	Note     string
+	Tags     []string
+}
+
+// ParseExpenseText разбирает сумму, заметку и теги из сообщения пользователя
+func ParseExpenseText(text string) (ExpenseInput, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ExpenseInput{}, ErrEmptyText
+	}
+
+	amount, err := ParseAmount(fields[0])
+	if err != nil {
+		return ExpenseInput{}, err
+	}
+
+	note, tags := ParseNote(strings.Join(fields[1:], " "))
+
+	return ExpenseInput{Amount: amount, Note: note, Tags: tags}, nil
+}
+
+// ParseCommandExpense разбирает команду "/add 500 продукты молоко #дача":
+// первое слово, совпадающее с категорией, задает категорию, по умолчанию "Прочие"
+func ParseCommandExpense(text string) (ExpenseInput, error) {
+	input, err := ParseExpenseText(text)
+	if err != nil {
+		return ExpenseInput{}, err
+	}
+
+	input.Category = StoredCategory("btn_other")
+
+	words := strings.Fields(input.Note)
+	for i, word := range words {
+		if category, ok := categoryByWord(strings.ToLower(word)); ok {
+			input.Category = category
+			input.Note = strings.Join(append(words[:i:i], words[i+1:]...), " ")
+			break
+		}
+	}
+
+	return input, nil
+}
+
+// ParseAmount разбирает сумму, допускается запятая в качестве разделителя
+func ParseAmount(s string) (float64, error) {
+	if !decimalRx.MatchString(s) {
+		if strings.HasPrefix(s, "-") && decimalRx.MatchString(s[1:]) {
+			return 0, ErrInvalidAmount
+		}
+		return 0, ErrAmountFormat
+	}
+
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	if err != nil {
+		return 0, err
+	}
+	if amount <= 0 {
+		return 0, ErrInvalidAmount
+	}
+
+	return amount, nil
+}
+
+// ParseNote отделяет #теги от текста заметки
+func ParseNote(text string) (string, []string) {
+	var words, tags []string
+	seen := make(map[string]bool)
+
+	for _, word := range strings.Fields(text) {
+		if !strings.HasPrefix(word, "#") {
+			words = append(words, word)
+			continue
+		}
+
+		tag := NormalizeTag(word)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	return strings.Join(words, " "), tags
+}
+
+// NormalizeTag приводит тег к виду, в котором он хранится в базе
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.Trim(tag, "#.,;:!?"))
+}
+
+// ParsePeriod ищет период по его названию на любом языке ("неделя", "quarter", ...)
+func ParsePeriod(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, locale := range Locales() {
+		for _, key := range Periods {
+			if strings.ToLower(locale.Periods[key]) == s {
+				return key, true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"expense_accounting_bot/config"
+)
+
+func TestParseExpenseText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want ExpenseInput
+	}{
+		{"только сумма", "500", ExpenseInput{Amount: 500}},
+		{"дробная сумма с запятой", "99,90", ExpenseInput{Amount: 99.9}},
+		{"дробная сумма с точкой", "  12.5  ", ExpenseInput{Amount: 12.5}},
+		{"заметка", "1200 такси в аэропорт", ExpenseInput{Amount: 1200, Note: "такси в аэропорт"}},
+		{
+			"теги среди заметки", "1200 такси #Командировка в аэропорт #такси",
+			ExpenseInput{Amount: 1200, Note: "такси в аэропорт", Tags: []string{"командировка", "такси"}},
+		},
+		{"только теги", "300 #дача #ДАЧА, #дача!", ExpenseInput{Amount: 300, Tags: []string{"дача"}}},
+		{"пустой тег отбрасывается", "300 # кофе #!", ExpenseInput{Amount: 300, Note: "кофе"}},
+		{"лишние пробелы", "300\tкофе \n с  собой", ExpenseInput{Amount: 300, Note: "кофе с собой"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExpenseText(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExpenseText(%q) = %+v, ожидалось %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExpenseTextInvalid(t *testing.T) {
+	tests := []struct {
+		text string
+		want error
+	}{
+		{"", ErrEmptyText},
+		{" \n\t", ErrEmptyText},
+		{"0 кофе", ErrInvalidAmount},
+		{"0,00", ErrInvalidAmount},
+		{"-100 кофе", ErrInvalidAmount},
+		{"кофе 300", ErrAmountFormat},
+		{"#кофе 300", ErrAmountFormat},
+		{"12,5,0", ErrAmountFormat},
+		{"1.", ErrAmountFormat},
+		{".5", ErrAmountFormat},
+		{"+5", ErrAmountFormat},
+		{"NaN", ErrAmountFormat},
+		{"Inf", ErrAmountFormat},
+		{"1e3", ErrAmountFormat},
+		{"0x10", ErrAmountFormat},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseExpenseText(tt.text)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("ParseExpenseText(%q): ошибка %v, ожидалась %v", tt.text, err, tt.want)
+		}
+	}
+}
+
+func TestParseNote(t *testing.T) {
+	tests := []struct {
+		text string
+		note string
+		tags []string
+	}{
+		{"", "", nil},
+		{"молоко и хлеб", "молоко и хлеб", nil},
+		{"#дача", "", []string{"дача"}},
+		{"молоко #Дача, хлеб #продукты.", "молоко хлеб", []string{"дача", "продукты"}},
+		{"#a #b #a #B", "", []string{"a", "b"}},
+		// Решетка внутри слова не делает его тегом
+		{"c# и f#", "c# и f#", nil},
+		{"## #?!", "", nil},
+	}
+
+	for _, tt := range tests {
+		note, tags := ParseNote(tt.text)
+		if note != tt.note || !reflect.DeepEqual(tags, tt.tags) {
+			t.Errorf("ParseNote(%q) = %q, %q, ожидалось %q, %q", tt.text, note, tags, tt.note, tt.tags)
+		}
+	}
+}
+
+func TestParseCommandExpense(t *testing.T) {
+	if err := InitStringValues(config.StringValues(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want ExpenseInput
+	}{
+		{
+			"категория и заметка", "500 продукты молоко #дача",
+			ExpenseInput{Amount: 500, Category: StoredCategory("btn_groceries"), Note: "молоко", Tags: []string{"дача"}},
+		},
+		{
+			"категория после заметки", "500 молоко Продукты",
+			ExpenseInput{Amount: 500, Category: StoredCategory("btn_groceries"), Note: "молоко"},
+		},
+		{
+			"категория на английском", "20 taxi transport",
+			ExpenseInput{Amount: 20, Category: StoredCategory("btn_transport"), Note: "taxi"},
+		},
+		{
+			"учитывается первая категория", "500 продукты рестораны",
+			ExpenseInput{Amount: 500, Category: StoredCategory("btn_groceries"), Note: "рестораны"},
+		},
+		{"без категории", "500 молоко", ExpenseInput{Amount: 500, Category: StoredCategory("btn_other"), Note: "молоко"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCommandExpense(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCommandExpense(%q) = %+v, ожидалось %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseCommandExpense("продукты 500"); !errors.Is(err, ErrAmountFormat) {
+		t.Errorf("сумма после категории: ошибка %v, ожидалась %v", err, ErrAmountFormat)
+	}
+}
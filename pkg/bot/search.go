@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var ErrEmptyQuery = errors.New("пустой поисковый запрос")
+
+var (
+	amountRangeRx = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)-(\d+(?:[.,]\d+)?)$`)
+	amountBoundRx = regexp.MustCompile(`^([<>])(\d+(?:[.,]\d+)?)$`)
+	amountRx      = regexp.MustCompile(`^\d+(?:[.,]\d+)?$`)
+	dateRangeRx   = regexp.MustCompile(`^(\d{2}\.\d{2}\.\d{4})-(\d{2}\.\d{2}\.\d{4})$`)
+	dateRx        = regexp.MustCompile(`^\d{2}\.\d{2}\.\d{4}$`)
+	monthRx       = regexp.MustCompile(`^\d{2}\.\d{4}$`)
+	yearRx        = regexp.MustCompile(`^\d{4}$`)
+	// Сумма с разделителем разрядов вида "4 500" или "1 250 000"
+	thousandsRx = regexp.MustCompile(`\d{1,3}(?: \d{3})+`)
+)
+
+// minSearchYear первый год, который число из четырех цифр обозначает в запросе; круглые суммы вроде 2000 остаются суммами
+const minSearchYear = 2001
+
+// SearchQuery разобранный запрос команды /find, нулевые значения не ограничивают поиск
+type SearchQuery struct {
+	MinAmount  float64
+	MaxAmount  float64
+	Start      time.Time
+	End        time.Time
+	Categories []string
+	Tags       []string
+	Text       string
+}
+
+// ParseSearchQuery разбирает запрос вида "1000-5000 01.08.2024-31.08.2024 продукты #отпуск кофе";
+// периоды ("месяц", "квартал") отсчитываются от начала недели и месяца пользователя, а год вида "2024"
+// с минимального года поиска по текущий считается периодом, а не суммой
+func ParseSearchQuery(text string, opts PeriodOptions) (SearchQuery, error) {
+	var query SearchQuery
+	var words []string
+
+	now := time.Now()
+	for _, token := range strings.Fields(joinThousands(text)) {
+		lower := strings.ToLower(token)
+
+		switch {
+		case strings.HasPrefix(token, "#"):
+			if tag := NormalizeTag(token); tag != "" {
+				query.Tags = append(query.Tags, tag)
+			}
+		// Месяц проверяется до сумм: "08.2024" иначе разберется как дробная сумма
+		case monthRx.MatchString(token):
+			month, err := time.ParseInLocation("01.2006", token, time.Local)
+			if err != nil {
+				return SearchQuery{}, err
+			}
+			query.Start, query.End = month, month.AddDate(0, 1, 0).Add(-time.Nanosecond)
+		case amountRangeRx.MatchString(token):
+			match := amountRangeRx.FindStringSubmatch(token)
+			query.MinAmount, _ = ParseAmount(match[1])
+			query.MaxAmount, _ = ParseAmount(match[2])
+		case amountBoundRx.MatchString(token):
+			match := amountBoundRx.FindStringSubmatch(token)
+			amount, _ := ParseAmount(match[2])
+			if match[1] == ">" {
+				query.MinAmount = amount
+			} else {
+				query.MaxAmount = amount
+			}
+		case yearRx.MatchString(token) && isSearchYear(token, now):
+			year, _ := strconv.Atoi(token)
+			query.Start = time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+			query.End = query.Start.AddDate(1, 0, 0).Add(-time.Nanosecond)
+		case amountRx.MatchString(token):
+			amount, _ := ParseAmount(token)
+			query.MinAmount, query.MaxAmount = amount, amount
+		case dateRangeRx.MatchString(token):
+			match := dateRangeRx.FindStringSubmatch(token)
+			start, err := time.ParseInLocation("02.01.2006", match[1], time.Local)
+			if err != nil {
+				return SearchQuery{}, err
+			}
+			end, err := time.ParseInLocation("02.01.2006", match[2], time.Local)
+			if err != nil {
+				return SearchQuery{}, err
+			}
+			query.Start, query.End = start, endOfDay(end)
+		case dateRx.MatchString(token):
+			date, err := time.ParseInLocation("02.01.2006", token, time.Local)
+			if err != nil {
+				return SearchQuery{}, err
+			}
+			query.Start, query.End = date, endOfDay(date)
+		default:
+			if periodKey, ok := ParsePeriod(lower); ok {
+				query.Start, query.End = PeriodBounds(periodKey, now, opts)
+			} else if category, ok := categoryByWord(lower); ok {
+				query.Categories = append(query.Categories, category)
+			} else {
+				words = append(words, token)
+			}
+		}
+	}
+
+	query.Text = strings.Join(words, " ")
+
+	if query.MinAmount == 0 && query.MaxAmount == 0 && query.Start.IsZero() && len(query.Categories) == 0 &&
+		len(query.Tags) == 0 && query.Text == "" {
+		return SearchQuery{}, ErrEmptyQuery
+	}
+
+	return query, nil
+}
+
+// joinThousands убирает разделители разрядов только внутри отдельной суммы: число должно начинаться с начала
+// слова или после "-", "<", ">" и заканчиваться вместе со словом, диапазоном или дробной частью,
+// поэтому дата и следующая за ней сумма ("15.08.2024 500") не склеиваются
+func joinThousands(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range thousandsRx.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if start > 0 && !strings.ContainsRune(" <>-", rune(text[start-1])) {
+			continue
+		}
+		if end < len(text) && !strings.ContainsRune(" -.,", rune(text[end])) {
+			continue
+		}
+
+		b.WriteString(text[last:start])
+		b.WriteString(strings.ReplaceAll(text[start:end], " ", ""))
+		last = end
+	}
+	b.WriteString(text[last:])
+
+	return b.String()
+}
+
+// isSearchYear проверяет, что число из четырех цифр похоже на год, а не на сумму
+func isSearchYear(token string, now time.Time) bool {
+	year, err := strconv.Atoi(token)
+
+	return err == nil && year >= minSearchYear && year <= now.Year()
+}
+
+// categoryByWord ищет встроенную категорию по слову из ее названия на любом языке ("продукты", "business", ...)
+// и возвращает название, под которым она хранится в базе
+func categoryByWord(word string) (string, bool) {
+	for _, locale := range Locales() {
+		for _, key := range Categories {
+			name := strings.TrimLeftFunc(strings.ToLower(locale.Categories[key]), func(r rune) bool {
+				return !unicode.IsLetter(r)
+			})
+
+			for _, part := range strings.Split(name, "/") {
+				if strings.TrimRight(part, ".") == word {
+					return StoredCategory(key), true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+func endOfDay(date time.Time) time.Time {
+	return date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"expense_accounting_bot/config"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	if err := InitStringValues(config.StringValues(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(year int, month time.Month, d int) time.Time {
+		return date(time.Local, year, month, d)
+	}
+	opts := PeriodOptions{WeekStart: time.Monday, MonthStart: 1}
+
+	tests := []struct {
+		name  string
+		query string
+		want  SearchQuery
+	}{
+		{"сумма", "500", SearchQuery{MinAmount: 500, MaxAmount: 500}},
+		{"сумма с разрядами", "4 500", SearchQuery{MinAmount: 4500, MaxAmount: 4500}},
+		{"миллион с разрядами", "1 250 000", SearchQuery{MinAmount: 1250000, MaxAmount: 1250000}},
+		{"дробная сумма с разрядами", "1 500,50", SearchQuery{MinAmount: 1500.5, MaxAmount: 1500.5}},
+		{"диапазон с разрядами", "1 000-5 000", SearchQuery{MinAmount: 1000, MaxAmount: 5000}},
+		{"нижняя граница с разрядами", ">1 000", SearchQuery{MinAmount: 1000}},
+		{"верхняя граница", "<500", SearchQuery{MaxAmount: 500}},
+		{
+			"дата и сумма не склеиваются", "15.08.2024 500",
+			SearchQuery{MinAmount: 500, MaxAmount: 500, Start: day(2024, 8, 15), End: endOfDay(day(2024, 8, 15))},
+		},
+		{
+			"месяц и сумма не склеиваются", "08.2024 500",
+			SearchQuery{MinAmount: 500, MaxAmount: 500, Start: day(2024, 8, 1), End: day(2024, 9, 1).Add(-time.Nanosecond)},
+		},
+		{
+			"диапазон дат", "01.08.2024-31.08.2024",
+			SearchQuery{Start: day(2024, 8, 1), End: endOfDay(day(2024, 8, 31))},
+		},
+		{"год", "2024", SearchQuery{Start: day(2024, 1, 1), End: day(2025, 1, 1).Add(-time.Nanosecond)}},
+		{
+			"год и сумма не склеиваются", "2024 500",
+			SearchQuery{MinAmount: 500, MaxAmount: 500, Start: day(2024, 1, 1), End: day(2025, 1, 1).Add(-time.Nanosecond)},
+		},
+		{"круглая сумма не год", "2000", SearchQuery{MinAmount: 2000, MaxAmount: 2000}},
+		{"будущий год — сумма", "9999", SearchQuery{MinAmount: 9999, MaxAmount: 9999}},
+		{
+			"категория, теги и текст", "продукты #Отпуск кофе #еда, с собой",
+			SearchQuery{Categories: []string{StoredCategory("btn_groceries")}, Tags: []string{"отпуск", "еда"}, Text: "кофе с собой"},
+		},
+		{"текст с числом", "кофе 12 345", SearchQuery{MinAmount: 12345, MaxAmount: 12345, Text: "кофе"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSearchQuery(tt.query, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSearchQuery(%q) = %+v, ожидалось %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearchQueryPeriod(t *testing.T) {
+	if err := InitStringValues(config.StringValues(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := PeriodOptions{WeekStart: time.Monday, MonthStart: 1}
+	got, err := ParseSearchQuery("месяц", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start, end := PeriodBounds("period_month", time.Now(), opts)
+	if !got.Start.Equal(start) || !got.End.Equal(end) {
+		t.Errorf("период месяц: %v — %v, ожидалось %v — %v", got.Start, got.End, start, end)
+	}
+}
+
+func TestParseSearchQueryInvalid(t *testing.T) {
+	if err := InitStringValues(config.StringValues(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := PeriodOptions{WeekStart: time.Monday, MonthStart: 1}
+	for _, query := range []string{"", "   ", "#", "#,"} {
+		if _, err := ParseSearchQuery(query, opts); !errors.Is(err, ErrEmptyQuery) {
+			t.Errorf("ParseSearchQuery(%q): ошибка %v, ожидалась %v", query, err, ErrEmptyQuery)
+		}
+	}
+	for _, query := range []string{"32.08.2024", "15.13.2024", "13.2024", "01.08.2024-31.09.2024"} {
+		if _, err := ParseSearchQuery(query, opts); err == nil || errors.Is(err, ErrEmptyQuery) {
+			t.Errorf("ParseSearchQuery(%q): ошибка %v, ожидалась ошибка даты", query, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+var (
+	ErrNoParticipants = errors.New("не указаны участники")
+	ErrMixedSplit     = errors.New("нельзя смешивать способы разделения")
+	ErrSplitTooLarge  = errors.New("сумма долей больше суммы расхода")
+)
+
+// SplitMode способ разделения счета
+type SplitMode int
+
+const (
+	// SplitEqual поровну между плательщиком и участниками: @alice @bob
+	SplitEqual SplitMode = iota
+	// SplitShares пропорционально долям: @alice:2 @bob:1 (доля плательщика задается как @me:N)
+	SplitShares
+	// SplitExact точными суммами: @alice=1200 @bob=800, остаток приходится на плательщика
+	SplitExact
+)
+
+// Имя, которым плательщик обозначает себя при разделении по долям
+const splitPayer = "me"
+
+// SplitParticipant участник разделения: доля или точная сумма в зависимости от способа
+type SplitParticipant struct {
+	Username string
+	Value    float64
+}
+
+// SplitInput разобранная команда "/split 3000 рестораны ужин @alice @bob"
+type SplitInput struct {
+	Amount       float64
+	Category     string
+	Note         string
+	Mode         SplitMode
+	PayerShare   float64
+	Participants []SplitParticipant
+}
+
+// ParseSplitText разбирает сумму, категорию, заметку и участников разделения
+func ParseSplitText(text string) (SplitInput, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return SplitInput{}, ErrEmptyText
+	}
+
+	amount, err := ParseAmount(fields[0])
+	if err != nil {
+		return SplitInput{}, err
+	}
+
+	input := SplitInput{Amount: amount, Mode: -1, PayerShare: 1}
+	var words []string
+
+	for _, field := range fields[1:] {
+		if !strings.HasPrefix(field, "@") {
+			if category, ok := categoryByWord(strings.ToLower(field)); ok && input.Category == "" {
+				input.Category = category
+			} else {
+				words = append(words, field)
+			}
+			continue
+		}
+
+		name, value, mode, err := parseSplitParticipant(strings.TrimRight(field[1:], ",;"))
+		if err != nil {
+			return SplitInput{}, err
+		}
+		if input.Mode >= 0 && input.Mode != mode {
+			return SplitInput{}, ErrMixedSplit
+		}
+		input.Mode = mode
+
+		if strings.EqualFold(name, splitPayer) {
+			input.PayerShare = value
+			continue
+		}
+		input.Participants = append(input.Participants, SplitParticipant{Username: name, Value: value})
+	}
+
+	if len(input.Participants) == 0 {
+		return SplitInput{}, ErrNoParticipants
+	}
+	if input.Category == "" {
+		input.Category = StoredCategory("btn_other")
+	}
+	input.Note = strings.Join(words, " ")
+
+	return input, nil
+}
+
+func parseSplitParticipant(s string) (string, float64, SplitMode, error) {
+	if name, value, ok := strings.Cut(s, ":"); ok {
+		share, err := ParseAmount(value)
+		return name, share, SplitShares, err
+	}
+	if name, value, ok := strings.Cut(s, "="); ok {
+		amount, err := ParseAmount(value)
+		return name, amount, SplitExact, err
+	}
+
+	return s, 1, SplitEqual, nil
+}
+
+// Debts возвращает долг каждого участника перед плательщиком и долю самого плательщика
+func (s SplitInput) Debts() (map[string]float64, float64, error) {
+	debts := make(map[string]float64, len(s.Participants))
+	var assigned float64
+
+	switch s.Mode {
+	case SplitExact:
+		for _, p := range s.Participants {
+			debts[p.Username] += roundMoney(p.Value)
+			assigned += roundMoney(p.Value)
+		}
+	default:
+		totalShares := s.PayerShare
+		for _, p := range s.Participants {
+			totalShares += p.Value
+		}
+		for _, p := range s.Participants {
+			debt := roundMoney(s.Amount * p.Value / totalShares)
+			debts[p.Username] += debt
+			assigned += debt
+		}
+	}
+
+	payerShare := roundMoney(s.Amount - assigned)
+	if payerShare < 0 {
+		return nil, 0, ErrSplitTooLarge
+	}
+
+	return debts, payerShare, nil
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
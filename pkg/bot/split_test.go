@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"expense_accounting_bot/config"
+)
+
+func TestParseSplitText(t *testing.T) {
+	if err := InitStringValues(config.StringValues(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want SplitInput
+	}{
+		{
+			"поровну с категорией и заметкой", "3000 рестораны ужин @alice, @bob;",
+			SplitInput{
+				Amount: 3000, Category: StoredCategory("btn_restaurants"), Note: "ужин", Mode: SplitEqual, PayerShare: 1,
+				Participants: []SplitParticipant{{"alice", 1}, {"bob", 1}},
+			},
+		},
+		{
+			"категория по умолчанию", "500 @alice",
+			SplitInput{
+				Amount: 500, Category: StoredCategory("btn_other"), Mode: SplitEqual, PayerShare: 1,
+				Participants: []SplitParticipant{{"alice", 1}},
+			},
+		},
+		{
+			"по долям с долей плательщика", "3000 @alice:2 @ME:0,5 @bob:1",
+			SplitInput{
+				Amount: 3000, Category: StoredCategory("btn_other"), Mode: SplitShares, PayerShare: 0.5,
+				Participants: []SplitParticipant{{"alice", 2}, {"bob", 1}},
+			},
+		},
+		{
+			"точными суммами", "3000 такси @alice=1200 @bob=800",
+			SplitInput{
+				Amount: 3000, Category: StoredCategory("btn_other"), Note: "такси", Mode: SplitExact, PayerShare: 1,
+				Participants: []SplitParticipant{{"alice", 1200}, {"bob", 800}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSplitText(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSplitText(%q) = %+v, ожидалось %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSplitTextInvalid(t *testing.T) {
+	if err := InitStringValues(config.StringValues(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want error
+	}{
+		{"пустой текст", "  ", ErrEmptyText},
+		{"нулевая сумма", "0 @alice", ErrInvalidAmount},
+		{"без участников", "3000 ужин", ErrNoParticipants},
+		{"только плательщик", "3000 @me:2", ErrNoParticipants},
+		{"смешанные способы", "3000 @alice:2 @bob=800", ErrMixedSplit},
+		{"доля плательщика другим способом", "3000 @alice=1000 @me:1", ErrMixedSplit},
+		{"нулевая доля", "3000 @alice:0", ErrInvalidAmount},
+		{"отрицательная сумма участника", "3000 @alice=-5", ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSplitText(tt.text); !errors.Is(err, tt.want) {
+				t.Errorf("ParseSplitText(%q): ошибка %v, ожидалась %v", tt.text, err, tt.want)
+			}
+		})
+	}
+
+	// Нечисловые суммы и доли отклоняются ошибкой разбора числа
+	for _, text := range []string{"три @alice", "3000 @alice:два", "3000 @alice="} {
+		if _, err := ParseSplitText(text); err == nil {
+			t.Errorf("ParseSplitText(%q) разобран без ошибки", text)
+		}
+	}
+}
+
+func TestSplitDebts(t *testing.T) {
+	tests := []struct {
+		name  string
+		input SplitInput
+		debts map[string]float64
+		payer float64
+	}{
+		{
+			"поровну", SplitInput{Amount: 3000, Mode: SplitEqual, PayerShare: 1, Participants: []SplitParticipant{{"alice", 1}, {"bob", 1}}},
+			map[string]float64{"alice": 1000, "bob": 1000}, 1000,
+		},
+		{
+			// Копейки, не делящиеся поровну, достаются плательщику
+			"остаток плательщику", SplitInput{Amount: 100, Mode: SplitEqual, PayerShare: 1, Participants: []SplitParticipant{{"alice", 1}, {"bob", 1}}},
+			map[string]float64{"alice": 33.33, "bob": 33.33}, 33.34,
+		},
+		{
+			"остаток меньше копейки на участника", SplitInput{Amount: 0.05, Mode: SplitEqual, PayerShare: 1, Participants: []SplitParticipant{{"alice", 1}, {"bob", 1}}},
+			map[string]float64{"alice": 0.02, "bob": 0.02}, 0.01,
+		},
+		{
+			"по долям", SplitInput{Amount: 3000, Mode: SplitShares, PayerShare: 1, Participants: []SplitParticipant{{"alice", 2}, {"bob", 1}}},
+			map[string]float64{"alice": 1500, "bob": 750}, 750,
+		},
+		{
+			"доля плательщика @me", SplitInput{Amount: 300, Mode: SplitShares, PayerShare: 2, Participants: []SplitParticipant{{"alice", 1}}},
+			map[string]float64{"alice": 100}, 200,
+		},
+		{
+			"дробная доля плательщика", SplitInput{Amount: 100, Mode: SplitShares, PayerShare: 0.5, Participants: []SplitParticipant{{"alice", 1}, {"bob", 1}}},
+			map[string]float64{"alice": 40, "bob": 40}, 20,
+		},
+		{
+			"точными суммами", SplitInput{Amount: 3000, Mode: SplitExact, PayerShare: 1, Participants: []SplitParticipant{{"alice", 1200}, {"bob", 800}}},
+			map[string]float64{"alice": 1200, "bob": 800}, 1000,
+		},
+		{
+			"точные суммы на весь счет", SplitInput{Amount: 2000, Mode: SplitExact, PayerShare: 1, Participants: []SplitParticipant{{"alice", 1200}, {"bob", 800}}},
+			map[string]float64{"alice": 1200, "bob": 800}, 0,
+		},
+		{
+			"точные суммы округляются до копеек", SplitInput{Amount: 100, Mode: SplitExact, PayerShare: 1, Participants: []SplitParticipant{{"alice", 33.333}}},
+			map[string]float64{"alice": 33.33}, 66.67,
+		},
+		{
+			"повторный участник", SplitInput{Amount: 90, Mode: SplitEqual, PayerShare: 1, Participants: []SplitParticipant{{"alice", 1}, {"alice", 1}}},
+			map[string]float64{"alice": 60}, 30,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			debts, payer, err := tt.input.Debts()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(debts, tt.debts) || payer != tt.payer {
+				t.Errorf("Debts() = %v, %v, ожидалось %v, %v", debts, payer, tt.debts, tt.payer)
+			}
+		})
+	}
+}
+
+func TestSplitDebtsTooLarge(t *testing.T) {
+	input := SplitInput{Amount: 1000, Mode: SplitExact, PayerShare: 1, Participants: []SplitParticipant{{"alice", 700}, {"bob", 300.01}}}
+	if _, _, err := input.Debts(); !errors.Is(err, ErrSplitTooLarge) {
+		t.Errorf("ошибка %v, ожидалась %v", err, ErrSplitTooLarge)
+	}
+}
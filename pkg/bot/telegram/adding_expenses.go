@@ -2,7 +2,7 @@ package telegram
 
 import (
 	"fmt"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/tucnak/telebot"
@@ -13,23 +13,36 @@ import (
 )
 
 // Обработчик нажатия кнопки "Добавить расход"
-func btnNewExpenseFunc(e *ExpenseBot, menu *telebot.ReplyMarkup) func(*telebot.Callback) {
+func btnNewExpenseFunc(e *ExpenseBot) func(*telebot.Callback) {
 	return func(c *telebot.Callback) {
-		logger.L.Info(fmt.Sprintf("Нажата кнопка '%s' пользователем %s", bot.BtnTitlesList.BtnNewExpense, c.Sender.Username))
+		loc := e.tr(c.Sender.ID)
+		logger.L.Info("Нажата кнопка", "button", loc.BtnNewExpense, "user_id", c.Sender.ID, "user_name", c.Sender.Username)
 
-		menu.InlineKeyboard = nil
+		if wallet, ok := getWallet(e, c.Sender.ID); !ok || !wallet.CanEdit() {
+			e.bot.Respond(c, &telebot.CallbackResponse{Text: loc.NoWalletRights, ShowAlert: true})
+			return
+		}
 
-		createButtonsOfCategories(e, menu)
+		editBotMessageWithMenu(e, c, loc.SelectCategory, createButtonsOfCategories(e, loc))
+	}
+}
 
-		editBotMessageWithMenu(e, c, bot.MessagesList.SelectCategory, menu)
+// Обработчики кнопок категорий; в базу записывается название категории на языке по умолчанию,
+// каким бы ни был язык кнопки
+func handleCategoryButtons(e *ExpenseBot) {
+	for _, key := range bot.Categories {
+		e.handle(&telebot.InlineButton{Unique: key}, btnCategoryFunc(e, bot.StoredCategory(key)))
 	}
 }
 
-func createButtonsOfCategories(e *ExpenseBot, menu *telebot.ReplyMarkup) {
+func createButtonsOfCategories(e *ExpenseBot, loc *bot.Locale) *telebot.ReplyMarkup {
+	menu := &telebot.ReplyMarkup{}
 	row := make([]telebot.InlineButton, 0, 2)
 	for _, key := range bot.Categories {
-		value := bot.BtnCategoriesList[key]
-		addBtnOfCategory(&row, e, key, value, menu)
+		row = append(row, telebot.InlineButton{
+			Unique: key,
+			Text:   loc.Categories[key],
+		})
 
 		if len(row) == 2 {
 			menu.InlineKeyboard = append(menu.InlineKeyboard, row)
@@ -37,78 +50,92 @@ func createButtonsOfCategories(e *ExpenseBot, menu *telebot.ReplyMarkup) {
 		}
 	}
 
-	btnBack := telebot.InlineButton{
-		Unique: "btn_back",
-		Text:   bot.BtnTitlesList.BtnBack,
-	}
-	e.bot.Handle(&btnBack, btnBackFunc(e, menu, "MainMenu"))
+	btnBack := backButton(loc, "MainMenu")
 	menu.InlineKeyboard = append(menu.InlineKeyboard, []telebot.InlineButton{btnBack})
-}
-
-func addBtnOfCategory(row *[]telebot.InlineButton, e *ExpenseBot, unique string, category string, menu *telebot.ReplyMarkup) {
-	newBtn := telebot.InlineButton{
-		Unique: unique,
-		Text:   category,
-	}
 
-	e.bot.Handle(&newBtn, btnCategoryFunc(e, category, menu))
-
-	*row = append(*row, newBtn)
+	return menu
 }
 
-func btnCategoryFunc(e *ExpenseBot, category string, menu *telebot.ReplyMarkup) func(*telebot.Callback) {
+func btnCategoryFunc(e *ExpenseBot, category string) func(*telebot.Callback) {
 	return func(c *telebot.Callback) {
-		menu.InlineKeyboard = nil
-
-		e.bot.Respond(c, &telebot.CallbackResponse{Text: fmt.Sprintf(bot.MessagesList.Category, category)})
+		loc := e.tr(c.Sender.ID)
+		e.bot.Respond(c, &telebot.CallbackResponse{Text: fmt.Sprintf(loc.Category, loc.CategoryTitle(category))})
 
 		//userID := c.Sender.ID
 		//userStates[userID] = category
 
-		btnBack := telebot.InlineButton{
-			Unique: "btn_back",
-			Text:   bot.BtnTitlesList.BtnBack,
-		}
-		e.bot.Handle(&btnBack, btnBackFunc(e, menu, "SelectCategory"))
-		menu.InlineKeyboard = append(menu.InlineKeyboard, []telebot.InlineButton{btnBack})
+		btnBack := backButton(loc, "SelectCategory")
+		menu := &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{btnBack}}}
 
-		editBotMessageWithMenu(e, c, bot.MessagesList.EnterAmount, menu)
+		editBotMessageWithMenu(e, c, loc.EnterAmount, menu)
 
-		e.bot.Handle(telebot.OnText, addExpense(e, menu, c, category))
+		e.expectInput(c.Sender.ID, addExpense(e, c, category))
 	}
 }
 
-func addExpense(e *ExpenseBot, menu *telebot.ReplyMarkup, c *telebot.Callback, category string) func(m *telebot.Message) {
+func addExpense(e *ExpenseBot, c *telebot.Callback, category string) func(m *telebot.Message) {
 	return func(m *telebot.Message) {
 		//category, ok := userStates[m.Sender.ID]
 		//if !ok || category == "" {
 		//	return
 		//}
 
-		amount, err := strconv.ParseFloat(m.Text, 64)
+		loc := e.tr(m.Sender.ID)
+		input, err := bot.ParseExpenseText(m.Text)
 		if err != nil {
-			sendBotMessage(e, m, bot.MessagesList.NumberError)
+			sendBotMessage(e, m, loc.NumberError)
+			return
+		}
+		e.finishInput(m.Sender.ID)
+
+		wallet, ok := getWallet(e, c.Sender.ID)
+		if !ok || !wallet.CanEdit() {
+			sendBotMessage(e, m, loc.NoWalletRights)
+			sendMainMenu(e, m)
 			return
 		}
 
 		expense := repository.Expense{
 			Date:     time.Now(),
 			UserID:   c.Sender.ID,
+			WalletID: wallet.ID,
 			Category: category,
-			Amount:   amount,
+			Amount:   input.Amount,
+			Note:     input.Note,
+			Tags:     input.Tags,
 		}
 
-		if err = e.repo.AddExpense(expense); err != nil {
+		if expense.ID, err = e.repo.AddExpense(expense); err != nil {
 			logger.L.Error("Ошибка при добавлении расхода:", err)
 		} else {
-			msg := fmt.Sprintf(bot.MessagesList.AddedExpense, expense.Date.Format("2006-01-02 15:04:05"), expense.Category, expense.Amount)
-			sendBotMessage(e, m, msg)
+			sendBotMessage(e, m, formatAddedExpense(loc, expense))
+			checkNewExpense(e, expense)
 		}
 
-		menu.InlineKeyboard = nil
-		editBotMessageWithMenu(e, c, bot.MessagesList.EnterAmount, menu)
+		editBotMessageWithMenu(e, c, loc.EnterAmount, &telebot.ReplyMarkup{})
+
+		sendBotMessageWithMenu(e, m, loc.SelectAction, mainMenu(e, loc))
+	}
+}
+
+// Сообщение о добавленном расходе с заметкой и тегами
+func formatAddedExpense(loc *bot.Locale, expense repository.Expense) string {
+	msg := fmt.Sprintf(loc.AddedExpense, loc.DateTime(expense.Date), loc.CategoryTitle(expense.Category), loc.Amount(expense.Amount))
+	if expense.Note != "" {
+		msg += "\n" + fmt.Sprintf(loc.ExpenseNote, expense.Note)
+	}
+	if len(expense.Tags) > 0 {
+		msg += "\n" + fmt.Sprintf(loc.ExpenseTags, formatTags(expense.Tags))
+	}
+
+	return msg
+}
 
-		createButtonsMainMenu(e, menu)
-		sendBotMessageWithMenu(e, m, bot.MessagesList.SelectAction, menu)
+func formatTags(tags []string) string {
+	formatted := make([]string, len(tags))
+	for i, tag := range tags {
+		formatted[i] = "#" + tag
 	}
+
+	return strings.Join(formatted, " ")
 }
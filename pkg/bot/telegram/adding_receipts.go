@@ -0,0 +1,142 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/receipt"
+	"expense_accounting_bot/pkg/repository"
+)
+
+// pendingReceipt чек, ожидающий выбора категории, и кошелек, в котором он проверен на повтор
+type pendingReceipt struct {
+	receipt.Receipt
+	walletID int64
+}
+
+// Обработчик строки из QR-кода кассового чека
+func handleReceipt(e *ExpenseBot, m *telebot.Message) {
+	logger.L.Info("Получен QR-код чека", "user_id", m.Sender.ID, "user_name", m.Sender.Username)
+	loc := e.tr(m.Sender.ID)
+
+	r, err := receipt.Parse(m.Text)
+	if err != nil {
+		logger.L.Warning("Не удалось разобрать чек", "error", err)
+		sendBotMessage(e, m, loc.ReceiptError)
+		sendMainMenu(e, m)
+		return
+	}
+
+	wallet, ok := getWallet(e, m.Sender.ID)
+	if !ok || !wallet.CanEdit() {
+		sendBotMessage(e, m, loc.NoWalletRights)
+		sendMainMenu(e, m)
+		return
+	}
+
+	// Один чек не добавляется в кошелек дважды, даже если его отсканировали разные участники
+	isAdded, err := e.repo.IsReceiptAdded(wallet.ID, fiscalData(r))
+	if err != nil {
+		logger.L.Error("Ошибка при проверке чека:", err)
+		sendBotMessage(e, m, loc.ReceiptError)
+		sendMainMenu(e, m)
+		return
+	}
+	if isAdded {
+		sendBotMessage(e, m, loc.ReceiptDuplicate)
+		sendMainMenu(e, m)
+		return
+	}
+
+	e.mu.Lock()
+	e.receipts[m.Sender.ID] = pendingReceipt{Receipt: r, walletID: wallet.ID}
+	e.mu.Unlock()
+
+	msg := fmt.Sprintf(loc.ReceiptSelectCategory, loc.DateTime(r.Date), loc.Amount(r.Sum))
+	sendBotMessageWithMenu(e, m, msg, createButtonsOfReceiptCategories(e, loc))
+}
+
+// Обработчики кнопок категорий расхода по чеку
+func handleReceiptButtons(e *ExpenseBot) {
+	for _, key := range bot.Categories {
+		e.handle(&telebot.InlineButton{Unique: "receipt_" + key}, btnReceiptCategoryFunc(e, bot.StoredCategory(key)))
+	}
+}
+
+func createButtonsOfReceiptCategories(e *ExpenseBot, loc *bot.Locale) *telebot.ReplyMarkup {
+	menu := &telebot.ReplyMarkup{}
+	row := make([]telebot.InlineButton, 0, 2)
+	for _, key := range bot.Categories {
+		newBtn := telebot.InlineButton{
+			Unique: "receipt_" + key,
+			Text:   loc.Categories[key],
+		}
+		row = append(row, newBtn)
+
+		if len(row) == 2 {
+			menu.InlineKeyboard = append(menu.InlineKeyboard, row)
+			row = make([]telebot.InlineButton, 0, 2)
+		}
+	}
+
+	btnBack := backButton(loc, "MainMenu")
+	menu.InlineKeyboard = append(menu.InlineKeyboard, []telebot.InlineButton{btnBack})
+
+	return menu
+}
+
+func btnReceiptCategoryFunc(e *ExpenseBot, category string) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		loc := e.tr(c.Sender.ID)
+		e.bot.Respond(c, &telebot.CallbackResponse{Text: fmt.Sprintf(loc.Category, loc.CategoryTitle(category))})
+
+		e.mu.Lock()
+		r, ok := e.receipts[c.Sender.ID]
+		delete(e.receipts, c.Sender.ID)
+		e.mu.Unlock()
+
+		msg := loc.UnknownAction
+		if ok {
+			msg = addReceiptExpense(e, loc, c.Sender.ID, r, category)
+		}
+		editBotMessageWithMenu(e, c, msg, &telebot.ReplyMarkup{})
+
+		e.bot.Send(c.Sender, loc.SelectAction, mainMenu(e, loc))
+	}
+}
+
+// addReceiptExpense добавляет расход по чеку в тот кошелек, где чек проверялся на повтор,
+// даже если пользователь успел переключиться на другой
+func addReceiptExpense(e *ExpenseBot, loc *bot.Locale, userID int, r pendingReceipt, category string) string {
+	wallet, ok := getUserWallet(e, userID, r.walletID)
+	if !ok || !wallet.CanEdit() {
+		return loc.NoWalletRights
+	}
+
+	expense := repository.Expense{
+		Date:     r.Date,
+		UserID:   userID,
+		WalletID: wallet.ID,
+		Category: category,
+		Amount:   r.Sum,
+	}
+
+	err := e.repo.AddReceiptExpense(expense, fiscalData(r.Receipt))
+	if errors.Is(err, repository.ErrReceiptAdded) {
+		return loc.ReceiptDuplicate
+	}
+	if err != nil {
+		logger.L.Error("Ошибка при добавлении расхода по чеку:", err, "wallet_id", wallet.ID)
+		return loc.ReceiptError
+	}
+
+	return formatAddedExpense(loc, expense)
+}
+
+func fiscalData(r receipt.Receipt) repository.FiscalData {
+	return repository.FiscalData{FN: r.FN, FD: r.FD, FP: r.FP}
+}
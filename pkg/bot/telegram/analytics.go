@@ -0,0 +1,79 @@
+package telegram
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/analytics"
+	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/repository"
+)
+
+// Сколько крупнейших расходов показывать в аналитике
+const analyticsTopExpenses = 3
+
+// Раздел аналитики под отчетом по категориям; пустая строка, если расходов за период нет
+func getAnalyticsReport(e *ExpenseBot, loc *bot.Locale, userID int, wallet repository.Wallet, startDate, endDate int64) string {
+	expenses, err := e.repo.GetExpenseRowsUnix(wallet.ID, startDate, endDate)
+	if err != nil {
+		logger.L.Error("Ошибка при получении расходов для аналитики:", err)
+		return ""
+	}
+	if len(expenses) == 0 {
+		return ""
+	}
+
+	now := time.Now()
+	report := analytics.Analyze(expenses, time.UnixMilli(startDate), time.UnixMilli(endDate), now, analyticsTopExpenses)
+
+	var section strings.Builder
+	section.WriteString(loc.AnalyticsTitle + "\n")
+	// За один день среднее совпадает с итогом, а самый затратный день недели — с сегодняшним
+	if report.Days > 1 {
+		section.WriteString(fmt.Sprintf(loc.AnalyticsAverage, loc.Amount(report.AveragePerDay)) + "\n")
+	}
+	if report.Days >= 7 {
+		section.WriteString(fmt.Sprintf(loc.AnalyticsWeekday, loc.Weekdays[report.BusiestWeekday], loc.Amount(report.BusiestWeekdayTotal)) + "\n")
+	}
+
+	section.WriteString("\n" + loc.AnalyticsShares + "\n")
+	for _, share := range report.Shares {
+		section.WriteString(fmt.Sprintf(loc.AnalyticsShare, loc.CategoryTitle(share.Category), int(math.Round(share.Percent))) + "\n")
+	}
+
+	section.WriteString("\n" + loc.AnalyticsLargest + "\n")
+	for i, expense := range report.Largest {
+		section.WriteString(formatExpenseLine(loc, i+1, expense) + "\n")
+	}
+
+	if projection, ok := monthProjection(e, loc, userID, wallet, now); ok {
+		section.WriteString("\n" + projection)
+	}
+
+	return strings.TrimRight(section.String(), "\n")
+}
+
+// Прогноз расходов кошелька на конец текущего месяца пользователя по темпу с его начала
+func monthProjection(e *ExpenseBot, loc *bot.Locale, userID int, wallet repository.Wallet, now time.Time) (string, bool) {
+	start, end := bot.PeriodBounds("period_month", now, e.periodOptions(userID))
+
+	totals, err := e.repo.GetExpensesByPeriodUnix(wallet.ID, start.UnixMilli(), end.UnixMilli())
+	if err != nil {
+		logger.L.Error("Ошибка при получении расходов за месяц:", err)
+		return "", false
+	}
+
+	var spent float64
+	for _, sum := range totals {
+		spent += sum
+	}
+	if spent == 0 {
+		return "", false
+	}
+
+	projection := analytics.Projection(spent, start, end, now)
+	return fmt.Sprintf(loc.AnalyticsProjection, loc.Date(end), loc.Amount(projection)), true
+}
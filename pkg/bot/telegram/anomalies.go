@@ -0,0 +1,133 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/analytics"
+	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/repository"
+)
+
+const (
+	// За сколько месяцев берется история категории для поиска необычных сумм
+	anomalyHistoryMonths = 6
+	// Такой же расход в пределах этого времени считается возможным повтором
+	duplicateWindow = 5 * time.Minute
+)
+
+// checkNewExpense просит пользователя подтвердить только что добавленный расход, если он похож
+// на повтор или выбивается из его обычных трат в категории
+func checkNewExpense(e *ExpenseBot, expense repository.Expense) {
+	loc := e.tr(expense.UserID)
+
+	msg, ok := expenseAnomaly(e, loc, expense)
+	if !ok {
+		return
+	}
+
+	data := fmt.Sprintf("%d|%d", expense.WalletID, expense.ID)
+	btnKeep := telebot.InlineButton{
+		Unique: "anomaly_keep",
+		Text:   loc.BtnKeep,
+		Data:   data,
+	}
+	btnDelete := telebot.InlineButton{
+		Unique: "anomaly_delete",
+		Text:   loc.BtnDelete,
+		Data:   data,
+	}
+	menu := &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{btnKeep, btnDelete}}}
+	if _, err := e.bot.Send(&telebot.Chat{ID: int64(expense.UserID)}, msg, menu); err != nil {
+		logger.L.ErrorSendMessage(err)
+	}
+}
+
+// Текст предупреждения о расходе; повтор проверяется первым, потому что он не зависит от истории
+func expenseAnomaly(e *ExpenseBot, loc *bot.Locale, expense repository.Expense) (string, bool) {
+	category := loc.CategoryTitle(expense.Category)
+	amount := loc.Amount(expense.Amount)
+
+	similar, err := e.repo.GetSimilarExpenses(expense,
+		expense.Date.Add(-duplicateWindow).UnixMilli(), expense.Date.Add(duplicateWindow).UnixMilli())
+	if err != nil {
+		logger.L.Error("Ошибка при поиске повторов расхода:", err, "expense_id", expense.ID)
+	} else if len(similar) > 0 {
+		return fmt.Sprintf(loc.AnomalyDuplicate, amount, category, loc.DateTime(similar[0].Date)), true
+	}
+
+	history, err := e.repo.GetCategoryAmounts(expense.UserID, expense.Category, expense.ID,
+		expense.Date.AddDate(0, -anomalyHistoryMonths, 0).UnixMilli(), expense.Date.UnixMilli())
+	if err != nil {
+		logger.L.Error("Ошибка при получении истории категории:", err, "expense_id", expense.ID)
+		return "", false
+	}
+
+	median, outlier := analytics.CheckOutlier(expense.Amount, history)
+	if !outlier {
+		return "", false
+	}
+
+	return fmt.Sprintf(loc.AnomalyOutlier, amount, category, loc.Ratio(expense.Amount/median), loc.Amount(median)), true
+}
+
+// Кошелек и расход из данных кнопок предупреждения
+func anomalyExpense(e *ExpenseBot, c *telebot.Callback) (repository.Expense, bool) {
+	wallet, expense, _ := strings.Cut(c.Data, "|")
+	walletID, _ := strconv.ParseInt(wallet, 10, 64)
+	expenseID, _ := strconv.ParseInt(expense, 10, 64)
+
+	found, err := e.repo.GetExpense(walletID, expenseID)
+	if err != nil || found.UserID != c.Sender.ID {
+		return repository.Expense{}, false
+	}
+
+	return found, true
+}
+
+// Обработчики кнопок под предупреждением о расходе
+func handleAnomalyButtons(e *ExpenseBot) {
+	e.handle(&telebot.InlineButton{Unique: "anomaly_keep"}, btnAnomalyKeepFunc(e))
+	e.handle(&telebot.InlineButton{Unique: "anomaly_delete"}, btnAnomalyDeleteFunc(e))
+}
+
+// Обработчик кнопки "Оставить" под предупреждением о расходе
+func btnAnomalyKeepFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		loc := e.tr(c.Sender.ID)
+		e.bot.Respond(c)
+
+		msg := loc.AnomalyKept
+		if _, ok := anomalyExpense(e, c); !ok {
+			msg = loc.ExpenseNotFound
+		}
+		if _, err := e.bot.Edit(c.Message, msg); err != nil {
+			logger.L.ErrorEditMessage(err)
+		}
+	}
+}
+
+// Обработчик кнопки "Удалить" под предупреждением о расходе
+func btnAnomalyDeleteFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		loc := e.tr(c.Sender.ID)
+		e.bot.Respond(c)
+
+		msg := loc.ExpenseDeleted
+		expense, ok := anomalyExpense(e, c)
+		if !ok {
+			msg = loc.ExpenseNotFound
+		} else if err := e.repo.DeleteExpense(expense.WalletID, expense.ID); err != nil {
+			logger.L.Error("Ошибка при удалении расхода:", err)
+			msg = loc.ExpenseNotFound
+		}
+		if _, err := e.bot.Edit(c.Message, msg); err != nil {
+			logger.L.ErrorEditMessage(err)
+		}
+	}
+}
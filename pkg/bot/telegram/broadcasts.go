@@ -0,0 +1,274 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/repository"
+)
+
+const (
+	// Пауза между сообщениями рассылки: Telegram допускает около 30 сообщений в секунду
+	broadcastInterval = 40 * time.Millisecond
+	// Сколько получателей читается из базы за раз
+	broadcastBatchSize = 100
+	// Сколько раз повторяется отправка после ответа Too Many Requests
+	broadcastRetries = 3
+)
+
+// Пауза, которую Telegram просит выдержать при превышении лимита: "retry after 5"
+var retryAfterRx = regexp.MustCompile(`retry after (\d+)`)
+
+// Обработчик команды /broadcast текст — предпросмотр рассылки всем пользователям с подтверждением
+func cmdBroadcast(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		text := commandText(m)
+		if text == "" {
+			sendBotMessage(e, m, loc.BroadcastUsage)
+			return
+		}
+
+		recipients, err := e.repo.GetActiveUserTotal()
+		if err != nil {
+			logger.L.Error("Ошибка при подсчете получателей рассылки:", err)
+			return
+		}
+
+		broadcastID, err := e.repo.CreateBroadcast(text, m.Sender.ID)
+		if err != nil {
+			logger.L.Error("Ошибка при создании рассылки:", err)
+			return
+		}
+
+		data := strconv.FormatInt(broadcastID, 10)
+		btnSend := telebot.InlineButton{
+			Unique: "broadcast_send",
+			Text:   loc.BtnBroadcastSend,
+			Data:   data,
+		}
+		btnCancel := telebot.InlineButton{
+			Unique: "broadcast_cancel",
+			Text:   loc.BtnBroadcastCancel,
+			Data:   data,
+		}
+		// Текст отправляется отдельным сообщением, чтобы было видно, как его получат пользователи
+		sendBotMessage(e, m, fmt.Sprintf(loc.BroadcastPreview, loc.Plural(loc.RecipientsCount, recipients)))
+		preview := &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{btnSend, btnCancel}}}
+		if _, err = e.bot.Send(m.Chat, text, preview); err != nil {
+			logger.L.ErrorSendMessage(err)
+		}
+	}
+}
+
+// Кнопки подтверждения и отмены рассылки доступны только администраторам
+func handleBroadcastButtons(e *ExpenseBot) {
+	e.guardCallback(&telebot.InlineButton{Unique: "broadcast_send"}, repository.UserRoleAdmin, btnBroadcastSendFunc(e))
+	e.guardCallback(&telebot.InlineButton{Unique: "broadcast_cancel"}, repository.UserRoleAdmin, btnBroadcastCancelFunc(e))
+}
+
+// Обработчик кнопки подтверждения рассылки
+func btnBroadcastSendFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		loc := e.tr(c.Sender.ID)
+		broadcastID, _ := strconv.ParseInt(c.Data, 10, 64)
+		if err := e.repo.StartBroadcast(broadcastID); err != nil {
+			if !errors.Is(err, repository.ErrBroadcastNotFound) {
+				logger.L.Error("Ошибка при запуске рассылки:", err)
+			}
+			e.bot.Respond(c, &telebot.CallbackResponse{Text: loc.BroadcastNotFound, ShowAlert: true})
+			return
+		}
+
+		logger.L.Info("Запущена рассылка", "broadcast_id", broadcastID, "user_id", c.Sender.ID)
+		e.bot.Respond(c)
+		if _, err := e.bot.Edit(c.Message, fmt.Sprintf(loc.BroadcastStarted, broadcastID)); err != nil {
+			logger.L.ErrorEditMessage(err)
+		}
+
+		e.runBroadcast(broadcastID)
+	}
+}
+
+// Обработчик кнопки отмены рассылки
+func btnBroadcastCancelFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		loc := e.tr(c.Sender.ID)
+		broadcastID, _ := strconv.ParseInt(c.Data, 10, 64)
+		if err := e.repo.CancelBroadcast(broadcastID); err != nil {
+			if !errors.Is(err, repository.ErrBroadcastNotFound) {
+				logger.L.Error("Ошибка при отмене рассылки:", err)
+			}
+			e.bot.Respond(c, &telebot.CallbackResponse{Text: loc.BroadcastNotFound, ShowAlert: true})
+			return
+		}
+
+		e.bot.Respond(c)
+		if _, err := e.bot.Edit(c.Message, loc.BroadcastCanceled); err != nil {
+			logger.L.ErrorEditMessage(err)
+		}
+	}
+}
+
+// resumeBroadcasts продолжает рассылки, прерванные остановкой бота
+func resumeBroadcasts(e *ExpenseBot) {
+	broadcasts, err := e.repo.GetBroadcastsByStatus(repository.BroadcastSending)
+	if err != nil {
+		logger.L.Error("Ошибка при получении незавершенных рассылок:", err)
+		return
+	}
+
+	for _, b := range broadcasts {
+		logger.L.Info("Продолжается рассылка", "broadcast_id", b.ID)
+		e.runBroadcast(b.ID)
+	}
+}
+
+// runBroadcast отправляет рассылку оставшимся получателям в фоне. При остановке бота отправка прерывается,
+// а неотправленные получатели остаются в очереди до следующего запуска
+func (e *ExpenseBot) runBroadcast(broadcastID int64) {
+	e.tasks.spawn(func() {
+		if err := sendBroadcast(e, broadcastID); err != nil {
+			logger.L.Error("Ошибка при отправке рассылки:", err, "broadcast_id", broadcastID)
+		}
+	})
+}
+
+func sendBroadcast(e *ExpenseBot, broadcastID int64) error {
+	ticker := time.NewTicker(broadcastInterval)
+	defer ticker.Stop()
+
+	for {
+		broadcast, err := e.repo.GetBroadcast(broadcastID)
+		if err != nil {
+			return err
+		}
+		if broadcast.Status != repository.BroadcastSending {
+			return nil
+		}
+
+		deliveries, err := e.repo.GetPendingDeliveries(broadcastID, broadcastBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			if err = e.repo.FinishBroadcast(broadcastID); err != nil {
+				return err
+			}
+			reportBroadcast(e, broadcast)
+			return nil
+		}
+
+		for _, delivery := range deliveries {
+			select {
+			case <-e.stopping:
+				return nil
+			case <-ticker.C:
+			}
+
+			// Рассылку могли отменить, пока она отправлялась: после отмены не уходит ни одно сообщение
+			if canceled, err := broadcastCanceled(e, broadcastID); err != nil || canceled {
+				return err
+			}
+
+			status, errText := deliverBroadcast(e, delivery.ChatID, broadcast.Text)
+			if status == repository.DeliveryPending {
+				return nil
+			}
+			if err = e.repo.SetDeliveryStatus(broadcastID, delivery.UserID, status, errText); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// broadcastCanceled проверяет, что рассылка больше не отправляется: ее отменили или она завершена
+func broadcastCanceled(e *ExpenseBot, broadcastID int64) (bool, error) {
+	broadcast, err := e.repo.GetBroadcast(broadcastID)
+	if err != nil {
+		return false, err
+	}
+
+	return broadcast.Status != repository.BroadcastSending, nil
+}
+
+// deliverBroadcast отправляет сообщение рассылки в чат и возвращает состояние доставки.
+// DeliveryPending означает, что бот останавливается и отправку нужно повторить после запуска
+func deliverBroadcast(e *ExpenseBot, chatID int64, text string) (string, string) {
+	var err error
+	for attempt := 0; attempt < broadcastRetries; attempt++ {
+		if _, err = e.bot.Send(&telebot.Chat{ID: chatID}, text); err == nil {
+			return repository.DeliverySent, ""
+		}
+
+		if isBlockedError(err) {
+			return repository.DeliveryBlocked, err.Error()
+		}
+
+		wait, ok := retryAfter(err)
+		if !ok {
+			break
+		}
+
+		select {
+		case <-e.stopping:
+			return repository.DeliveryPending, ""
+		case <-time.After(wait):
+		}
+	}
+
+	return repository.DeliveryFailed, err.Error()
+}
+
+// Итоги рассылки для ее автора
+func reportBroadcast(e *ExpenseBot, broadcast repository.Broadcast) {
+	stats, err := e.repo.GetBroadcastStats(broadcast.ID)
+	if err != nil {
+		logger.L.Error("Ошибка при подсчете итогов рассылки:", err)
+		return
+	}
+
+	logger.L.Info("Рассылка завершена", "broadcast_id", broadcast.ID, "sent", stats.Sent, "blocked", stats.Blocked, "failed", stats.Failed)
+
+	loc := e.tr(broadcast.CreatedBy)
+	msg := fmt.Sprintf(loc.BroadcastDone, broadcast.ID, stats.Sent, stats.Blocked, stats.Failed)
+	if _, err = e.bot.Send(&telebot.Chat{ID: int64(broadcast.CreatedBy)}, msg); err != nil {
+		logger.L.ErrorSendMessage(err)
+	}
+}
+
+// isBlockedError сообщает, что пользователь заблокировал бота, удалил аккаунт или ни разу не открывал чат с ним
+func isBlockedError(err error) bool {
+	text := err.Error()
+	return strings.Contains(text, "Forbidden") || strings.Contains(text, "chat not found")
+}
+
+// retryAfter возвращает паузу, которую Telegram просит выдержать перед повтором
+func retryAfter(err error) (time.Duration, bool) {
+	match := retryAfterRx.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0, false
+	}
+
+	seconds, _ := strconv.Atoi(match[1])
+	return time.Duration(seconds+1) * time.Second, true
+}
+
+// commandText возвращает весь текст после команды вместе с переносами строк:
+// Payload в telebot содержит только первую строку
+func commandText(m *telebot.Message) string {
+	i := strings.IndexFunc(m.Text, unicode.IsSpace)
+	if i < 0 {
+		return ""
+	}
+
+	return strings.TrimSpace(m.Text[i:])
+}
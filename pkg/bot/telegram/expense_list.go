@@ -0,0 +1,337 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/repository"
+)
+
+const (
+	// Количество расходов на одной странице списка
+	expensesPageSize = 10
+	// Длина страницы списка: лимит Telegram в 4096 символов с запасом для приписки вроде "Расход не найден"
+	maxListLength = 4000
+	// Заголовок, категория, заметка и теги обрезаются, чтобы в сообщение помещался хотя бы один расход
+	maxNoteLength = 100
+)
+
+// expenseList открытый пользователем постраничный список расходов кошелька
+type expenseList struct {
+	title  string
+	filter repository.ExpenseFilter
+	page   int
+	// Количество расходов на странице; уменьшается, если страница не поместилась в сообщение
+	pageSize int
+	canEdit  bool
+}
+
+// Открытие нового списка расходов текущего кошелька пользователя
+func openExpenseList(e *ExpenseBot, userID int, title string, filter repository.ExpenseFilter) {
+	wallet, _ := getWallet(e, userID)
+
+	filter.WalletID = wallet.ID
+
+	e.mu.Lock()
+	e.lists[userID] = expenseList{
+		title:    truncateText(title, maxNoteLength),
+		filter:   filter,
+		pageSize: expensesPageSize,
+		canEdit:  wallet.CanEdit(),
+	}
+	e.mu.Unlock()
+}
+
+// Формирование страницы списка расходов с кнопками навигации и редактирования
+func renderExpenseList(e *ExpenseBot, userID int, page int) (string, *telebot.ReplyMarkup) {
+	loc := e.tr(userID)
+
+	e.mu.Lock()
+	list, ok := e.lists[userID]
+	e.mu.Unlock()
+	if !ok {
+		return loc.SelectAction, mainMenu(e, loc)
+	}
+
+	if page < 0 {
+		page = 0
+	}
+	list.filter.Limit = list.pageSize
+	list.filter.Offset = page * list.pageSize
+
+	expenses, total, err := e.repo.SearchExpenses(list.filter)
+	if err != nil {
+		logger.L.Error("Ошибка при поиске расходов:", err)
+	}
+
+	pages := (total + list.pageSize - 1) / list.pageSize
+	// После удаления расходов страница могла опустеть
+	if len(expenses) == 0 && page > 0 && page >= pages {
+		return renderExpenseList(e, userID, pages-1)
+	}
+
+	list.page = page
+	e.mu.Lock()
+	e.lists[userID] = list
+	e.mu.Unlock()
+
+	menu := &telebot.ReplyMarkup{}
+	if total == 0 {
+		addBackToMenuButton(loc, menu)
+		return list.title + "\n\n" + loc.ListEmpty, menu
+	}
+
+	var text strings.Builder
+	text.WriteString(list.title + "\n")
+	text.WriteString(fmt.Sprintf(loc.ListPage, page+1, pages, loc.Plural(loc.ExpensesCount, total)) + "\n\n")
+	for i, expense := range expenses {
+		text.WriteString(formatExpenseLine(loc, list.filter.Offset+i+1, expense) + "\n")
+	}
+
+	// Страница не помещается в сообщение: расходов на страницах становится меньше,
+	// а первый расход текущей страницы остается на открытой странице
+	if messageLength(text.String()) > maxListLength && list.pageSize > 1 {
+		list.pageSize--
+		e.mu.Lock()
+		e.lists[userID] = list
+		e.mu.Unlock()
+
+		return renderExpenseList(e, userID, list.filter.Offset/list.pageSize)
+	}
+
+	row := make([]telebot.InlineButton, 0, 5)
+	for i, expense := range expenses {
+		if !list.canEdit {
+			break
+		}
+
+		number := list.filter.Offset + i + 1
+		btnEdit := telebot.InlineButton{
+			Unique: "expense_edit",
+			Text:   "✏️ " + strconv.Itoa(number),
+			Data:   strconv.FormatInt(expense.ID, 10),
+		}
+		row = append(row, btnEdit)
+
+		if len(row) == 5 {
+			menu.InlineKeyboard = append(menu.InlineKeyboard, row)
+			row = make([]telebot.InlineButton, 0, 5)
+		}
+	}
+	if len(row) > 0 {
+		menu.InlineKeyboard = append(menu.InlineKeyboard, row)
+	}
+
+	var navigation []telebot.InlineButton
+	if page > 0 {
+		navigation = append(navigation, listPageButton(loc.BtnPrev, page-1))
+	}
+	if page+1 < pages {
+		navigation = append(navigation, listPageButton(loc.BtnNext, page+1))
+	}
+	if len(navigation) > 0 {
+		menu.InlineKeyboard = append(menu.InlineKeyboard, navigation)
+	}
+
+	addBackToMenuButton(loc, menu)
+
+	return text.String(), menu
+}
+
+// Обработчики кнопок списка расходов
+func handleExpenseListButtons(e *ExpenseBot) {
+	e.handle(&telebot.InlineButton{Unique: "list_page"}, btnListPageFunc(e))
+	e.handle(&telebot.InlineButton{Unique: "expense_edit"}, btnExpenseEditFunc(e))
+	e.handle(&telebot.InlineButton{Unique: "expense_amount"}, btnExpenseAmountFunc(e))
+	e.handle(&telebot.InlineButton{Unique: "expense_delete"}, btnExpenseDeleteFunc(e))
+}
+
+func listPageButton(title string, page int) telebot.InlineButton {
+	return telebot.InlineButton{
+		Unique: "list_page",
+		Text:   title,
+		Data:   strconv.Itoa(page),
+	}
+}
+
+func addBackToMenuButton(loc *bot.Locale, menu *telebot.ReplyMarkup) {
+	btnBack := backButton(loc, "MainMenu")
+	menu.InlineKeyboard = append(menu.InlineKeyboard, []telebot.InlineButton{btnBack})
+}
+
+// Строка списка: номер, дата, категория, сумма, заметка и теги
+func formatExpenseLine(loc *bot.Locale, number int, expense repository.Expense) string {
+	category := truncateText(loc.CategoryTitle(expense.Category), maxNoteLength)
+	line := fmt.Sprintf("%d. %s %s — %s", number, loc.DateTime(expense.Date), category, loc.Amount(expense.Amount))
+
+	details := truncateText(expense.Note, maxNoteLength)
+	if len(expense.Tags) > 0 {
+		details = strings.TrimSpace(details + " " + truncateText(formatTags(expense.Tags), maxNoteLength))
+	}
+	if details != "" {
+		line += "\n    " + details
+	}
+
+	return line
+}
+
+// Длина текста так, как ее считает Telegram, — в кодовых единицах UTF-16
+func messageLength(text string) int {
+	return len(utf16.Encode([]rune(text)))
+}
+
+func truncateText(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	runes := []rune(text)
+	return string(runes[:limit-1]) + "…"
+}
+
+// Обработчик кнопок перелистывания списка
+func btnListPageFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		e.bot.Respond(c)
+
+		page, _ := strconv.Atoi(c.Data)
+		msg, menu := renderExpenseList(e, c.Sender.ID, page)
+		editBotMessageWithMenu(e, c, msg, menu)
+	}
+}
+
+// Обработчик кнопки редактирования расхода из списка
+func btnExpenseEditFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		e.bot.Respond(c)
+
+		loc := e.tr(c.Sender.ID)
+
+		expenseID, _ := strconv.ParseInt(c.Data, 10, 64)
+		expense, err := e.repo.GetExpense(listWalletID(e, c.Sender.ID), expenseID)
+		if err != nil {
+			logger.L.Error("Ошибка при получении расхода:", err)
+			msg, menu := renderExpenseList(e, c.Sender.ID, currentListPage(e, c.Sender.ID))
+			editBotMessageWithMenu(e, c, loc.ExpenseNotFound+"\n\n"+msg, menu)
+			return
+		}
+
+		data := strconv.FormatInt(expense.ID, 10)
+		btnAmount := telebot.InlineButton{
+			Unique: "expense_amount",
+			Text:   loc.BtnEditAmount,
+			Data:   data,
+		}
+		btnDelete := telebot.InlineButton{
+			Unique: "expense_delete",
+			Text:   loc.BtnDelete,
+			Data:   data,
+		}
+		btnBack := listPageButton(loc.BtnBack, currentListPage(e, c.Sender.ID))
+
+		menu := &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{
+			{btnAmount, btnDelete},
+			{btnBack},
+		}}
+
+		editBotMessageWithMenu(e, c, formatExpenseCard(loc, expense), menu)
+	}
+}
+
+func formatExpenseCard(loc *bot.Locale, expense repository.Expense) string {
+	msg := fmt.Sprintf(loc.ExpenseCard, loc.DateTime(expense.Date), loc.CategoryTitle(expense.Category), loc.Amount(expense.Amount))
+	if expense.Note != "" {
+		msg += "\n" + fmt.Sprintf(loc.ExpenseNote, expense.Note)
+	}
+	if len(expense.Tags) > 0 {
+		msg += "\n" + fmt.Sprintf(loc.ExpenseTags, formatTags(expense.Tags))
+	}
+
+	return msg
+}
+
+func currentListPage(e *ExpenseBot, userID int) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.lists[userID].page
+}
+
+// Кошелек открытого списка; 0, если список открыт только для просмотра
+func listWalletID(e *ExpenseBot, userID int) int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	list := e.lists[userID]
+	if !list.canEdit {
+		return 0
+	}
+
+	return list.filter.WalletID
+}
+
+// Обработчик кнопки удаления расхода
+func btnExpenseDeleteFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		loc := e.tr(c.Sender.ID)
+		msg := loc.ExpenseDeleted
+
+		expenseID, _ := strconv.ParseInt(c.Data, 10, 64)
+		if err := e.repo.DeleteExpense(listWalletID(e, c.Sender.ID), expenseID); err != nil {
+			logger.L.Error("Ошибка при удалении расхода:", err)
+			msg = loc.ExpenseNotFound
+		}
+		e.bot.Respond(c, &telebot.CallbackResponse{Text: msg})
+
+		list, menu := renderExpenseList(e, c.Sender.ID, currentListPage(e, c.Sender.ID))
+		editBotMessageWithMenu(e, c, list, menu)
+	}
+}
+
+// Обработчик кнопки изменения суммы расхода
+func btnExpenseAmountFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		e.bot.Respond(c)
+
+		loc := e.tr(c.Sender.ID)
+
+		expenseID, _ := strconv.ParseInt(c.Data, 10, 64)
+
+		btnBack := listPageButton(loc.BtnBack, currentListPage(e, c.Sender.ID))
+		menu := &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{btnBack}}}
+
+		editBotMessageWithMenu(e, c, loc.EnterNewAmount, menu)
+
+		e.expectInput(c.Sender.ID, editExpenseAmount(e, c, expenseID))
+	}
+}
+
+func editExpenseAmount(e *ExpenseBot, c *telebot.Callback, expenseID int64) func(m *telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		amount, err := bot.ParseAmount(strings.TrimSpace(m.Text))
+		if err != nil {
+			sendBotMessage(e, m, loc.NumberError)
+			return
+		}
+
+		e.finishInput(m.Sender.ID)
+		if err = e.repo.UpdateExpenseAmount(listWalletID(e, m.Sender.ID), expenseID, amount); err != nil {
+			logger.L.Error("Ошибка при изменении расхода:", err)
+			sendBotMessage(e, m, loc.ExpenseNotFound)
+		} else {
+			sendBotMessage(e, m, fmt.Sprintf(loc.ExpenseUpdated, loc.Amount(amount)))
+		}
+
+		editBotMessageWithMenu(e, c, loc.EnterNewAmount, &telebot.ReplyMarkup{})
+
+		sendMainMenu(e, m)
+	}
+}
@@ -2,91 +2,231 @@ package telegram
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/tucnak/telebot"
 
 	"expense_accounting_bot/internal/utils/logger"
 	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/repository"
 )
 
 // Обработчик нажатия кнопки "Мои расходы"
-func btnMyExpensesFunc(e *ExpenseBot, menu *telebot.ReplyMarkup) func(*telebot.Callback) {
+func btnMyExpensesFunc(e *ExpenseBot) func(*telebot.Callback) {
 	return func(c *telebot.Callback) {
-		logger.L.Info(fmt.Sprintf("Нажата кнопка '%s' пользователем %s", bot.BtnTitlesList.BtnMyExpenses, c.Sender.Username))
+		loc := e.tr(c.Sender.ID)
+		logger.L.Info("Нажата кнопка", "button", loc.BtnMyExpenses, "user_id", c.Sender.ID, "user_name", c.Sender.Username)
 
-		menu.InlineKeyboard = nil
+		editBotMessageWithMenu(e, c, loc.SelectPeriod, createButtonsOfPeriods(e, loc))
+	}
+}
 
-		createButtonsOfPeriods(e, menu)
-		editBotMessageWithMenu(e, c, bot.MessagesList.SelectPeriod, menu)
+// Обработчики кнопок периодов и кнопки "Показать записи"
+func handlePeriodButtons(e *ExpenseBot) {
+	for _, key := range bot.Periods {
+		e.handle(&telebot.InlineButton{Unique: key}, btnPeriodFunc(e, key))
 	}
+	e.handle(&telebot.InlineButton{Unique: "show_entries"}, btnShowEntriesFunc(e))
 }
 
-func createButtonsOfPeriods(e *ExpenseBot, menu *telebot.ReplyMarkup) {
+// Кнопки периодов по две в ряд: календарные периоды, затем скользящие
+func createButtonsOfPeriods(e *ExpenseBot, loc *bot.Locale) *telebot.ReplyMarkup {
+	menu := &telebot.ReplyMarkup{}
+	row := make([]telebot.InlineButton, 0, 2)
 	for _, key := range bot.Periods {
-		value := bot.BtnPeriodsList[key]
 		newBtn := telebot.InlineButton{
 			Unique: key,
-			Text:   value,
+			Text:   loc.Periods[key],
 		}
-		e.bot.Handle(&newBtn, btnPeriodFunc(e, key, value, menu))
 
-		menu.InlineKeyboard = append(menu.InlineKeyboard, []telebot.InlineButton{newBtn})
+		row = append(row, newBtn)
+		if len(row) == 2 {
+			menu.InlineKeyboard = append(menu.InlineKeyboard, row)
+			row = make([]telebot.InlineButton, 0, 2)
+		}
 	}
-
-	btnBack := telebot.InlineButton{
-		Unique: "btn_back",
-		Text:   bot.BtnTitlesList.BtnBack,
+	if len(row) > 0 {
+		menu.InlineKeyboard = append(menu.InlineKeyboard, row)
 	}
-	e.bot.Handle(&btnBack, btnBackFunc(e, menu, "MainMenu"))
+
+	btnBack := backButton(loc, "MainMenu")
 	menu.InlineKeyboard = append(menu.InlineKeyboard, []telebot.InlineButton{btnBack})
+
+	return menu
 }
 
-func btnPeriodFunc(e *ExpenseBot, period_key string, period string, menu *telebot.ReplyMarkup) func(callback *telebot.Callback) {
+func btnPeriodFunc(e *ExpenseBot, period_key string) func(callback *telebot.Callback) {
 	return func(c *telebot.Callback) {
-		e.bot.Respond(c, &telebot.CallbackResponse{Text: fmt.Sprintf(bot.MessagesList.Period, period)})
-
-		menu.InlineKeyboard = nil
+		loc := e.tr(c.Sender.ID)
+		period := loc.PeriodTitle(period_key)
+		e.bot.Respond(c, &telebot.CallbackResponse{Text: fmt.Sprintf(loc.Period, period)})
 
 		userID := c.Sender.ID
-		report := getExpensesByPeriod(e, userID, period_key, period)
-		editBotMessageWithMenu(e, c, report, menu)
+		report, analyticsReport, expenses := getExpensesByPeriod(e, loc, userID, period_key)
+		editBotMessageWithMenu(e, c, report, createButtonsOfEntries(e, loc, period_key, expenses))
+		if analyticsReport != "" {
+			if _, err := e.bot.Send(c.Sender, analyticsReport); err != nil {
+				logger.L.ErrorSendMessage(err)
+			}
+		}
 
-		createButtonsMainMenu(e, menu)
-		e.bot.Send(c.Sender, bot.MessagesList.SelectAction, menu)
+		e.bot.Send(c.Sender, loc.SelectAction, mainMenu(e, loc))
 	}
 }
 
 // Функция для обработки запроса по расходам в зависимости от периода
-func getExpensesByPeriod(e *ExpenseBot, userID int, period_key string, period string) string {
+func getExpensesByPeriod(e *ExpenseBot, loc *bot.Locale, userID int, period_key string) (string, string, map[string]float64) {
+
+	wallet, ok := getWallet(e, userID)
+	if !ok {
+		return loc.ErrorReg, "", nil
+	}
+
+	return getWalletReport(e, loc, userID, wallet, period_key)
+}
 
+// Отчет по категориям кошелька за период; границы периода берутся из настроек пользователя userID.
+// Аналитика возвращается отдельно и отправляется отдельным сообщением: вместе с отчетом по категориям
+// и участникам она может не поместиться в одно сообщение Telegram
+func getWalletReport(e *ExpenseBot, loc *bot.Locale, userID int, wallet repository.Wallet, period_key string) (string, string, map[string]float64) {
 	// Получаем дату начала и конца периода
-	startDate, endDate := bot.GetPeriodDates(period_key)
+	startDate, endDate := e.periodDates(userID, period_key)
 
 	// Получаем данные о расходах из базы данных
-	expenses, err := e.repo.GetExpensesByPeriodUnix(userID, startDate, endDate)
+	expenses, err := e.repo.GetExpensesByPeriodUnix(wallet.ID, startDate, endDate)
 	if err != nil {
 		logger.L.Error("Ошибка при получении данных.", err)
-		return ""
+		return "", "", nil
 	}
 
 	// Формируем сообщение с результатами
-	report := formatExpensesReport(expenses, period)
+	report := formatExpensesReport(loc, expenses, periodHeader(loc, period_key, startDate, endDate))
+	analyticsReport := getAnalyticsReport(e, loc, userID, wallet, startDate, endDate)
+
+	// Для общего кошелька добавляем разбивку по участникам
+	if wallet.Members > 1 {
+		members, err := e.repo.GetMemberTotalsUnix(wallet.ID, startDate, endDate)
+		if err != nil {
+			logger.L.Error("Ошибка при получении расходов участников:", err)
+		} else if len(members) > 0 {
+			report = fmt.Sprintf(loc.WalletReport, walletName(loc, wallet)) + "\n" + report +
+				"\n\n" + loc.MembersReport + "\n" + formatTotals(loc, members)
+		}
+	}
+
+	return report, analyticsReport, expenses
+}
+
+// Кнопки "Показать записи" под отчетом: по каждой категории и все сразу
+func createButtonsOfEntries(e *ExpenseBot, loc *bot.Locale, periodKey string, expenses map[string]float64) *telebot.ReplyMarkup {
+	menu := &telebot.ReplyMarkup{}
+	if len(expenses) == 0 {
+		return menu
+	}
+
+	row := make([]telebot.InlineButton, 0, 2)
+	for _, key := range bot.Categories {
+		if _, ok := expenses[bot.StoredCategory(key)]; !ok {
+			continue
+		}
+
+		row = append(row, entriesButton(loc.Categories[key], periodKey, key))
+		if len(row) == 2 {
+			menu.InlineKeyboard = append(menu.InlineKeyboard, row)
+			row = make([]telebot.InlineButton, 0, 2)
+		}
+	}
+	if len(row) > 0 {
+		menu.InlineKeyboard = append(menu.InlineKeyboard, row)
+	}
+
+	btnAll := entriesButton(loc.BtnShowEntries, periodKey, "")
+	menu.InlineKeyboard = append(menu.InlineKeyboard, []telebot.InlineButton{btnAll})
 
-	return report
+	return menu
+}
+
+func entriesButton(title string, periodKey string, categoryKey string) telebot.InlineButton {
+	return telebot.InlineButton{
+		Unique: "show_entries",
+		Text:   title,
+		Data:   periodKey + "|" + categoryKey,
+	}
+}
+
+// Обработчик кнопки "Показать записи" — постраничный список расходов за период
+func btnShowEntriesFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		e.bot.Respond(c)
+		loc := e.tr(c.Sender.ID)
+
+		periodKey, categoryKey, _ := strings.Cut(c.Data, "|")
+		startDate, endDate := e.periodDates(c.Sender.ID, periodKey)
+		period := periodHeader(loc, periodKey, startDate, endDate)
+
+		filter := repository.ExpenseFilter{
+			StartUnixMilli: startDate,
+			EndUnixMilli:   endDate,
+		}
+		title := fmt.Sprintf(loc.EntriesTitle, period)
+		if categoryKey != "" {
+			filter.Categories = []string{bot.StoredCategory(categoryKey)}
+			title = fmt.Sprintf(loc.EntriesCategoryTitle, loc.Categories[categoryKey], period)
+		}
+
+		openExpenseList(e, c.Sender.ID, title, filter)
+
+		msg, menu := renderExpenseList(e, c.Sender.ID, 0)
+		editBotMessageWithMenu(e, c, msg, menu)
+	}
 }
 
 // Форматирование отчета о расходах
-func formatExpensesReport(expenses map[string]float64, period string) string {
+func formatExpensesReport(loc *bot.Locale, expenses map[string]float64, period string) string {
+	return formatTotalsReport(loc, fmt.Sprintf(loc.ExpensesByCategory, period)+"\n", categoryTitles(loc, expenses))
+}
+
+// Суммы по категориям с названиями категорий на языке пользователя
+func categoryTitles(loc *bot.Locale, totals map[string]float64) map[string]float64 {
+	titles := make(map[string]float64, len(totals))
+	for category, sum := range totals {
+		titles[loc.CategoryTitle(category)] += sum
+	}
+
+	return titles
+}
+
+// Форматирование сумм под заголовком, по убыванию суммы
+func formatTotalsReport(loc *bot.Locale, header string, totals map[string]float64) string {
 	var report strings.Builder
 	var totalSum float64
 
-	report.WriteString(fmt.Sprintf("Расходы по категориям за %s:\n", period))
-	for category, sum := range expenses {
-		report.WriteString(fmt.Sprintf("%s: %.2f\n", category, sum))
+	report.WriteString(header)
+	report.WriteString(formatTotals(loc, totals))
+	for _, sum := range totals {
 		totalSum += sum
 	}
 
-	report.WriteString(fmt.Sprintf("\nИтоговая сумма: %.2f", totalSum))
+	report.WriteString("\n" + fmt.Sprintf(loc.TotalAmount, loc.Amount(totalSum)))
 	return report.String()
 }
+
+// Строки "название: сумма" по убыванию суммы
+func formatTotals(loc *bot.Locale, totals map[string]float64) string {
+	var lines strings.Builder
+
+	names := make([]string, 0, len(totals))
+	for name := range totals {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return totals[names[i]] > totals[names[j]]
+	})
+
+	for _, name := range names {
+		lines.WriteString(fmt.Sprintf("%s: %s\n", name, loc.Amount(totals[name])))
+	}
+
+	return lines.String()
+}
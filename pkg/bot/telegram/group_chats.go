@@ -0,0 +1,264 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/repository"
+)
+
+// Команды, на которые бот отвечает в групповых чатах
+var groupCommands = map[string]bool{
+	"/add":      true,
+	"/report":   true,
+	"/rename":   true,
+	"/split":    true,
+	"/balance":  true,
+	"/settle":   true,
+	"/help":     true,
+	"/language": true,
+}
+
+// Период отчета /report по умолчанию
+const defaultReportPeriod = "period_month"
+
+// filterUpdate отсеивает обновления из групп, которые не адресованы боту:
+// обычную переписку, файлы и команды, недоступные в группе
+func filterUpdate(u *telebot.Update) bool {
+	m := u.Message
+	if m == nil || m.Chat == nil || m.Private() {
+		return true
+	}
+
+	if !strings.HasPrefix(m.Text, "/") {
+		return false
+	}
+
+	command := strings.Fields(m.Text)[0]
+	command, _, _ = strings.Cut(command, "@")
+
+	return groupCommands[command]
+}
+
+// Кошелек, в который записываются расходы из чата:
+// в группе — общий кошелек чата, в личном чате — текущий кошелек пользователя.
+// Если кошелек получить нельзя, пользователю отправляется причина
+func chatWallet(e *ExpenseBot, m *telebot.Message) (repository.Wallet, bool) {
+	loc := e.tr(m.Sender.ID)
+	if m.Private() {
+		wallet, ok := getWallet(e, m.Sender.ID)
+		if !ok {
+			sendBotMessage(e, m, loc.ErrorReg)
+		}
+		return wallet, ok
+	}
+
+	// Участником кошелька чата становится только зарегистрированный пользователь
+	registered, _, err := e.repo.IsUserRegistered(m.Sender.ID)
+	if err != nil {
+		logger.L.Error("Ошибка при проверке регистрации:", err, "user_id", m.Sender.ID)
+		sendBotMessage(e, m, loc.ErrorReg)
+		return repository.Wallet{}, false
+	}
+	if !registered {
+		sendBotMessage(e, m, loc.GroupRegister)
+		return repository.Wallet{}, false
+	}
+
+	wallet, err := e.repo.GetChatWallet(m.Chat.ID, m.Sender.ID)
+	if errors.Is(err, repository.ErrChatWalletNotFound) {
+		// Кошелек чата принадлежит создателю или администратору группы, а не первому написавшему
+		ownerID, ok := chatOwner(e, m.Chat)
+		if !ok {
+			sendBotMessage(e, m, loc.GroupOwnerRequired)
+			return repository.Wallet{}, false
+		}
+		if err = e.repo.CreateChatWallet(m.Chat.ID, ownerID, m.Chat.Title); err == nil {
+			wallet, err = e.repo.GetChatWallet(m.Chat.ID, m.Sender.ID)
+		}
+	}
+	if err != nil {
+		logger.L.Error("Ошибка при получении кошелька чата:", err, "chat_id", m.Chat.ID)
+		sendBotMessage(e, m, loc.ErrorReg)
+		return repository.Wallet{}, false
+	}
+
+	return wallet, true
+}
+
+// Владелец кошелька чата: создатель группы, а если он не зарегистрирован в боте — первый зарегистрированный администратор
+func chatOwner(e *ExpenseBot, chat *telebot.Chat) (int, bool) {
+	admins, err := e.bot.AdminsOf(chat)
+	if err != nil {
+		logger.L.Error("Ошибка при получении администраторов чата:", err, "chat_id", chat.ID)
+		return 0, false
+	}
+
+	sort.SliceStable(admins, func(i, j int) bool {
+		return admins[i].Role == telebot.Creator && admins[j].Role != telebot.Creator
+	})
+	for _, admin := range admins {
+		if admin.User == nil {
+			continue
+		}
+
+		registered, _, err := e.repo.IsUserRegistered(admin.User.ID)
+		if err != nil {
+			logger.L.Error("Ошибка при проверке регистрации:", err, "user_id", admin.User.ID)
+			return 0, false
+		}
+		if registered {
+			return admin.User.ID, true
+		}
+	}
+
+	return 0, false
+}
+
+// Обработчик команды /help
+func cmdHelp(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		if !m.Private() {
+			sendBotMessage(e, m, loc.GroupHelp)
+			return
+		}
+
+		deleteBotMessage(e, m)
+
+		sendBotMessage(e, m, loc.Help)
+		sendMainMenu(e, m)
+	}
+}
+
+// Обработчик команды /add 500 продукты [заметка] [#теги]
+func cmdAdd(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		logger.L.Info("Команда /add", "user_id", m.Sender.ID, "user_name", m.Sender.Username)
+
+		deleteBotMessage(e, m)
+		defer sendMainMenu(e, m)
+
+		input, err := bot.ParseCommandExpense(m.Payload)
+		if err != nil {
+			sendBotMessage(e, m, loc.AddUsage)
+			return
+		}
+
+		wallet, ok := chatWallet(e, m)
+		if !ok {
+			return
+		}
+		if !wallet.CanEdit() {
+			sendBotMessage(e, m, loc.NoWalletRights)
+			return
+		}
+
+		expense := repository.Expense{
+			UserID:   m.Sender.ID,
+			WalletID: wallet.ID,
+			Date:     time.Now(),
+			Category: input.Category,
+			Amount:   input.Amount,
+			Note:     input.Note,
+			Tags:     input.Tags,
+		}
+
+		if expense.ID, err = e.repo.AddExpense(expense); err != nil {
+			logger.L.Error("Ошибка при добавлении расхода:", err)
+			return
+		}
+
+		sendBotMessage(e, m, formatAddedExpense(loc, expense))
+		// Предупреждение приходит в личный чат, чтобы кнопки видел только автор расхода
+		checkNewExpense(e, expense)
+	}
+}
+
+// Обработчик команды /report [период] — отчет по кошельку чата
+func cmdReport(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		logger.L.Info("Команда /report", "user_id", m.Sender.ID, "user_name", m.Sender.Username)
+
+		deleteBotMessage(e, m)
+		defer sendMainMenu(e, m)
+
+		periodKey := defaultReportPeriod
+		if payload := strings.TrimSpace(m.Payload); payload != "" {
+			key, ok := bot.ParsePeriod(payload)
+			if !ok {
+				sendBotMessage(e, m, loc.ReportUsage)
+				return
+			}
+			periodKey = key
+		}
+
+		wallet, ok := chatWallet(e, m)
+		if !ok {
+			return
+		}
+
+		report, analyticsReport, _ := getWalletReport(e, loc, m.Sender.ID, wallet, periodKey)
+		sendBotMessage(e, m, report)
+		if analyticsReport != "" {
+			sendBotMessage(e, m, analyticsReport)
+		}
+	}
+}
+
+// Обработчик команды /rename — переименование кошелька группы, доступно администраторам чата
+func cmdRename(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		logger.L.Info("Команда /rename", "user_id", m.Sender.ID, "user_name", m.Sender.Username)
+
+		if m.Private() {
+			sendBotMessage(e, m, loc.GroupOnly)
+			sendMainMenu(e, m)
+			return
+		}
+
+		name := strings.TrimSpace(m.Payload)
+		if name == "" {
+			sendBotMessage(e, m, loc.RenameUsage)
+			return
+		}
+
+		if !isChatAdmin(e, m.Chat, m.Sender) {
+			sendBotMessage(e, m, loc.GroupAdminOnly)
+			return
+		}
+
+		wallet, ok := chatWallet(e, m)
+		if !ok {
+			return
+		}
+
+		if err := e.repo.RenameWallet(wallet.ID, name); err != nil {
+			logger.L.Error("Ошибка при переименовании кошелька:", err)
+			return
+		}
+
+		sendBotMessage(e, m, fmt.Sprintf(loc.WalletRenamed, name))
+	}
+}
+
+// Проверка, является ли пользователь создателем или администратором чата
+func isChatAdmin(e *ExpenseBot, chat *telebot.Chat, user *telebot.User) bool {
+	member, err := e.bot.ChatMemberOf(chat, user)
+	if err != nil {
+		logger.L.Error("Ошибка при получении участника чата:", err)
+		return false
+	}
+
+	return member.Role == telebot.Creator || member.Role == telebot.Administrator
+}
@@ -0,0 +1,104 @@
+package telegram
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/repository"
+	"expense_accounting_bot/pkg/statement"
+)
+
+// Максимальный размер файла выписки
+const maxStatementSize = 5 << 20
+
+// Обработчик загрузки файла с выпиской банка
+func handleOnDocument(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		logger.L.Info("Загружен файл", "file_name", m.Document.FileName, "user_id", m.Sender.ID, "user_name", m.Sender.Username)
+
+		deleteBotMessage(e, m)
+
+		if !strings.HasSuffix(strings.ToLower(m.Document.FileName), ".csv") || m.Document.FileSize > maxStatementSize {
+			sendBotMessage(e, m, loc.ImportError)
+			sendMainMenu(e, m)
+			return
+		}
+
+		wallet, ok := getWallet(e, m.Sender.ID)
+		if !ok || !wallet.CanEdit() {
+			sendBotMessage(e, m, loc.NoWalletRights)
+			sendMainMenu(e, m)
+			return
+		}
+
+		data, err := downloadFile(e, m.Document.FileID)
+		if err != nil {
+			logger.L.Error("Ошибка при загрузке файла выписки:", err)
+			sendBotMessage(e, m, loc.ImportError)
+			sendMainMenu(e, m)
+			return
+		}
+
+		bankName, transactions, err := statement.Parse(data)
+		if err != nil {
+			logger.L.Error("Ошибка при разборе выписки:", err)
+			sendBotMessage(e, m, loc.ImportError)
+			sendMainMenu(e, m)
+			return
+		}
+
+		if len(transactions) == 0 {
+			sendBotMessage(e, m, fmt.Sprintf(loc.ImportEmpty, bankName))
+			sendMainMenu(e, m)
+			return
+		}
+
+		expenses := make([]repository.Expense, 0, len(transactions))
+		for _, t := range transactions {
+			expenses = append(expenses, repository.Expense{
+				Date:     t.Date,
+				UserID:   m.Sender.ID,
+				WalletID: wallet.ID,
+				Category: bot.StoredCategory(statement.Category(t)),
+				Amount:   t.Amount,
+				Note:     t.Description,
+			})
+		}
+
+		if err = e.repo.AddExpenses(expenses); err != nil {
+			logger.L.Error("Ошибка при импорте расходов:", err)
+			sendBotMessage(e, m, loc.ImportError)
+		} else {
+			sendBotMessage(e, m, fmt.Sprintf(loc.ImportDone, bankName, loc.Plural(loc.ExpensesCount, len(expenses))))
+		}
+
+		sendMainMenu(e, m)
+	}
+}
+
+// Скачивание файла с серверов Telegram
+func downloadFile(e *ExpenseBot, fileID string) ([]byte, error) {
+	url, err := e.bot.FileURLByID(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("не удалось скачать файл: %s", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxStatementSize))
+}
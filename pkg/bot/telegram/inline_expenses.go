@@ -0,0 +1,153 @@
+package telegram
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/repository"
+)
+
+const (
+	// Префикс идентификатора inline-результата, за ним следует номер категории в списке пользователя
+	inlineCategoryPrefix = "c"
+	// Параметры /start для кнопки перехода в личный чат из inline-режима
+	inlineStartHelp     = "help"
+	inlineStartRegister = "inline"
+	// Telegram принимает не больше 50 результатов на inline-запрос
+	maxInlineResults = 50
+)
+
+// Категории пользователя в том виде, в каком они хранятся в базе: стандартные и добавленные им самим
+func userCategories(e *ExpenseBot, userID int) []string {
+	categories := make([]string, 0, len(bot.Categories))
+	seen := make(map[string]bool, len(bot.Categories))
+	for _, key := range bot.Categories {
+		category := bot.StoredCategory(key)
+		categories = append(categories, category)
+		seen[category] = true
+	}
+
+	own, err := e.repo.GetUserCategories(userID)
+	if err != nil {
+		logger.L.Error("Ошибка при получении категорий пользователя:", err)
+	}
+	for _, category := range own {
+		if !seen[category] {
+			categories = append(categories, category)
+			seen[category] = true
+		}
+	}
+
+	return categories
+}
+
+// Обработчик inline-запроса "@bot 300 кофе" — варианты категорий для записи расхода
+func handleInlineQuery(e *ExpenseBot) func(*telebot.Query) {
+	return func(q *telebot.Query) {
+		loc := e.tr(q.From.ID)
+		response := &telebot.QueryResponse{IsPersonal: true}
+
+		input, err := bot.ParseCommandExpense(q.Text)
+		wallet, ok := getWallet(e, q.From.ID)
+		switch {
+		case !ok:
+			response.SwitchPMText = loc.InlineRegister
+			response.SwitchPMParameter = inlineStartRegister
+		case !wallet.CanEdit():
+			response.SwitchPMText = loc.InlineNoRights
+			response.SwitchPMParameter = inlineStartHelp
+		case err != nil:
+			response.SwitchPMText = loc.InlineUsage
+			response.SwitchPMParameter = inlineStartHelp
+		default:
+			response.Results = inlineCategoryResults(e, loc, q.From.ID, input)
+		}
+
+		if err = e.bot.Answer(q, response); err != nil {
+			logger.L.Error("Ошибка при ответе на inline-запрос:", err)
+		}
+	}
+}
+
+// Варианты категорий, категория из запроса идет первой
+func inlineCategoryResults(e *ExpenseBot, loc *bot.Locale, userID int, input bot.ExpenseInput) telebot.Results {
+	categories := userCategories(e, userID)
+	results := make(telebot.Results, 0, len(categories))
+
+	for i, category := range categories {
+		expense := repository.Expense{Category: category, Amount: input.Amount, Note: input.Note, Tags: input.Tags}
+
+		var content telebot.InputMessageContent = &telebot.InputTextMessageContent{Text: formatAddedExpense(loc, expense)}
+		result := &telebot.ArticleResult{
+			Title:       loc.CategoryTitle(category),
+			Description: strings.TrimSpace(loc.Amount(input.Amount) + " " + input.Note),
+		}
+		result.Content = &content
+		result.SetResultID(inlineCategoryPrefix + strconv.Itoa(i))
+
+		if category == input.Category {
+			results = append(telebot.Results{result}, results...)
+		} else {
+			results = append(results, result)
+		}
+	}
+
+	if len(results) > maxInlineResults {
+		results = results[:maxInlineResults]
+	}
+
+	return results
+}
+
+// HandleChosenInlineResult записывает расход по выбранному в inline-режиме варианту категории
+func (e *ExpenseBot) HandleChosenInlineResult(r *ChosenInlineResult) {
+	if e.ignored(&r.From, "chosen_inline_result") {
+		return
+	}
+
+	e.tasks.spawn(func() { addChosenInlineResult(e, r) })
+}
+
+func addChosenInlineResult(e *ExpenseBot, r *ChosenInlineResult) {
+	logger.L.Info("Выбран inline-результат", "result_id", r.ResultID, "user_id", r.From.ID, "user_name", r.From.Username)
+
+	index, err := strconv.Atoi(strings.TrimPrefix(r.ResultID, inlineCategoryPrefix))
+	categories := userCategories(e, r.From.ID)
+	if err != nil || index < 0 || index >= len(categories) {
+		logger.L.Warning("Неизвестный inline-результат", "result_id", r.ResultID)
+		return
+	}
+
+	input, err := bot.ParseCommandExpense(r.Query)
+	if err != nil {
+		logger.L.Error("Ошибка при разборе inline-запроса:", err)
+		return
+	}
+
+	wallet, ok := getWallet(e, r.From.ID)
+	if !ok || !wallet.CanEdit() {
+		return
+	}
+
+	expense := repository.Expense{
+		UserID:   r.From.ID,
+		WalletID: wallet.ID,
+		Date:     time.Now(),
+		Category: categories[index],
+		Amount:   input.Amount,
+		Note:     input.Note,
+		Tags:     input.Tags,
+	}
+
+	if expense.ID, err = e.repo.AddExpense(expense); err != nil {
+		logger.L.Error("Ошибка при добавлении расхода:", err)
+		return
+	}
+
+	checkNewExpense(e, expense)
+}
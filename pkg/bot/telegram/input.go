@@ -0,0 +1,31 @@
+package telegram
+
+import (
+	"github.com/tucnak/telebot"
+)
+
+// expectInput направляет следующие текстовые сообщения пользователя в личном чате в handler,
+// пока ввод не будет завершен. Нажатие кнопки или команда отменяют ожидание
+func (e *ExpenseBot) expectInput(userID int, handler func(*telebot.Message)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.inputs[userID] = handler
+}
+
+// finishInput завершает ожидание ввода от пользователя
+func (e *ExpenseBot) finishInput(userID int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.inputs, userID)
+}
+
+// pendingInput возвращает обработчик ввода, которого бот ждет от пользователя
+func (e *ExpenseBot) pendingInput(userID int) (func(*telebot.Message), bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	handler, ok := e.inputs[userID]
+	return handler, ok
+}
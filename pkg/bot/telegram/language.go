@@ -0,0 +1,155 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/bot"
+)
+
+// Значение /language, возвращающее язык интерфейса Telegram
+const languageAuto = "auto"
+
+// userLanguage язык, выбранный пользователем, и язык его интерфейса Telegram
+type userLanguage struct {
+	chosen   string
+	detected string
+}
+
+// tr возвращает язык, на котором бот отвечает пользователю: выбранный им самим,
+// иначе язык интерфейса Telegram, иначе язык по умолчанию
+func (e *ExpenseBot) tr(userID int) *bot.Locale {
+	lang := e.userLanguage(userID)
+	if lang.chosen != "" {
+		return bot.GetLocale(lang.chosen)
+	}
+
+	return bot.GetLocale(lang.detected)
+}
+
+// userLanguage возвращает язык пользователя, при первом обращении читая его из базы
+func (e *ExpenseBot) userLanguage(userID int) userLanguage {
+	e.mu.Lock()
+	lang, ok := e.languages[userID]
+	e.mu.Unlock()
+	if ok {
+		return lang
+	}
+
+	chosen, detected, err := e.repo.GetUserLanguage(userID)
+	if err != nil {
+		logger.L.Error("Ошибка при получении языка пользователя:", err, "user_id", userID)
+	}
+	lang = userLanguage{chosen: chosen, detected: detected}
+
+	e.mu.Lock()
+	e.languages[userID] = lang
+	e.mu.Unlock()
+
+	return lang
+}
+
+// HandleUserLanguage запоминает язык интерфейса Telegram отправителя обновления
+func (e *ExpenseBot) HandleUserLanguage(userID int, languageCode string) {
+	lang := e.userLanguage(userID)
+	if lang.detected == languageCode {
+		return
+	}
+
+	lang.detected = languageCode
+	e.mu.Lock()
+	e.languages[userID] = lang
+	e.mu.Unlock()
+
+	if err := e.repo.SetUserLanguageCode(userID, languageCode); err != nil {
+		logger.L.Error("Ошибка при сохранении языка пользователя:", err, "user_id", userID)
+	}
+}
+
+// Обработчик команды /language [код языка или auto]; без параметра предлагает выбрать язык кнопками
+func cmdLanguage(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+
+		code := strings.ToLower(strings.TrimSpace(m.Payload))
+		if code == "" {
+			menu := &telebot.ReplyMarkup{}
+			for _, language := range append(bot.Languages(), languageAuto) {
+				btn := telebot.InlineButton{
+					Unique: "language",
+					Text:   languageTitle(loc, language),
+					Data:   language,
+				}
+				menu.InlineKeyboard = append(menu.InlineKeyboard, []telebot.InlineButton{btn})
+			}
+
+			if _, err := e.bot.Send(m.Chat, loc.LanguageSelect, menu); err != nil {
+				logger.L.ErrorSendMessage(err)
+			}
+			return
+		}
+
+		if code != languageAuto && !bot.IsLanguage(code) {
+			sendBotMessage(e, m, fmt.Sprintf(loc.LanguageUsage, strings.Join(bot.Languages(), ", ")))
+			return
+		}
+
+		sendBotMessage(e, m, setLanguage(e, m.Sender.ID, code))
+	}
+}
+
+// Обработчик кнопок выбора языка
+func handleLanguageButtons(e *ExpenseBot) {
+	e.handle(&telebot.InlineButton{Unique: "language"}, btnLanguageFunc(e))
+}
+
+// Обработчик кнопки выбора языка
+func btnLanguageFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		e.bot.Respond(c)
+
+		code := c.Data
+		if code != languageAuto && !bot.IsLanguage(code) {
+			return
+		}
+
+		if _, err := e.bot.Edit(c.Message, setLanguage(e, c.Sender.ID, code)); err != nil {
+			logger.L.ErrorEditMessage(err)
+		}
+	}
+}
+
+// setLanguage сохраняет язык, выбранный пользователем, и возвращает подтверждение уже на новом языке
+func setLanguage(e *ExpenseBot, userID int, code string) string {
+	chosen := code
+	if code == languageAuto {
+		chosen = ""
+	}
+
+	if err := e.repo.SetUserLanguage(userID, chosen); err != nil {
+		logger.L.Error("Ошибка при сохранении языка пользователя:", err, "user_id", userID)
+	}
+
+	lang := e.userLanguage(userID)
+	lang.chosen = chosen
+	e.mu.Lock()
+	e.languages[userID] = lang
+	e.mu.Unlock()
+
+	logger.L.Info("Пользователь выбрал язык", "user_id", userID, "language", code)
+
+	loc := e.tr(userID)
+	return fmt.Sprintf(loc.LanguageChanged, languageTitle(loc, code))
+}
+
+// languageTitle название языка для кнопок и подтверждений
+func languageTitle(loc *bot.Locale, code string) string {
+	if code == languageAuto {
+		return loc.BtnLanguageAuto
+	}
+
+	return bot.GetLocale(code).LanguageName
+}
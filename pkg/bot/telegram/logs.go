@@ -0,0 +1,141 @@
+package telegram
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+)
+
+// Ограничение несжатого размера отправляемых логов
+const logsSizeLimit = 10 << 20
+
+// Обработчик команды /logs [уровень] [с какого времени] [текст] — последние записи лога файлом
+func cmdSendLogFile(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		filter := parseLogsFilter(m.Payload, time.Now())
+
+		// Буферизованные записи должны попасть в файл до чтения
+		if err := logger.L.Flush(); err != nil {
+			logger.L.Error("Ошибка при записи лога:", err)
+		}
+
+		lines, truncated, err := logger.Tail(logger.L.Path(), filter, logsSizeLimit)
+		if err != nil {
+			logger.L.Error("Ошибка при чтении лога:", err)
+			sendBotMessage(e, m, fmt.Sprintf(loc.LogsReadError, err))
+			return
+		}
+		if len(lines) == 0 {
+			sendBotMessage(e, m, loc.LogsEmpty)
+			return
+		}
+
+		caption := fmt.Sprintf(loc.LogsCaption, len(lines))
+		if truncated {
+			caption += "\n" + fmt.Sprintf(loc.LogsTruncated, logsSizeLimit>>20)
+		}
+
+		if err = sendLogLines(e, m, lines, caption); err != nil {
+			logger.L.Error("Ошибка при отправке лога:", err)
+		}
+	}
+}
+
+// sendLogLines отправляет строки лога сжатым файлом
+func sendLogLines(e *ExpenseBot, m *telebot.Message, lines []string, caption string) error {
+	// telebot загружает документы только с диска, имя файла берется из пути
+	dir, err := os.MkdirTemp("", "logs")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bot-"+time.Now().Format("20060102-150405")+".log.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = logger.WriteGzip(file, lines); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	doc := &telebot.Document{
+		File:     telebot.FromDisk(path),
+		FileName: filepath.Base(path),
+		MIME:     "application/gzip",
+		Caption:  caption,
+	}
+	_, err = e.bot.Send(m.Chat, doc)
+
+	return err
+}
+
+// parseLogsFilter разбирает аргументы /logs: уровень (debug, info, warn, error),
+// начало периода (30m, 2h, 3d или дата 2024-08-01) и текст для поиска в любом количестве слов
+func parseLogsFilter(payload string, now time.Time) logger.Filter {
+	filter := logger.Filter{Level: slog.LevelDebug}
+	words := strings.Fields(payload)
+
+	levelSet, sinceSet := false, false
+	for len(words) > 0 {
+		if level, ok := parseLogLevel(words[0]); ok && !levelSet {
+			filter.Level = level
+			levelSet = true
+		} else if since, ok := parseLogsSince(words[0], now); ok && !sinceSet {
+			filter.Since = since
+			sinceSet = true
+		} else {
+			break
+		}
+		words = words[1:]
+	}
+
+	filter.Grep = strings.Join(words, " ")
+
+	return filter
+}
+
+func parseLogLevel(word string) (slog.Level, bool) {
+	switch strings.ToLower(word) {
+	case "debug", "info", "warn", "warning", "error":
+		level, err := logger.ParseLevel(word)
+		return level, err == nil
+	}
+
+	return 0, false
+}
+
+// parseLogsSince разбирает начало периода: длительность назад от now или дату
+func parseLogsSince(word string, now time.Time) (time.Time, bool) {
+	if days, ok := strings.CutSuffix(word, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, -n), true
+		}
+		return time.Time{}, false
+	}
+
+	if d, err := time.ParseDuration(word); err == nil && d > 0 {
+		return now.Add(-d), true
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02", "02.01.2006"} {
+		if t, err := time.ParseInLocation(layout, word, now.Location()); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,49 @@
+package telegram
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"expense_accounting_bot/internal/utils/logger"
+)
+
+func TestParseLogsFilter(t *testing.T) {
+	now := time.Date(2024, 8, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		payload string
+		want    logger.Filter
+	}{
+		{"без аргументов", "", logger.Filter{Level: slog.LevelDebug}},
+		{"уровень", "error", logger.Filter{Level: slog.LevelError}},
+		{"уровень в любом регистре", "WARNING", logger.Filter{Level: slog.LevelWarn}},
+		{"минуты", "30m", logger.Filter{Level: slog.LevelDebug, Since: now.Add(-30 * time.Minute)}},
+		{"дни", "3d", logger.Filter{Level: slog.LevelDebug, Since: now.AddDate(0, 0, -3)}},
+		{"дата", "2024-08-01", logger.Filter{Level: slog.LevelDebug, Since: time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)}},
+		{"дата со временем", "2024-08-01T15:04", logger.Filter{Level: slog.LevelDebug, Since: time.Date(2024, 8, 1, 15, 4, 0, 0, time.UTC)}},
+		{"русская дата", "01.08.2024", logger.Filter{Level: slog.LevelDebug, Since: time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)}},
+		{
+			"уровень, период и текст", "warn 2h Ошибка базы",
+			logger.Filter{Level: slog.LevelWarn, Since: now.Add(-2 * time.Hour), Grep: "Ошибка базы"},
+		},
+		{
+			"период перед уровнем", "1d error timeout",
+			logger.Filter{Level: slog.LevelError, Since: now.AddDate(0, 0, -1), Grep: "timeout"},
+		},
+		// После первого слова текста уровни и периоды считаются частью текста
+		{"текст с уровнем", "user error 2h", logger.Filter{Level: slog.LevelDebug, Grep: "user error 2h"}},
+		{"повторный уровень", "error info", logger.Filter{Level: slog.LevelError, Grep: "info"}},
+		{"некорректный период", "0d -5m", logger.Filter{Level: slog.LevelDebug, Grep: "0d -5m"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseLogsFilter(tt.payload, now)
+			if got.Level != tt.want.Level || !got.Since.Equal(tt.want.Since) || got.Grep != tt.want.Grep {
+				t.Errorf("parseLogsFilter(%q) = %+v, ожидалось %+v", tt.payload, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/bot"
+)
+
+// periodOptions возвращает, с какого дня у пользователя начинаются неделя и месяц
+func (e *ExpenseBot) periodOptions(userID int) bot.PeriodOptions {
+	weekStart, monthStart, err := e.repo.GetUserPeriodStart(userID)
+	if err != nil {
+		logger.L.Error("Ошибка при получении начала периодов пользователя:", err, "user_id", userID)
+		return bot.DefaultPeriodOptions
+	}
+
+	return bot.PeriodOptions{WeekStart: time.Weekday(weekStart), MonthStart: monthStart}
+}
+
+// periodDates возвращает границы текущего периода пользователя в миллисекундах Unix
+func (e *ExpenseBot) periodDates(userID int, periodKey string) (int64, int64) {
+	return bot.GetPeriodDates(periodKey, time.Now(), e.periodOptions(userID))
+}
+
+// periodHeader название периода с датами, которые он охватывает: "Месяц (01.03.2024 – 31.03.2024)"
+func periodHeader(loc *bot.Locale, periodKey string, startDate, endDate int64) string {
+	dates := loc.DateRange(time.UnixMilli(startDate), time.UnixMilli(endDate))
+	return fmt.Sprintf(loc.PeriodDates, loc.PeriodTitle(periodKey), dates)
+}
+
+// Обработчик команды /weekstart [1-7] — день начала недели, 1 — понедельник, 7 — воскресенье
+func cmdWeekStart(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		opts := e.periodOptions(m.Sender.ID)
+
+		day, err := strconv.Atoi(strings.TrimSpace(m.Payload))
+		if err != nil || day < 1 || day > 7 {
+			sendBotMessage(e, m, fmt.Sprintf(loc.WeekStartUsage, loc.Weekdays[opts.WeekStart]))
+			return
+		}
+
+		// Воскресенье в time.Weekday нулевое
+		weekday := time.Weekday(day % 7)
+		if err = e.repo.SetUserWeekStart(m.Sender.ID, int(weekday)); err != nil {
+			logger.L.Error("Ошибка при сохранении начала недели:", err)
+			return
+		}
+
+		logger.L.Info("Изменено начало недели", "user_id", m.Sender.ID, "weekday", weekday)
+		sendBotMessage(e, m, fmt.Sprintf(loc.WeekStartChanged, loc.Weekdays[weekday]))
+	}
+}
+
+// Обработчик команды /monthstart [1-28] — число, с которого начинается месяц
+func cmdMonthStart(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		opts := e.periodOptions(m.Sender.ID)
+
+		day, err := strconv.Atoi(strings.TrimSpace(m.Payload))
+		if err != nil || day < 1 || day > bot.MaxMonthStart {
+			sendBotMessage(e, m, fmt.Sprintf(loc.MonthStartUsage, opts.MonthStart, bot.MaxMonthStart))
+			return
+		}
+
+		if err = e.repo.SetUserMonthStart(m.Sender.ID, day); err != nil {
+			logger.L.Error("Ошибка при сохранении начала месяца:", err)
+			return
+		}
+
+		logger.L.Info("Изменено начало месяца", "user_id", m.Sender.ID, "day", day)
+		sendBotMessage(e, m, fmt.Sprintf(loc.MonthStartChanged, day))
+	}
+}
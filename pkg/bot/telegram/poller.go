@@ -0,0 +1,228 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+)
+
+// Пауза перед повторным запросом обновлений после ошибки
+const pollRetryDelay = 3 * time.Second
+
+// ChosenInlineResult выбранный пользователем результат inline-запроса.
+// telebot не разбирает этот тип обновлений, поэтому он читается из ответа Telegram напрямую
+type ChosenInlineResult struct {
+	ResultID        string       `json:"result_id"`
+	From            telebot.User `json:"from"`
+	Query           string       `json:"query"`
+	InlineMessageID string       `json:"inline_message_id"`
+}
+
+// rawUpdate обновление Telegram вместе с полями, которых нет в telebot.Update
+type rawUpdate struct {
+	telebot.Update
+	ChosenInlineResult *ChosenInlineResult `json:"chosen_inline_result,omitempty"`
+
+	// Отправитель обновления и язык его интерфейса: telebot.User не содержит language_code
+	SenderID     int    `json:"-"`
+	LanguageCode string `json:"-"`
+}
+
+// UnmarshalJSON разбирает обновление и находит язык его отправителя
+func (u *rawUpdate) UnmarshalJSON(data []byte) error {
+	type plain rawUpdate
+	if err := json.Unmarshal(data, (*plain)(u)); err != nil {
+		return err
+	}
+
+	type from struct {
+		From struct {
+			ID           int    `json:"id"`
+			LanguageCode string `json:"language_code"`
+		} `json:"from"`
+	}
+	var senders struct {
+		Message            *from `json:"message"`
+		EditedMessage      *from `json:"edited_message"`
+		Callback           *from `json:"callback_query"`
+		Query              *from `json:"inline_query"`
+		ChosenInlineResult *from `json:"chosen_inline_result"`
+	}
+	if err := json.Unmarshal(data, &senders); err != nil {
+		return err
+	}
+
+	for _, sender := range []*from{senders.Message, senders.EditedMessage, senders.Callback, senders.Query, senders.ChosenInlineResult} {
+		if sender != nil {
+			u.SenderID, u.LanguageCode = sender.From.ID, sender.From.LanguageCode
+			break
+		}
+	}
+
+	return nil
+}
+
+// UpdateSource источник обновлений, передающий выбранные inline-результаты и язык отправителей
+// отдельным обработчикам
+type UpdateSource interface {
+	telebot.Poller
+	OnChosenInlineResult(handler func(*ChosenInlineResult))
+	OnUserLanguage(handler func(userID int, languageCode string))
+	OnUpdate(accept func(*telebot.Update) bool)
+}
+
+// updateHandlers обработчики, общие для источников обновлений
+type updateHandlers struct {
+	onChosen   func(*ChosenInlineResult)
+	onLanguage func(userID int, languageCode string)
+	accept     func(*telebot.Update) bool
+}
+
+// OnChosenInlineResult задает обработчик выбранных inline-результатов
+func (h *updateHandlers) OnChosenInlineResult(handler func(*ChosenInlineResult)) {
+	h.onChosen = handler
+}
+
+// OnUserLanguage задает обработчик языка отправителя, вызываемый до обработки обновления
+func (h *updateHandlers) OnUserLanguage(handler func(userID int, languageCode string)) {
+	h.onLanguage = handler
+}
+
+// OnUpdate задает проверку, которую обновление проходит перед передачей боту
+func (h *updateHandlers) OnUpdate(accept func(*telebot.Update) bool) {
+	h.accept = accept
+}
+
+// dispatch передает выбранные inline-результаты обработчику, остальные обновления — боту.
+// Язык отправителя сообщается до обработки, чтобы ответ был на нем
+func (h *updateHandlers) dispatch(update rawUpdate, dest chan telebot.Update) {
+	if h.onLanguage != nil && update.SenderID != 0 && update.LanguageCode != "" {
+		h.onLanguage(update.SenderID, update.LanguageCode)
+	}
+
+	if update.ChosenInlineResult != nil {
+		if h.onChosen != nil {
+			h.onChosen(update.ChosenInlineResult)
+		}
+		return
+	}
+
+	if h.accept == nil || h.accept(&update.Update) {
+		dest <- update.Update
+	}
+}
+
+// Poller long polling, который в отличие от telebot.LongPoller передает боту выбранные inline-результаты
+type Poller struct {
+	updateHandlers
+
+	Timeout      time.Duration
+	LastUpdateID int
+}
+
+// NewPoller создает Poller с таймаутом long polling
+func NewPoller(timeout time.Duration) *Poller {
+	return &Poller{Timeout: timeout}
+}
+
+// Poll получает обновления и передает их боту до остановки
+func (p *Poller) Poll(b *telebot.Bot, dest chan telebot.Update, stop chan struct{}) {
+	stopped := make(chan struct{})
+	go func() {
+		<-stop
+		close(stopped)
+	}()
+
+	// getUpdates не работает, пока у бота установлен webhook
+	if err := rawCall(b, "deleteWebhook", map[string]string{}); err != nil {
+		logger.L.Error("Ошибка при удалении webhook:", err)
+	}
+
+	for {
+		select {
+		case <-stopped:
+			p.confirmUpdates(b)
+			close(stop)
+			return
+		default:
+		}
+
+		updates, err := getUpdates(b, p.LastUpdateID+1, p.Timeout)
+		if err != nil {
+			logger.L.Error("Ошибка при получении обновлений:", err)
+			select {
+			case <-stopped:
+			case <-time.After(pollRetryDelay):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			p.LastUpdateID = update.ID
+			p.dispatch(update, dest)
+		}
+	}
+}
+
+// confirmUpdates подтверждает Telegram уже полученные обновления, чтобы после перезапуска они не пришли повторно
+func (p *Poller) confirmUpdates(b *telebot.Bot) {
+	if p.LastUpdateID == 0 {
+		return
+	}
+
+	err := rawCall(b, "getUpdates", map[string]string{
+		"offset":  strconv.Itoa(p.LastUpdateID + 1),
+		"limit":   "1",
+		"timeout": "0",
+	})
+	if err != nil {
+		logger.L.Error("Ошибка при подтверждении обновлений:", err)
+	}
+}
+
+func getUpdates(b *telebot.Bot, offset int, timeout time.Duration) ([]rawUpdate, error) {
+	params := map[string]string{
+		"offset":  strconv.Itoa(offset),
+		"timeout": strconv.Itoa(int(timeout / time.Second)),
+	}
+
+	var updates []rawUpdate
+	err := rawRequest(b, "getUpdates", params, &updates)
+
+	return updates, err
+}
+
+// rawCall вызывает метод Bot API, результат которого не нужен
+func rawCall(b *telebot.Bot, method string, params map[string]string) error {
+	return rawRequest(b, method, params, nil)
+}
+
+// rawRequest вызывает метод Bot API и разбирает поле result ответа в result
+func rawRequest(b *telebot.Bot, method string, params map[string]string, result any) error {
+	data, err := b.Raw(method, params)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Ok          bool
+		Result      json.RawMessage
+		Description string
+	}
+	if err = json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	if !resp.Ok {
+		return errors.New(resp.Description)
+	}
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(resp.Result, result)
+}
@@ -0,0 +1,279 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+)
+
+const (
+	// Сколько обновлений пользователь может прислать подряд и сколько в секунду после этого
+	userBurst = 10
+	userRate  = 2
+	// Лимиты исходящих сообщений Telegram: всего, в личный чат и в группу
+	sendGlobalRate = 30
+	sendChatRate   = 1
+	sendGroupRate  = 20.0 / 60
+	sendChatBurst  = 3
+	// Через сколько простоя лимиты пользователя или чата забываются
+	limiterIdleTTL = 10 * time.Minute
+)
+
+// tokenBucket ведро токенов: пополняется со скоростью rate в секунду до burst.
+// Не потокобезопасно, доступ защищает владелец
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst float64, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// allow забирает токен, если он есть
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// reserve забирает токен в долг и возвращает, сколько нужно подождать, пока долг погасится
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// idle сообщает, что ведро давно не использовалось; за это время оно успевает наполниться
+func (b *tokenBucket) idle(now time.Time) bool {
+	return now.Sub(b.last) >= limiterIdleTTL
+}
+
+// userLimit лимит обновлений одного пользователя
+type userLimit struct {
+	bucket *tokenBucket
+	// Сколько обновлений пропущено с начала текущего ограничения
+	dropped int
+}
+
+// userLimiter ограничивает частоту обновлений от каждого пользователя
+type userLimiter struct {
+	mu    sync.Mutex
+	users map[int]*userLimit
+	sweep time.Time
+	now   func() time.Time
+}
+
+func newUserLimiter() *userLimiter {
+	return &userLimiter{users: make(map[int]*userLimit), now: time.Now}
+}
+
+// allow учитывает обновление пользователя. Если лимит превышен, возвращает false,
+// а notify — нужно ли предупредить пользователя: только о первом пропущенном обновлении
+func (l *userLimiter) allow(userID int) (allowed bool, notify bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.forgetIdle(now)
+
+	limit, ok := l.users[userID]
+	if !ok {
+		limit = &userLimit{bucket: newTokenBucket(userRate, userBurst, now)}
+		l.users[userID] = limit
+	}
+
+	if limit.bucket.allow(now) {
+		if limit.dropped > 0 {
+			logger.L.Info("Ограничение запросов пользователя снято", "user_id", userID, "dropped", limit.dropped)
+			limit.dropped = 0
+		}
+		return true, false
+	}
+
+	limit.dropped++
+	if limit.dropped == 1 {
+		logger.L.Warning("Пользователь превысил лимит запросов", "user_id", userID)
+	}
+
+	return false, limit.dropped == 1
+}
+
+// forgetIdle раз в limiterIdleTTL удаляет лимиты давно не писавших пользователей
+func (l *userLimiter) forgetIdle(now time.Time) {
+	if now.Sub(l.sweep) < limiterIdleTTL {
+		return
+	}
+	l.sweep = now
+
+	for userID, limit := range l.users {
+		if limit.bucket.idle(now) {
+			delete(l.users, userID)
+		}
+	}
+}
+
+// throttled проверяет лимит обновлений пользователя и возвращает true, если обновление нужно пропустить.
+// notify вызывается один раз за период ограничения, чтобы вежливо предупредить пользователя
+func (e *ExpenseBot) throttled(user *telebot.User, handler string, notify func()) bool {
+	if user == nil {
+		return false
+	}
+
+	allowed, warn := e.limiter.allow(user.ID)
+	if allowed {
+		return false
+	}
+
+	logger.L.Debug("Пропущено обновление сверх лимита", "handler", handler, "user_id", user.ID)
+	if warn && notify != nil && !e.ignored(user, handler) {
+		e.tasks.run(notify)
+	}
+
+	return true
+}
+
+// LimitedTransport ограничивает исходящие сообщения бота лимитами Telegram: не больше 30 в секунду всего,
+// одного в секунду в личный чат и 20 в минуту в группу. Запросы сверх лимита ждут своей очереди
+type LimitedTransport struct {
+	next http.RoundTripper
+
+	mu     sync.Mutex
+	global *tokenBucket
+	chats  map[int64]*tokenBucket
+	sweep  time.Time
+	now    func() time.Time
+}
+
+// NewLimitedTransport создает LimitedTransport поверх next.
+// telebot отправляет запросы через http.Post, поэтому транспорт устанавливается в http.DefaultTransport
+func NewLimitedTransport(next http.RoundTripper) *LimitedTransport {
+	return &LimitedTransport{
+		next:   next,
+		global: newTokenBucket(sendGlobalRate, sendGlobalRate, time.Now()),
+		chats:  make(map[int64]*tokenBucket),
+		now:    time.Now,
+	}
+}
+
+// RoundTrip выполняет запрос, дождавшись очереди, если это отправка или изменение сообщения
+func (t *LimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != "api.telegram.org" || !isSendMethod(path.Base(req.URL.Path)) {
+		return t.next.RoundTrip(req)
+	}
+
+	chatID, err := requestChatID(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if wait := t.reserve(chatID, t.now()); wait > 0 {
+		logger.L.Debug("Отправка отложена лимитом Telegram", "chat_id", chatID, "wait", wait)
+
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// reserve занимает место в общей очереди и в очереди чата и возвращает, сколько нужно подождать
+func (t *LimitedTransport) reserve(chatID int64, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	wait := t.global.reserve(now)
+	if chatID == 0 {
+		return wait
+	}
+
+	if now.Sub(t.sweep) >= limiterIdleTTL {
+		t.sweep = now
+		for id, bucket := range t.chats {
+			if bucket.idle(now) {
+				delete(t.chats, id)
+			}
+		}
+	}
+
+	bucket, ok := t.chats[chatID]
+	if !ok {
+		// ID групп отрицательные
+		rate := float64(sendChatRate)
+		if chatID < 0 {
+			rate = sendGroupRate
+		}
+		bucket = newTokenBucket(rate, sendChatBurst, now)
+		t.chats[chatID] = bucket
+	}
+
+	return max(wait, bucket.reserve(now))
+}
+
+// isSendMethod сообщает, что метод Bot API отправляет или изменяет сообщение
+func isSendMethod(method string) bool {
+	for _, prefix := range []string{"send", "edit", "forward", "copy"} {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requestChatID возвращает chat_id из JSON-тела запроса, оставляя тело нетронутым.
+// Для запросов с файлами и сообщений inline-режима чат неизвестен, и возвращается 0
+func requestChatID(req *http.Request) (int64, error) {
+	if req.Body == nil || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		return 0, nil
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return 0, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+
+	var payload struct {
+		ChatID json.RawMessage `json:"chat_id"`
+	}
+	if json.Unmarshal(data, &payload) != nil {
+		return 0, nil
+	}
+
+	chatID, _ := strconv.ParseInt(strings.Trim(string(payload.ChatID), `"`), 10, 64)
+	return chatID, nil
+}
@@ -0,0 +1,197 @@
+package telegram
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"expense_accounting_bot/internal/utils/logger"
+)
+
+// Паузы считаются через float64, поэтому сравниваются с точностью до микросекунды
+func sameWait(got, want time.Duration) bool {
+	return (got - want).Abs() < time.Microsecond
+}
+
+func TestTokenBucketAllow(t *testing.T) {
+	start := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	b := newTokenBucket(2, 3, start)
+
+	// Моменты отсчитываются от создания ведра
+	steps := []struct {
+		name string
+		at   time.Duration
+		want bool
+	}{
+		{"первый токен", 0, true},
+		{"второй токен", 0, true},
+		{"последний токен", 0, true},
+		{"ведро пусто", 0, false},
+		{"токен еще не накопился", 400 * time.Millisecond, false},
+		{"токен накопился", 500 * time.Millisecond, true},
+		{"часы пошли назад", 0, false},
+		{"после простоя ведро полно", time.Hour, true},
+		{"второй после простоя", time.Hour, true},
+		{"третий после простоя", time.Hour, true},
+		{"больше burst не накапливается", time.Hour, false},
+	}
+
+	for _, step := range steps {
+		if got := b.allow(start.Add(step.at)); got != step.want {
+			t.Errorf("%s: allow = %v, ожидалось %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	start := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	b := newTokenBucket(1, 3, start)
+
+	// Моменты отсчитываются от создания ведра
+	steps := []struct {
+		at   time.Duration
+		want time.Duration
+	}{
+		{0, 0},
+		{0, 0},
+		{0, 0},
+		// Каждый следующий запрос встает в очередь за предыдущими
+		{0, time.Second},
+		{0, 2 * time.Second},
+		// За полсекунды долг уменьшился, но новый запрос добавил свой
+		{500 * time.Millisecond, 2500 * time.Millisecond},
+		// Долг погашен и накопилось полтора токена
+		{4500 * time.Millisecond, 0},
+		{4500 * time.Millisecond, 500 * time.Millisecond},
+	}
+
+	for i, step := range steps {
+		if got := b.reserve(start.Add(step.at)); !sameWait(got, step.want) {
+			t.Errorf("запрос %d через %v: ожидание %v, ожидалось %v", i+1, step.at, got, step.want)
+		}
+	}
+}
+
+func TestTokenBucketIdle(t *testing.T) {
+	start := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	b := newTokenBucket(1, 1, start)
+
+	if b.idle(start.Add(limiterIdleTTL - time.Second)) {
+		t.Error("ведро простаивает меньше limiterIdleTTL")
+	}
+	if !b.idle(start.Add(limiterIdleTTL)) {
+		t.Error("ведро простаивает limiterIdleTTL")
+	}
+}
+
+func TestLimitedTransportReserve(t *testing.T) {
+	const (
+		privateChat = int64(42)
+		groupChat   = int64(-100)
+	)
+	start := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		chatID int64
+		count  int
+		want   time.Duration
+	}{
+		{"личный чат: burst без ожидания", privateChat, sendChatBurst, 0},
+		{"личный чат: сообщение в секунду", privateChat, sendChatBurst + 2, 2 * time.Second},
+		{"группа: 20 сообщений в минуту", groupChat, sendChatBurst + 1, 3 * time.Second},
+		{"без чата: только общий лимит", 0, sendGlobalRate, 0},
+		{"без чата: сверх общего лимита", 0, sendGlobalRate + 1, time.Second / sendGlobalRate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewLimitedTransport(nil)
+			tr.global = newTokenBucket(sendGlobalRate, sendGlobalRate, start)
+
+			var wait time.Duration
+			for i := 0; i < tt.count; i++ {
+				wait = tr.reserve(tt.chatID, start)
+			}
+			if !sameWait(wait, tt.want) {
+				t.Errorf("ожидание последнего сообщения %v, ожидалось %v", wait, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitedTransportGlobalLimit(t *testing.T) {
+	start := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	tr := NewLimitedTransport(nil)
+	tr.global = newTokenBucket(sendGlobalRate, sendGlobalRate, start)
+
+	// Лимиты чатов не превышены, но общий лимит ждут все
+	var wait time.Duration
+	for chatID := int64(1); chatID <= sendGlobalRate+1; chatID++ {
+		wait = tr.reserve(chatID, start)
+	}
+	if !sameWait(wait, time.Second/sendGlobalRate) {
+		t.Errorf("ожидание %v, ожидалось %v", wait, time.Second/sendGlobalRate)
+	}
+}
+
+func TestLimitedTransportForgetsIdleChats(t *testing.T) {
+	start := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	tr := NewLimitedTransport(nil)
+	tr.global = newTokenBucket(sendGlobalRate, sendGlobalRate, start)
+
+	tr.reserve(1, start)
+	tr.reserve(2, start.Add(limiterIdleTTL/2))
+	tr.reserve(3, start.Add(limiterIdleTTL))
+
+	if _, ok := tr.chats[1]; ok {
+		t.Error("простаивающий чат не забыт")
+	}
+	if _, ok := tr.chats[2]; !ok {
+		t.Error("недавний чат забыт")
+	}
+}
+
+func TestUserLimiterAllow(t *testing.T) {
+	if err := logger.InitLogger(logger.Config{Path: filepath.Join(t.TempDir(), "bot.log"), Level: "error"}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	l := newUserLimiter()
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < userBurst; i++ {
+		if allowed, _ := l.allow(1); !allowed {
+			t.Fatalf("обновление %d пропущено в пределах burst", i+1)
+		}
+	}
+
+	// Предупреждение только о первом пропущенном обновлении
+	if allowed, notify := l.allow(1); allowed || !notify {
+		t.Errorf("первое обновление сверх лимита: allow = %v, notify = %v", allowed, notify)
+	}
+	if allowed, notify := l.allow(1); allowed || notify {
+		t.Errorf("второе обновление сверх лимита: allow = %v, notify = %v", allowed, notify)
+	}
+
+	// Лимит одного пользователя не влияет на другого
+	if allowed, _ := l.allow(2); !allowed {
+		t.Error("обновление другого пользователя пропущено")
+	}
+
+	now = now.Add(time.Second / userRate)
+	if allowed, _ := l.allow(1); !allowed {
+		t.Error("обновление не пропущено после пополнения")
+	}
+	if l.users[1].dropped != 0 {
+		t.Errorf("счетчик пропущенных = %d после снятия ограничения", l.users[1].dropped)
+	}
+
+	// После простоя лимиты пользователей забываются
+	now = now.Add(limiterIdleTTL)
+	l.allow(2)
+	if _, ok := l.users[1]; ok {
+		t.Error("лимит простаивающего пользователя не забыт")
+	}
+}
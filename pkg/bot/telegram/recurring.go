@@ -0,0 +1,288 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/analytics"
+	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/repository"
+)
+
+const (
+	// Как часто записываются расходы по наступившим правилам
+	recurringJobInterval = time.Hour
+	// Как часто история расходов проверяется на новые регулярные платежи
+	recurringDetectInterval = 24 * time.Hour
+	// За сколько месяцев берется история: ежегодный платеж должен встретиться в ней дважды
+	recurringHistoryMonths = 13
+)
+
+// runRecurringJobs записывает расходы по правилам и ищет новые регулярные платежи, пока бот не остановится
+func (e *ExpenseBot) runRecurringJobs() {
+	ticker := time.NewTicker(recurringJobInterval)
+	defer ticker.Stop()
+
+	var detected time.Time
+	for {
+		now := time.Now()
+		bookRecurringRules(e, now)
+		if now.Sub(detected) >= recurringDetectInterval {
+			detectRecurring(e, now)
+			detected = now
+		}
+
+		select {
+		case <-e.stopping:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// bookRecurringRules записывает расходы по всем наступившим платежам правил, в том числе пропущенным,
+// пока бот не работал
+func bookRecurringRules(e *ExpenseBot, now time.Time) {
+	rules, err := e.repo.GetDueRecurringRules(now)
+	if err != nil {
+		logger.L.Error("Ошибка при получении регулярных расходов:", err)
+		return
+	}
+
+	for _, rule := range rules {
+		for !rule.Next.After(now) {
+			expense := repository.Expense{
+				UserID:   rule.UserID,
+				WalletID: rule.WalletID,
+				Date:     rule.Next,
+				Category: rule.Category,
+				Amount:   rule.Amount,
+				Note:     rule.Note,
+			}
+			next := analytics.NextOccurrence(rule.Next, rule.Interval, rule.Day)
+
+			if expense.ID, err = e.repo.AddRecurringExpense(rule.ID, expense, next); err != nil {
+				logger.L.Error("Ошибка при записи регулярного расхода:", err, "rule_id", rule.ID)
+				break
+			}
+			rule.Next = next
+
+			loc := e.tr(rule.UserID)
+			msg := fmt.Sprintf(loc.RecurringBooked, formatAddedExpense(loc, expense))
+			if _, err = e.bot.Send(&telebot.Chat{ID: int64(rule.UserID)}, msg); err != nil {
+				logger.L.ErrorSendMessage(err)
+			}
+		}
+	}
+}
+
+// detectRecurring ищет в истории новые регулярные платежи и предлагает их пользователям
+func detectRecurring(e *ExpenseBot, now time.Time) {
+	// Заметки сравниваются без учета регистра, что SQLite не умеет для кириллицы, поэтому в базе
+	// расходы отбираются только по категории и сумме, а на платежи их делит DetectRecurring
+	expenses, err := e.repo.GetRepeatedExpensesSince(now.AddDate(0, -recurringHistoryMonths, 0).UnixMilli(), analytics.MinRecurringCount)
+	if err != nil {
+		logger.L.Error("Ошибка при получении истории расходов:", err)
+		return
+	}
+
+	for _, recurring := range analytics.DetectRecurring(expenses, now) {
+		detection := repository.RecurringDetection{
+			UserID:   recurring.UserID,
+			WalletID: recurring.WalletID,
+			Key:      recurring.Key(),
+			Category: recurring.Category,
+			Amount:   recurring.Amount,
+			Note:     recurring.Note,
+			Interval: recurring.Interval,
+			Day:      recurring.Day,
+			Next:     recurring.Next,
+			Count:    recurring.Count,
+		}
+
+		// Платеж, который уже предлагался, принят или отклонен, повторно не предлагается
+		detectionID, added, err := e.repo.AddRecurringDetection(detection)
+		if err != nil {
+			logger.L.Error("Ошибка при сохранении регулярного платежа:", err, "user_id", recurring.UserID)
+			continue
+		}
+		if added {
+			detection.ID = detectionID
+			proposeRecurring(e, detection)
+		}
+	}
+}
+
+// Предложение записывать найденный платеж автоматически
+func proposeRecurring(e *ExpenseBot, detection repository.RecurringDetection) {
+	loc := e.tr(detection.UserID)
+	data := strconv.FormatInt(detection.ID, 10)
+
+	menu := &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{
+		{Unique: "recurring_accept", Text: loc.BtnCreateRule, Data: data},
+		{Unique: "recurring_dismiss", Text: loc.BtnDismiss, Data: data},
+	}}}
+
+	msg := fmt.Sprintf(loc.RecurringDetected, recurringTitle(loc, detection.Category, detection.Note),
+		loc.Amount(detection.Amount), intervalName(loc, detection.Interval), detection.Count, loc.Date(detection.Next))
+	if _, err := e.bot.Send(&telebot.Chat{ID: int64(detection.UserID)}, msg, menu); err != nil {
+		logger.L.ErrorSendMessage(err)
+	}
+}
+
+// Предложения рассылаются фоновой задачей и переживают перезапуск бота, поэтому обработчики их кнопок
+// регистрируются при запуске, а не при отправке
+func handleRecurringButtons(e *ExpenseBot) {
+	e.handle(&telebot.InlineButton{Unique: "recurring_accept"}, btnRecurringAcceptFunc(e))
+	e.handle(&telebot.InlineButton{Unique: "recurring_dismiss"}, btnRecurringDismissFunc(e))
+	e.handle(&telebot.InlineButton{Unique: "recurring_delete"}, btnRecurringDeleteFunc(e))
+}
+
+// Название регулярного платежа: заметка, а если ее нет — категория
+func recurringTitle(loc *bot.Locale, category string, note string) string {
+	if note != "" {
+		return note
+	}
+
+	return loc.CategoryTitle(category)
+}
+
+func intervalName(loc *bot.Locale, interval string) string {
+	switch interval {
+	case repository.IntervalWeek:
+		return loc.RecurringWeekly
+	case repository.IntervalYear:
+		return loc.RecurringYearly
+	default:
+		return loc.RecurringMonthly
+	}
+}
+
+// Обработчик кнопки "Записывать автоматически" под найденным платежом
+func btnRecurringAcceptFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		loc := e.tr(c.Sender.ID)
+		detectionID, _ := strconv.ParseInt(c.Data, 10, 64)
+
+		detection, err := e.repo.GetRecurringDetection(c.Sender.ID, detectionID)
+		var rule repository.RecurringRule
+		if err == nil {
+			// Платеж, дата которого уже прошла, пользователь записал сам или запишет, поэтому правило
+			// начинается со следующего
+			next := detection.Next
+			for !next.After(time.Now()) {
+				next = analytics.NextOccurrence(next, detection.Interval, detection.Day)
+			}
+			rule, err = e.repo.AcceptRecurringDetection(c.Sender.ID, detectionID, next)
+		}
+		if err != nil {
+			if !errors.Is(err, repository.ErrRecurringNotFound) {
+				logger.L.Error("Ошибка при создании регулярного расхода:", err)
+			}
+			e.bot.Respond(c, &telebot.CallbackResponse{Text: loc.RecurringNotFound, ShowAlert: true})
+			return
+		}
+		e.bot.Respond(c)
+
+		msg := fmt.Sprintf(loc.RecurringCreated, recurringTitle(loc, rule.Category, rule.Note),
+			loc.Amount(rule.Amount), intervalName(loc, rule.Interval), loc.Date(rule.Next))
+		if _, err = e.bot.Edit(c.Message, msg); err != nil {
+			logger.L.ErrorEditMessage(err)
+		}
+	}
+}
+
+// Обработчик кнопки "Не предлагать" под найденным платежом
+func btnRecurringDismissFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		loc := e.tr(c.Sender.ID)
+		detectionID, _ := strconv.ParseInt(c.Data, 10, 64)
+
+		if err := e.repo.DismissRecurringDetection(c.Sender.ID, detectionID); err != nil {
+			if !errors.Is(err, repository.ErrRecurringNotFound) {
+				logger.L.Error("Ошибка при отклонении регулярного платежа:", err)
+			}
+			e.bot.Respond(c, &telebot.CallbackResponse{Text: loc.RecurringNotFound, ShowAlert: true})
+			return
+		}
+		e.bot.Respond(c)
+
+		if _, err := e.bot.Edit(c.Message, loc.RecurringDismissed); err != nil {
+			logger.L.ErrorEditMessage(err)
+		}
+	}
+}
+
+// Обработчик команды /recurring — правила пользователя с кнопками удаления
+func cmdRecurring(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		msg, menu := renderRecurringRules(e, m.Sender.ID)
+		if _, err := e.bot.Send(m.Chat, msg, menu); err != nil {
+			logger.L.ErrorSendMessage(err)
+		}
+	}
+}
+
+// Список правил пользователя и кнопки их удаления
+func renderRecurringRules(e *ExpenseBot, userID int) (string, *telebot.ReplyMarkup) {
+	loc := e.tr(userID)
+	menu := &telebot.ReplyMarkup{}
+
+	rules, err := e.repo.GetRecurringRules(userID)
+	if err != nil {
+		logger.L.Error("Ошибка при получении регулярных расходов:", err)
+	}
+	if len(rules) == 0 {
+		return loc.RecurringEmpty, menu
+	}
+
+	lines := []string{loc.RecurringTitle}
+	row := make([]telebot.InlineButton, 0, 3)
+	for i, rule := range rules {
+		lines = append(lines, fmt.Sprintf(loc.RecurringLine, i+1, recurringTitle(loc, rule.Category, rule.Note),
+			loc.Amount(rule.Amount), intervalName(loc, rule.Interval), loc.Date(rule.Next)))
+
+		row = append(row, telebot.InlineButton{
+			Unique: "recurring_delete",
+			Text:   fmt.Sprintf("%s %d", loc.BtnDelete, i+1),
+			Data:   strconv.FormatInt(rule.ID, 10),
+		})
+		if len(row) == cap(row) {
+			menu.InlineKeyboard = append(menu.InlineKeyboard, row)
+			row = make([]telebot.InlineButton, 0, 3)
+		}
+	}
+	if len(row) > 0 {
+		menu.InlineKeyboard = append(menu.InlineKeyboard, row)
+	}
+
+	return strings.Join(lines, "\n"), menu
+}
+
+// Обработчик кнопки удаления правила в списке /recurring
+func btnRecurringDeleteFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		loc := e.tr(c.Sender.ID)
+		ruleID, _ := strconv.ParseInt(c.Data, 10, 64)
+
+		response := loc.RecurringDeleted
+		if err := e.repo.DeleteRecurringRule(c.Sender.ID, ruleID); err != nil {
+			if !errors.Is(err, repository.ErrRecurringNotFound) {
+				logger.L.Error("Ошибка при удалении регулярного расхода:", err)
+			}
+			response = loc.RecurringNotFound
+		}
+		e.bot.Respond(c, &telebot.CallbackResponse{Text: response})
+
+		msg, menu := renderRecurringRules(e, c.Sender.ID)
+		if _, err := e.bot.Edit(c.Message, msg, menu); err != nil {
+			logger.L.ErrorEditMessage(err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/repository"
+)
+
+// Правила регистрации новых пользователей
+const (
+	RegistrationOpen      = "open"
+	RegistrationInvite    = "invite"
+	RegistrationAllowlist = "allowlist"
+)
+
+const (
+	// Префикс параметра /start с кодом приглашения на регистрацию
+	registrationInvitePrefix = "r_"
+	// Срок действия приглашения на регистрацию
+	registrationInviteTTL = 7 * 24 * time.Hour
+	// Сколько регистраций можно выдать одним приглашением
+	registrationInviteMaxUses = 100
+)
+
+// Registration правила регистрации новых пользователей
+type Registration struct {
+	// RegistrationOpen, RegistrationInvite или RegistrationAllowlist
+	Policy string
+	// Пользователи, которым разрешена регистрация в режиме allowlist
+	Allowed []int
+}
+
+// ignored сообщает, что обновление пользователя нужно молча пропустить: пользователь заблокирован
+// или, когда регистрация закрыта, еще не зарегистрирован и обращается не к /start
+func (e *ExpenseBot) ignored(user *telebot.User, handler string) bool {
+	if user == nil {
+		return false
+	}
+
+	role, err := e.repo.GetUserRole(user.ID)
+	if err != nil {
+		logger.L.Error("Ошибка при получении роли пользователя:", err, "user_id", user.ID)
+		return false
+	}
+	if role == repository.UserRoleBanned {
+		logger.L.Debug("Пропущено обновление заблокированного пользователя", "handler", handler, "user_id", user.ID)
+		return true
+	}
+
+	if e.registration.Policy == RegistrationOpen || handler == "/start" {
+		return false
+	}
+
+	isRegistered, _, err := e.repo.IsUserRegistered(user.ID)
+	if err != nil {
+		logger.L.Error("Ошибка при проверке регистрации пользователя:", err, "user_id", user.ID)
+		return false
+	}
+	if !isRegistered {
+		logger.L.Debug("Пропущено обновление незарегистрированного пользователя", "handler", handler, "user_id", user.ID)
+	}
+
+	return !isRegistered
+}
+
+// registerUser регистрирует пользователя по правилам регистрации и возвращает false, если регистрация закрыта.
+// Пользователям с назначенной ролью и пришедшим по приглашению регистрация доступна всегда;
+// приглашение расходуется вместе с регистрацией
+func registerUser(e *ExpenseBot, m *telebot.Message) (bool, error) {
+	allowed, err := canRegister(e, m)
+	if err != nil {
+		return false, err
+	}
+	if allowed {
+		return true, e.repo.AddUser(m.Sender.ID, m.Sender.Username)
+	}
+
+	code, ok := strings.CutPrefix(m.Payload, registrationInvitePrefix)
+	if !ok {
+		logRegistrationDenied(e, m)
+		return false, nil
+	}
+
+	err = e.repo.AddInvitedUser(m.Sender.ID, m.Sender.Username, code)
+	if errors.Is(err, repository.ErrInviteNotFound) {
+		logRegistrationDenied(e, m)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	logger.L.Info("Регистрация по приглашению", "user_id", m.Sender.ID, "code", code)
+	return true, nil
+}
+
+// canRegister проверяет, что пользователь может зарегистрироваться без приглашения
+func canRegister(e *ExpenseBot, m *telebot.Message) (bool, error) {
+	if e.registration.Policy == RegistrationOpen {
+		return true, nil
+	}
+
+	role, err := e.repo.GetUserRole(m.Sender.ID)
+	if err != nil {
+		return false, err
+	}
+	if repository.UserRoleLevel(role) > repository.UserRoleLevel(repository.UserRoleUser) {
+		return true, nil
+	}
+
+	return e.registration.Policy == RegistrationAllowlist && slices.Contains(e.registration.Allowed, m.Sender.ID), nil
+}
+
+func logRegistrationDenied(e *ExpenseBot, m *telebot.Message) {
+	logger.L.Info("Отклонена регистрация", "user_id", m.Sender.ID, "user_name", m.Sender.Username, "policy", e.registration.Policy)
+}
+
+// Обработчик команды /invite [количество] — приглашение на регистрацию для одного или нескольких пользователей
+func cmdInvite(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		uses := 1
+		if payload := strings.TrimSpace(m.Payload); payload != "" {
+			n, err := strconv.Atoi(payload)
+			if err != nil || n < 1 || n > registrationInviteMaxUses {
+				sendBotMessage(e, m, fmt.Sprintf(loc.InviteUsage, registrationInviteMaxUses))
+				return
+			}
+			uses = n
+		}
+
+		expires := time.Now().Add(registrationInviteTTL)
+		code, err := generateToken()
+		if err == nil {
+			err = e.repo.CreateRegistrationInvite(code, m.Sender.ID, uses, expires)
+		}
+		if err != nil {
+			logger.L.Error("Ошибка при создании приглашения на регистрацию:", err)
+			return
+		}
+
+		link := fmt.Sprintf("https://t.me/%s?start=%s%s", e.bot.Me.Username, registrationInvitePrefix, code)
+		sendBotMessage(e, m, fmt.Sprintf(loc.InviteCreated, loc.Plural(loc.RegistrationsCount, uses), loc.DateTime(expires), link))
+	}
+}
+
+// Обработчик команды /ban @пользователь — бот перестает отвечать пользователю
+func cmdBan(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		args := strings.Fields(m.Payload)
+		if len(args) != 1 {
+			sendBotMessage(e, m, loc.BanUsage)
+			return
+		}
+
+		targetID, title, ok := resolveUser(e, m, args[0])
+		if !ok {
+			return
+		}
+
+		if changeRole(e, m, targetID, repository.UserRoleBanned) {
+			sendBotMessage(e, m, fmt.Sprintf(loc.UserBanned, title))
+		}
+	}
+}
+
+// Обработчик команды /unban @пользователь — снятие блокировки
+func cmdUnban(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		args := strings.Fields(m.Payload)
+		if len(args) != 1 {
+			sendBotMessage(e, m, loc.UnbanUsage)
+			return
+		}
+
+		targetID, title, ok := resolveUser(e, m, args[0])
+		if !ok {
+			return
+		}
+
+		role, err := e.repo.GetUserRole(targetID)
+		if err != nil {
+			logger.L.Error("Ошибка при получении роли пользователя:", err)
+			return
+		}
+		if role != repository.UserRoleBanned {
+			sendBotMessage(e, m, fmt.Sprintf(loc.UserNotBanned, title))
+			return
+		}
+
+		if changeRole(e, m, targetID, repository.UserRoleUser) {
+			sendBotMessage(e, m, fmt.Sprintf(loc.UserUnbanned, title))
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/repository"
+)
+
+const (
+	// Сколько записей журнала показывает /audit по умолчанию и максимум
+	auditDefaultLimit = 20
+	auditMaxLimit     = 100
+)
+
+// guardMessage регистрирует команду, доступную только пользователям с ролью не ниже minRole.
+// Каждый вызов такой команды записывается в журнал действий
+func (e *ExpenseBot) guardMessage(endpoint interface{}, minRole string, handler func(*telebot.Message)) {
+	action := handlerName(endpoint)
+
+	e.handle(endpoint, func(m *telebot.Message) {
+		if !e.hasRole(m.Sender, minRole) {
+			logger.L.Warning("Отказано в доступе", "handler", action, "user_id", m.Sender.ID)
+			sendBotMessage(e, m, fmt.Sprintf(e.tr(m.Sender.ID).AccessDenied, minRole))
+			return
+		}
+
+		e.audit(m.Sender.ID, action, 0, commandText(m))
+		handler(m)
+	})
+}
+
+// guardCallback регистрирует кнопку, доступную только пользователям с ролью не ниже minRole.
+// Каждое нажатие такой кнопки записывается в журнал действий
+func (e *ExpenseBot) guardCallback(endpoint interface{}, minRole string, handler func(*telebot.Callback)) {
+	action := handlerName(endpoint)
+
+	e.handle(endpoint, func(c *telebot.Callback) {
+		if !e.hasRole(c.Sender, minRole) {
+			logger.L.Warning("Отказано в доступе", "handler", action, "user_id", c.Sender.ID)
+			e.bot.Respond(c, &telebot.CallbackResponse{Text: fmt.Sprintf(e.tr(c.Sender.ID).AccessDenied, minRole), ShowAlert: true})
+			return
+		}
+
+		e.audit(c.Sender.ID, action, 0, c.Data)
+		handler(c)
+	})
+}
+
+// hasRole проверяет, что роль пользователя не ниже minRole
+func (e *ExpenseBot) hasRole(user *telebot.User, minRole string) bool {
+	role, err := e.repo.GetUserRole(user.ID)
+	if err != nil {
+		logger.L.Error("Ошибка при получении роли пользователя:", err, "user_id", user.ID)
+		return false
+	}
+
+	return repository.UserRoleLevel(role) >= repository.UserRoleLevel(minRole)
+}
+
+// audit записывает действие с повышенными правами в журнал
+func (e *ExpenseBot) audit(actorID int, action string, targetID int, details string) {
+	entry := repository.AuditEntry{ActorID: actorID, Action: action, TargetID: targetID, Details: details}
+	if err := e.repo.AddAuditEntry(entry); err != nil {
+		logger.L.Error("Ошибка при записи в журнал действий:", err, "user_id", actorID, "handler", action)
+	}
+}
+
+// Обработчик команды /grant @пользователь роль
+func cmdGrant(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		args := strings.Fields(m.Payload)
+		if len(args) != 2 || !repository.IsUserRole(strings.ToLower(args[1])) {
+			sendBotMessage(e, m, loc.GrantUsage)
+			return
+		}
+
+		targetID, title, ok := resolveUser(e, m, args[0])
+		if !ok {
+			return
+		}
+
+		role := strings.ToLower(args[1])
+		if changeRole(e, m, targetID, role) {
+			sendBotMessage(e, m, fmt.Sprintf(loc.RoleChanged, title, role))
+		}
+	}
+}
+
+// Обработчик команды /revoke @пользователь — возврат к обычной роли user
+func cmdRevoke(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		args := strings.Fields(m.Payload)
+		if len(args) != 1 {
+			sendBotMessage(e, m, loc.RevokeUsage)
+			return
+		}
+
+		targetID, title, ok := resolveUser(e, m, args[0])
+		if !ok {
+			return
+		}
+
+		if changeRole(e, m, targetID, repository.UserRoleUser) {
+			sendBotMessage(e, m, fmt.Sprintf(loc.RoleChanged, title, repository.UserRoleUser))
+		}
+	}
+}
+
+// changeRole назначает роль пользователю и сообщает, удалось ли это. Владелец может назначить любую роль,
+// остальные — только роли ниже своей и только тем, чья роль тоже ниже их собственной
+func changeRole(e *ExpenseBot, m *telebot.Message, targetID int, role string) bool {
+	loc := e.tr(m.Sender.ID)
+	if targetID == m.Sender.ID {
+		sendBotMessage(e, m, loc.RoleSelf)
+		return false
+	}
+
+	actorRole, err := e.repo.GetUserRole(m.Sender.ID)
+	if err != nil {
+		logger.L.Error("Ошибка при получении роли пользователя:", err)
+		return false
+	}
+	currentRole, err := e.repo.GetUserRole(targetID)
+	if err != nil {
+		logger.L.Error("Ошибка при получении роли пользователя:", err)
+		return false
+	}
+
+	actorLevel := repository.UserRoleLevel(actorRole)
+	if actorRole != repository.UserRoleOwner &&
+		(repository.UserRoleLevel(role) >= actorLevel || repository.UserRoleLevel(currentRole) >= actorLevel) {
+		sendBotMessage(e, m, loc.RoleForbidden)
+		return false
+	}
+
+	if err = e.repo.SetUserRole(targetID, role, m.Sender.ID); err != nil {
+		logger.L.Error("Ошибка при назначении роли:", err)
+		return false
+	}
+
+	e.audit(m.Sender.ID, "role", targetID, currentRole+" -> "+role)
+	logger.L.Info("Изменена роль пользователя", "user_id", m.Sender.ID, "target_id", targetID, "from", currentRole, "to", role)
+
+	return true
+}
+
+// resolveUser находит пользователя по @имени или числовому ID
+func resolveUser(e *ExpenseBot, m *telebot.Message, target string) (int, string, bool) {
+	if id, err := strconv.Atoi(target); err == nil && id > 0 {
+		return id, target, true
+	}
+
+	userName := strings.TrimPrefix(target, "@")
+	user, err := e.repo.GetUserByName(userName)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		sendBotMessage(e, m, fmt.Sprintf(e.tr(m.Sender.ID).SplitUserNotFound, userName))
+		return 0, "", false
+	}
+	if err != nil {
+		logger.L.Error("Ошибка при поиске пользователя:", err)
+		return 0, "", false
+	}
+
+	return user.ID, "@" + user.Name, true
+}
+
+// Обработчик команды /roles — пользователи с назначенными ролями
+func cmdRoles(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		roles, err := e.repo.GetUserRoles()
+		if err != nil {
+			logger.L.Error("Ошибка при получении ролей:", err)
+			return
+		}
+		if len(roles) == 0 {
+			sendBotMessage(e, m, loc.RolesEmpty)
+			return
+		}
+
+		lines := []string{loc.RolesTitle}
+		for _, role := range roles {
+			name := strconv.Itoa(role.UserID)
+			if role.Name != "" {
+				name = fmt.Sprintf("@%s (%d)", role.Name, role.UserID)
+			}
+			lines = append(lines, fmt.Sprintf("%s — %s", name, role.Role))
+		}
+
+		sendBotMessage(e, m, strings.Join(lines, "\n"))
+	}
+}
+
+// Обработчик команды /audit [количество] — последние записи журнала действий
+func cmdAudit(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		limit := auditDefaultLimit
+		if n, err := strconv.Atoi(strings.TrimSpace(m.Payload)); err == nil && n > 0 {
+			limit = min(n, auditMaxLimit)
+		}
+
+		entries, err := e.repo.GetAuditLog(limit)
+		if err != nil {
+			logger.L.Error("Ошибка при чтении журнала действий:", err)
+			return
+		}
+
+		lines := []string{loc.AuditTitle}
+		for _, entry := range entries {
+			line := fmt.Sprintf("%s %d %s", loc.DateTime(entry.Date), entry.ActorID, entry.Action)
+			if entry.TargetID != 0 {
+				line += fmt.Sprintf(" → %d", entry.TargetID)
+			}
+			if entry.Details != "" {
+				line += ": " + truncateText(entry.Details, 100)
+			}
+			lines = append(lines, line)
+		}
+
+		sendBotMessage(e, m, strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,45 @@
+package telegram
+
+import (
+	"fmt"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/repository"
+)
+
+// Обработчик команды /find — поиск по истории расходов
+func cmdFind(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		logger.L.Info("Команда /find", "user_id", m.Sender.ID, "user_name", m.Sender.Username)
+
+		deleteBotMessage(e, m)
+
+		query, err := bot.ParseSearchQuery(m.Payload, e.periodOptions(m.Sender.ID))
+		if err != nil {
+			sendBotMessage(e, m, loc.FindUsage)
+			sendMainMenu(e, m)
+			return
+		}
+
+		filter := repository.ExpenseFilter{
+			MinAmount:  query.MinAmount,
+			MaxAmount:  query.MaxAmount,
+			Categories: query.Categories,
+			Tags:       query.Tags,
+			Text:       query.Text,
+		}
+		if !query.Start.IsZero() {
+			filter.StartUnixMilli = query.Start.UnixMilli()
+			filter.EndUnixMilli = query.End.UnixMilli()
+		}
+
+		openExpenseList(e, m.Sender.ID, fmt.Sprintf(loc.FindTitle, m.Payload), filter)
+
+		msg, menu := renderExpenseList(e, m.Sender.ID, 0)
+		sendBotMessageWithMenu(e, m, msg, menu)
+	}
+}
@@ -0,0 +1,312 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+)
+
+// Обработчики дольше этого времени записываются в лог как медленные
+const slowHandlerThreshold = 2 * time.Second
+
+// Разбор команд и данных inline-кнопок так же, как в telebot
+var (
+	commandRx  = regexp.MustCompile(`^(\/\w+)(@(\w+))?(\s|$)(.+)?`)
+	callbackRx = regexp.MustCompile(`^\f(\w+)(\|(.+))?$`)
+)
+
+// tasks учет обновлений и фоновых задач, которых бот дожидается при остановке.
+// Обновление учитывается до передачи в telebot и завершается вместе со своим обработчиком
+type tasks struct {
+	wg sync.WaitGroup
+}
+
+// add учитывает задачу, которая завершится вызовом done
+func (t *tasks) add() {
+	t.wg.Add(1)
+}
+
+func (t *tasks) done() {
+	t.wg.Done()
+}
+
+// run выполняет fn, учитывая ее до завершения
+func (t *tasks) run(fn func()) {
+	t.wg.Add(1)
+	defer t.wg.Done()
+
+	fn()
+}
+
+// spawn выполняет fn в отдельной горутине; задача учитывается до запуска горутины
+func (t *tasks) spawn(fn func()) {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		fn()
+	}()
+}
+
+// wait ждет завершения всех обновлений и задач или отмены ctx
+func (t *tasks) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("не завершились обработчики: %w", ctx.Err())
+	}
+}
+
+// AcceptUpdate решает, передавать ли обновление боту, и учитывает переданное обновление до завершения
+// его обработчика: так при остановке бот дождется и тех обновлений, обработчики которых еще не запущены
+func (e *ExpenseBot) AcceptUpdate(u *telebot.Update) bool {
+	if !filterUpdate(u) || !e.routed(u) {
+		return false
+	}
+
+	e.tasks.add()
+	return true
+}
+
+// routed повторяет выбор обработчика в telebot и сообщает, будет ли для обновления вызван ровно один
+// обработчик. Обновление без обработчика учитывать нельзя: завершить его было бы некому
+func (e *ExpenseBot) routed(u *telebot.Update) bool {
+	switch {
+	case u.Message != nil:
+		return e.routedMessage(u.Message)
+	case u.EditedMessage != nil:
+		return e.handles(telebot.OnEdited, messageHandler)
+	case u.ChannelPost != nil:
+		return e.handles(telebot.OnChannelPost, messageHandler)
+	case u.EditedChannelPost != nil:
+		return e.handles(telebot.OnEditedChannelPost, messageHandler)
+	case u.Callback != nil:
+		if match := callbackRx.FindStringSubmatch(u.Callback.Data); match != nil && e.handles("\f"+match[1], callbackHandler) {
+			return true
+		}
+		return e.handles(telebot.OnCallback, callbackHandler)
+	case u.Query != nil:
+		return e.handles(telebot.OnQuery, queryHandler)
+	}
+
+	return false
+}
+
+func (e *ExpenseBot) routedMessage(m *telebot.Message) bool {
+	if m.PinnedMessage != nil {
+		return e.handles(telebot.OnPinned, messageHandler)
+	}
+
+	if m.Text != "" {
+		if m.Text[0] == '\a' {
+			return false
+		}
+		if match := commandRx.FindStringSubmatch(m.Text); match != nil {
+			// Команду для другого бота telebot пропускает
+			if botName := match[3]; botName != "" && !strings.EqualFold(e.bot.Me.Username, botName) {
+				return false
+			}
+			if e.handles(match[1], messageHandler) {
+				return true
+			}
+		}
+
+		return e.handles(m.Text, messageHandler) || e.handles(telebot.OnText, messageHandler)
+	}
+
+	if endpoint, ok := mediaEndpoint(m); ok {
+		return e.handles(endpoint, messageHandler)
+	}
+
+	// Служебные сообщения о составе, названии и фото группы бот не обрабатывает
+	return false
+}
+
+// Событие telebot для сообщения с файлом в том же порядке проверки, что и в telebot
+func mediaEndpoint(m *telebot.Message) (string, bool) {
+	switch {
+	case m.Photo != nil:
+		return telebot.OnPhoto, true
+	case m.Audio != nil:
+		return telebot.OnAudio, true
+	case m.Document != nil:
+		return telebot.OnDocument, true
+	case m.Sticker != nil:
+		return telebot.OnSticker, true
+	case m.Video != nil:
+		return telebot.OnVideo, true
+	case m.VideoNote != nil:
+		return telebot.OnVideoNote, true
+	case m.Contact != nil:
+		return telebot.OnContact, true
+	case m.Location != nil:
+		return telebot.OnLocation, true
+	case m.Venue != nil:
+		return telebot.OnVenue, true
+	}
+
+	return "", false
+}
+
+// Виды обработчиков, которые telebot вызывает для обновлений
+const (
+	messageHandler = iota + 1
+	callbackHandler
+	queryHandler
+)
+
+// handles проверяет, зарегистрирован ли для endpoint обработчик нужного вида
+func (e *ExpenseBot) handles(endpoint string, kind int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.handlers[endpoint] == kind
+}
+
+// handle регистрирует обработчик telebot так, чтобы при остановке бот дождался его завершения
+// и записал в лог время обработки; заодно учитывается активность пользователя.
+// Обновления сверх лимита пользователя и обновления заблокированных пользователей до обработчика не доходят
+func (e *ExpenseBot) handle(endpoint interface{}, handler interface{}) {
+	name := handlerName(endpoint)
+
+	// Обработчики telebot читаются без блокировки, поэтому регистрировать их можно только до запуска
+	e.mu.Lock()
+	started := e.started
+	e.mu.Unlock()
+	if started {
+		logger.L.Error("Ошибка при регистрации обработчика:", errors.New("бот уже запущен"), "handler", name)
+		return
+	}
+
+	var kind int
+	switch h := handler.(type) {
+	case func(*telebot.Message):
+		kind = messageHandler
+		e.bot.Handle(endpoint, func(m *telebot.Message) {
+			// Обновление учтено в AcceptUpdate до передачи в telebot
+			defer e.tasks.done()
+			if e.throttled(m.Sender, name, func() { sendBotMessage(e, m, e.tr(m.Sender.ID).TooManyRequests) }) ||
+				e.ignored(m.Sender, name) {
+				return
+			}
+
+			defer logHandled(name, m.Sender, time.Now())
+			e.touchActivity(m.Sender)
+			// Команда отменяет ввод, которого бот ждал от пользователя
+			if endpoint != telebot.OnText {
+				e.finishInput(m.Sender.ID)
+			}
+			h(m)
+		})
+	case func(*telebot.Callback):
+		kind = callbackHandler
+		e.bot.Handle(endpoint, func(c *telebot.Callback) {
+			defer e.tasks.done()
+			if e.throttled(c.Sender, name, func() {
+				e.bot.Respond(c, &telebot.CallbackResponse{Text: e.tr(c.Sender.ID).TooManyRequests, ShowAlert: true})
+			}) || e.ignored(c.Sender, name) {
+				return
+			}
+
+			defer logHandled(name, c.Sender, time.Now())
+			e.touchActivity(c.Sender)
+			// Нажатие кнопки отменяет ожидаемый ввод; обработчик кнопки может начать новый
+			e.finishInput(c.Sender.ID)
+			h(c)
+		})
+	case func(*telebot.Query):
+		kind = queryHandler
+		e.bot.Handle(endpoint, func(q *telebot.Query) {
+			defer e.tasks.done()
+			if e.throttled(&q.From, name, nil) || e.ignored(&q.From, name) {
+				return
+			}
+
+			defer logHandled(name, &q.From, time.Now())
+			e.touchActivity(&q.From)
+			h(q)
+		})
+	default:
+		logger.L.Error("Ошибка при регистрации обработчика:", fmt.Errorf("неподдерживаемый тип %T", handler), "handler", name)
+		return
+	}
+
+	e.mu.Lock()
+	e.handlers[endpointKey(endpoint)] = kind
+	e.mu.Unlock()
+}
+
+// Ключ, под которым telebot хранит обработчик endpoint
+func endpointKey(endpoint interface{}) string {
+	switch end := endpoint.(type) {
+	case string:
+		return end
+	case telebot.CallbackEndpoint:
+		return end.CallbackUnique()
+	}
+
+	return ""
+}
+
+// Имя обработчика для логов: команда, событие telebot или кнопка
+func handlerName(endpoint interface{}) string {
+	switch end := endpoint.(type) {
+	case string:
+		return strings.TrimPrefix(end, "\a")
+	case telebot.CallbackEndpoint:
+		return strings.TrimPrefix(end.CallbackUnique(), "\f")
+	}
+
+	return fmt.Sprint(endpoint)
+}
+
+// logHandled записывает в лог завершение обработчика, начатого в start
+func logHandled(handler string, user *telebot.User, start time.Time) {
+	latency := time.Since(start)
+
+	userID := 0
+	if user != nil {
+		userID = user.ID
+	}
+
+	if latency >= slowHandlerThreshold {
+		logger.L.Warning("Медленная обработка обновления", "handler", handler, "user_id", userID, "latency", latency)
+		return
+	}
+	logger.L.Debug("Обновление обработано", "handler", handler, "user_id", userID, "latency", latency)
+}
+
+// Stop останавливает получение обновлений и ждет завершения уже начатой обработки
+func (e *ExpenseBot) Stop(ctx context.Context) error {
+	e.stopOnce.Do(func() { close(e.stopping) })
+
+	// Если бот уже остановился сам, Stop telebot некому принять, поэтому он вызывается в отдельной горутине
+	go e.bot.Stop()
+
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return fmt.Errorf("не остановилось получение обновлений: %w", ctx.Err())
+	}
+
+	return e.tasks.wait(ctx)
+}
+
+// Done закрывается, когда бот перестал получать обновления
+func (e *ExpenseBot) Done() <-chan struct{} {
+	return e.done
+}
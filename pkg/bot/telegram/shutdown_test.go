@@ -0,0 +1,268 @@
+package telegram
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tucnak/telebot"
+	_ "modernc.org/sqlite"
+
+	"expense_accounting_bot/config"
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/repository"
+)
+
+// fakeTelegram отвечает на запросы Bot API: один раз отдает updates, на остальные запросы
+// отвечает отправленным сообщением
+type fakeTelegram struct {
+	mu      sync.Mutex
+	updates string
+}
+
+func (f *fakeTelegram) RoundTrip(r *http.Request) (*http.Response, error) {
+	result := `{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}`
+	switch path.Base(r.URL.Path) {
+	case "getMe":
+		result = `{"id":1000,"is_bot":true,"first_name":"bot","username":"expense_bot"}`
+	case "getUpdates":
+		f.mu.Lock()
+		result, f.updates = f.updates, "[]"
+		f.mu.Unlock()
+		// Пустой ответ long polling приходит не сразу
+		if result == "[]" {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(`{"ok":true,"result":` + result + `}`)),
+		Request:    r,
+	}, nil
+}
+
+// slowRepository записывает расходы и язык пользователей с задержкой, чтобы остановка пришлась
+// на выполняющиеся обработчики и на обновления, которые источник еще передает боту
+type slowRepository struct {
+	repository.ExpenseRepository
+	started chan struct{}
+}
+
+func (r *slowRepository) AddExpense(expense repository.Expense) (int64, error) {
+	select {
+	case r.started <- struct{}{}:
+	default:
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	return r.ExpenseRepository.AddExpense(expense)
+}
+
+func (r *slowRepository) SetUserLanguageCode(userID int, languageCode string) error {
+	time.Sleep(5 * time.Millisecond)
+
+	return r.ExpenseRepository.SetUserLanguageCode(userID, languageCode)
+}
+
+// newShutdownTest готовит базу с пользователями и по одному обновлению /add от каждого из них
+func newShutdownTest(t *testing.T, users int) (*sql.DB, *slowRepository, []string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := logger.InitLogger(logger.Config{Path: filepath.Join(dir, "bot.log"), Level: "error"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.InitStringValues(config.StringValues(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", filepath.Join(dir, "bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	// Обработчики пишут в базу одновременно, а SQLite допускает одну запись за раз
+	db.SetMaxOpenConns(1)
+
+	repo := repository.NewSQLiteExpenseRepository(db)
+	if err = repo.InitSchema(); err != nil {
+		t.Fatal(err)
+	}
+
+	updates := make([]string, 0, users)
+	for i := 1; i <= users; i++ {
+		if err = repo.AddUser(i, fmt.Sprintf("user%d", i)); err != nil {
+			t.Fatal(err)
+		}
+		updates = append(updates, fmt.Sprintf(
+			`{"update_id":%d,"message":{"message_id":%d,"date":0,"from":{"id":%d,"first_name":"u","language_code":"ru"},"chat":{"id":%d,"type":"private"},"text":"/add %d"}}`,
+			i, i, i, i, i*10))
+	}
+
+	return db, &slowRepository{ExpenseRepository: repo, started: make(chan struct{}, 1)}, updates
+}
+
+// useFakeTelegram направляет запросы Bot API в fakeTelegram до конца теста
+func useFakeTelegram(t *testing.T, updates []string) {
+	t.Helper()
+
+	transport := http.DefaultTransport
+	http.DefaultTransport = &fakeTelegram{updates: "[" + strings.Join(updates, ",") + "]"}
+	t.Cleanup(func() { http.DefaultTransport = transport })
+}
+
+// stopAfterStart останавливает бот, как только начинает выполняться первый обработчик /add
+func stopAfterStart(t *testing.T, expenseBot *ExpenseBot, slow *slowRepository) {
+	t.Helper()
+
+	select {
+	case <-slow.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("обработчик /add не запустился")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := expenseBot.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func countExpenses(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM expenses`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+func TestStopWaitsForUpdates(t *testing.T) {
+	const users = 20
+
+	db, slow, updates := newShutdownTest(t, users)
+	useFakeTelegram(t, updates)
+
+	poller := NewPoller(0)
+	b, err := telebot.NewBot(telebot.Settings{Token: "test", Poller: poller})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expenseBot := NewExpenseBot(b, slow, Registration{Policy: RegistrationOpen})
+	poller.OnUpdate(expenseBot.AcceptUpdate)
+	go expenseBot.Start()
+
+	stopAfterStart(t, expenseBot, slow)
+
+	if count := countExpenses(t, db); count != users {
+		t.Errorf("после остановки записано %d расходов из %d", count, users)
+	}
+}
+
+func TestStopWaitsForWebhookUpdates(t *testing.T) {
+	const users = 20
+
+	db, slow, updates := newShutdownTest(t, users)
+	useFakeTelegram(t, nil)
+
+	// Свободный порт для сервера webhook
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	webhook, err := NewWebhook(addr, "http://"+addr+"/telegram", "secret", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Язык отправителя сохраняется до передачи обновления боту, поэтому остановка застает запросы в обработке
+	webhook.OnUserLanguage(func(int, string) { time.Sleep(20 * time.Millisecond) })
+
+	b, err := telebot.NewBot(telebot.Settings{Token: "test", Poller: webhook})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expenseBot := NewExpenseBot(b, slow, Registration{Policy: RegistrationOpen})
+	webhook.OnUpdate(expenseBot.AcceptUpdate)
+	go expenseBot.Start()
+
+	// Запросы к webhook идут мимо fakeTelegram
+	client := &http.Client{Transport: &http.Transport{}, Timeout: 5 * time.Second}
+	post := func(update string) (int, error) {
+		req, err := http.NewRequest(http.MethodPost, webhook.URL, strings.NewReader(update))
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set(secretTokenHeader, webhook.Secret)
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+
+		return resp.StatusCode, nil
+	}
+
+	// Сервер начинает слушать не сразу после запуска бота
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err = post("{}"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Обновления отправляются и во время остановки; после нее сервер отвечает ошибкой или недоступен
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, update := range updates {
+			wg.Add(1)
+			go func(update string) {
+				defer wg.Done()
+				if status, err := post(update); err == nil && status == http.StatusOK {
+					mu.Lock()
+					accepted++
+					mu.Unlock()
+				}
+			}(update)
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	stopAfterStart(t, expenseBot, slow)
+	wg.Wait()
+
+	if accepted == 0 {
+		t.Fatal("webhook не принял ни одного обновления")
+	}
+	if count := countExpenses(t, db); count != accepted {
+		t.Errorf("после остановки записано %d расходов из %d принятых", count, accepted)
+	}
+}
@@ -0,0 +1,242 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/repository"
+)
+
+// Обработчик команды /split — общий расход, разделенный с другими пользователями
+func cmdSplit(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		logger.L.Info("Команда /split", "user_id", m.Sender.ID, "user_name", m.Sender.Username)
+
+		deleteBotMessage(e, m)
+		defer sendMainMenu(e, m)
+
+		input, err := bot.ParseSplitText(m.Payload)
+		if err != nil {
+			msg := loc.SplitUsage
+			if errors.Is(err, bot.ErrMixedSplit) {
+				msg = loc.SplitMixed
+			}
+			sendBotMessage(e, m, msg)
+			return
+		}
+
+		shares, payerShare, err := input.Debts()
+		if err != nil {
+			sendBotMessage(e, m, loc.SplitTooLarge)
+			return
+		}
+
+		wallet, ok := chatWallet(e, m)
+		if !ok {
+			return
+		}
+		if !wallet.CanEdit() {
+			sendBotMessage(e, m, loc.NoWalletRights)
+			return
+		}
+
+		seen := make(map[string]bool, len(shares))
+		debtors := make([]repository.User, 0, len(shares))
+		debts := make([]repository.Debt, 0, len(shares))
+		for _, p := range input.Participants {
+			if seen[p.Username] {
+				continue
+			}
+			seen[p.Username] = true
+
+			user, ok := findUser(e, m, p.Username)
+			if !ok {
+				return
+			}
+			debtors = append(debtors, user)
+			debts = append(debts, repository.Debt{DebtorID: user.ID, Amount: shares[p.Username]})
+		}
+
+		note, tags := bot.ParseNote(input.Note)
+		expense := repository.Expense{
+			UserID:   m.Sender.ID,
+			WalletID: wallet.ID,
+			Date:     time.Now(),
+			Category: input.Category,
+			Amount:   payerShare,
+			Note:     note,
+			Tags:     tags,
+		}
+
+		if err = e.repo.AddSplitExpense(expense, debts); err != nil {
+			logger.L.Error("Ошибка при добавлении общего расхода:", err)
+			return
+		}
+
+		var lines strings.Builder
+		for i, debt := range debts {
+			user := debtors[i]
+			lines.WriteString(fmt.Sprintf(loc.BalanceOwesYou, userTitle(user), loc.Amount(debt.Amount)) + "\n")
+
+			// Участник получает уведомление на своем языке
+			userLoc := e.tr(user.ID)
+			notifyUser(e, user, fmt.Sprintf(userLoc.SplitNotify,
+				userTitle(repository.User{ID: m.Sender.ID, Name: m.Sender.Username}),
+				userLoc.CategoryTitle(input.Category), userLoc.Amount(input.Amount), userLoc.Amount(debt.Amount)))
+		}
+
+		sendBotMessage(e, m, fmt.Sprintf(loc.SplitAdded, loc.Amount(input.Amount), lines.String(), loc.Amount(payerShare)))
+	}
+}
+
+// Обработчик команды /balance — взаимные долги с другими пользователями
+func cmdBalance(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		logger.L.Info("Команда /balance", "user_id", m.Sender.ID, "user_name", m.Sender.Username)
+
+		deleteBotMessage(e, m)
+		defer sendMainMenu(e, m)
+
+		balances, err := e.repo.GetBalances(m.Sender.ID)
+		if err != nil {
+			logger.L.Error("Ошибка при получении балансов:", err)
+			return
+		}
+
+		if len(balances) == 0 {
+			sendBotMessage(e, m, loc.BalanceEmpty)
+			return
+		}
+
+		var msg strings.Builder
+		msg.WriteString(loc.BalanceTitle + "\n")
+		for _, balance := range balances {
+			if balance.Amount > 0 {
+				msg.WriteString(fmt.Sprintf(loc.BalanceOwesYou, userTitle(balance.User), loc.Amount(balance.Amount)) + "\n")
+			} else {
+				msg.WriteString(fmt.Sprintf(loc.BalanceYouOwe, userTitle(balance.User), loc.Amount(-balance.Amount)) + "\n")
+			}
+		}
+
+		sendBotMessage(e, m, msg.String())
+	}
+}
+
+// Обработчик команды /settle @user [сумма] — возврат долга пользователю
+func cmdSettle(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		logger.L.Info("Команда /settle", "user_id", m.Sender.ID, "user_name", m.Sender.Username)
+
+		deleteBotMessage(e, m)
+		defer sendMainMenu(e, m)
+
+		fields := strings.Fields(m.Payload)
+		if len(fields) == 0 || len(fields) > 2 || !strings.HasPrefix(fields[0], "@") {
+			sendBotMessage(e, m, loc.SettleUsage)
+			return
+		}
+
+		user, ok := findUser(e, m, strings.TrimPrefix(fields[0], "@"))
+		if !ok {
+			return
+		}
+
+		var amount float64
+		if len(fields) == 2 {
+			var err error
+			if amount, err = bot.ParseAmount(fields[1]); err != nil {
+				sendBotMessage(e, m, loc.SettleUsage)
+				return
+			}
+		} else {
+			// Без суммы гасится весь текущий долг
+			debt, err := debtTo(e, m.Sender.ID, user.ID)
+			if err != nil {
+				logger.L.Error("Ошибка при получении балансов:", err)
+				return
+			}
+			if debt <= 0 {
+				sendBotMessage(e, m, fmt.Sprintf(loc.SettleNothing, userTitle(user)))
+				return
+			}
+			amount = debt
+		}
+
+		if err := e.repo.AddSettlement(m.Sender.ID, user.ID, amount); err != nil {
+			logger.L.Error("Ошибка при записи возврата долга:", err)
+			return
+		}
+
+		sendBotMessage(e, m, fmt.Sprintf(loc.SettleDone, loc.Amount(amount), userTitle(user)))
+		userLoc := e.tr(user.ID)
+		notifyUser(e, user, fmt.Sprintf(userLoc.SettleNotify,
+			userTitle(repository.User{ID: m.Sender.ID, Name: m.Sender.Username}), userLoc.Amount(amount)))
+	}
+}
+
+// Поиск участника по имени; если он не найден, пользователю отправляется сообщение
+func findUser(e *ExpenseBot, m *telebot.Message, userName string) (repository.User, bool) {
+	loc := e.tr(m.Sender.ID)
+	user, err := e.repo.GetUserByName(userName)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		sendBotMessage(e, m, fmt.Sprintf(loc.SplitUserNotFound, userName))
+		return repository.User{}, false
+	}
+	if err != nil {
+		logger.L.Error("Ошибка при поиске пользователя:", err)
+		return repository.User{}, false
+	}
+	if user.ID == m.Sender.ID {
+		sendBotMessage(e, m, loc.SplitSelf)
+		return repository.User{}, false
+	}
+
+	return user, true
+}
+
+// Сколько пользователь должен другому, 0 если долга нет
+func debtTo(e *ExpenseBot, userID int, otherID int) (float64, error) {
+	balances, err := e.repo.GetBalances(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, balance := range balances {
+		if balance.User.ID == otherID && balance.Amount < 0 {
+			return math.Abs(balance.Amount), nil
+		}
+	}
+
+	return 0, nil
+}
+
+func userTitle(user repository.User) string {
+	if user.Name == "" {
+		return strconv.Itoa(user.ID)
+	}
+
+	return "@" + user.Name
+}
+
+// Уведомление пользователя в его сохраненный чат, для личного чата он совпадает с ID пользователя
+func notifyUser(e *ExpenseBot, user repository.User, msg string) {
+	chatID := user.ChatID
+	if chatID == 0 {
+		chatID = int64(user.ID)
+	}
+
+	if _, err := e.bot.Send(&telebot.Chat{ID: chatID}, msg); err != nil {
+		logger.L.ErrorSendMessage(err)
+	}
+}
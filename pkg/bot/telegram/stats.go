@@ -0,0 +1,183 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/repository"
+)
+
+const (
+	// За сколько дней показываются расходы по дням
+	statsDays = 14
+	// За сколько недель показываются регистрации и когорты удержания
+	statsWeeks = 8
+	// Сколько недель после регистрации отслеживается удержание
+	retentionWeeks = 4
+	// Сколько популярных категорий показывается
+	statsTopCategories = 5
+	// Длина самого длинного столбца графика
+	statsBarWidth = 12
+)
+
+// touchActivity отмечает, что пользователь пользовался ботом сегодня; в базу пишется раз в день
+func (e *ExpenseBot) touchActivity(user *telebot.User) {
+	if user == nil {
+		return
+	}
+
+	now := time.Now()
+	today := now.Format("2006-01-02")
+
+	e.mu.Lock()
+	if e.active[user.ID] == today {
+		e.mu.Unlock()
+		return
+	}
+	e.active[user.ID] = today
+	e.mu.Unlock()
+
+	if err := e.repo.TouchUserActivity(user.ID, now); err != nil {
+		logger.L.Error("Ошибка при записи активности пользователя:", err, "user_id", user.ID)
+	}
+}
+
+// Обработчик команды /stats — статистика использования бота для администратора
+func cmdStats(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		report, err := getStatsReport(e, e.tr(m.Sender.ID), time.Now())
+		if err != nil {
+			logger.L.Error("Ошибка при подсчете статистики:", err)
+			return
+		}
+
+		_, err = e.bot.Send(m.Chat, report, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		if err != nil {
+			logger.L.ErrorSendMessage(err)
+		}
+	}
+}
+
+func getStatsReport(e *ExpenseBot, loc *bot.Locale, now time.Time) (string, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	weeksStart := repository.WeekStart(today).AddDate(0, 0, -7*(statsWeeks-1))
+
+	users, err := e.repo.GetUserCount()
+	if err != nil {
+		return "", err
+	}
+
+	active := make([]int, 0, 3)
+	for _, since := range []time.Time{today, today.AddDate(0, 0, -6), today.AddDate(0, 0, -29)} {
+		count, err := e.repo.GetActiveUserCount(since)
+		if err != nil {
+			return "", err
+		}
+		active = append(active, count)
+	}
+
+	registrations, err := e.repo.GetRegistrationsByDay(weeksStart)
+	if err != nil {
+		return "", err
+	}
+	expenses, err := e.repo.GetExpenseCountsByDay(today.AddDate(0, 0, -(statsDays - 1)))
+	if err != nil {
+		return "", err
+	}
+	categories, err := e.repo.GetTopCategories(today.AddDate(0, 0, -29), statsTopCategories)
+	if err != nil {
+		return "", err
+	}
+	cohorts, err := e.repo.GetRetentionCohorts(weeksStart, retentionWeeks)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(loc.StatsTitle, loc.Date(now)))
+	sb.WriteString("\n\n")
+	sb.WriteString(fmt.Sprintf(loc.StatsUsers, users))
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf(loc.StatsActive, active[0], active[1], active[2]))
+
+	sb.WriteString("\n\n" + loc.StatsExpensesByDay + "\n")
+	sb.WriteString(formatChart(fillDays(expenses, today.AddDate(0, 0, -(statsDays-1)), statsDays, 1), loc.Format.ShortDate))
+
+	sb.WriteString("\n" + loc.StatsRegistrations + "\n")
+	sb.WriteString(formatChart(fillDays(registrations, weeksStart, statsWeeks, 7), loc.Format.ShortDate))
+
+	sb.WriteString("\n" + loc.StatsTopCategories + "\n")
+	if len(categories) == 0 {
+		sb.WriteString(loc.StatsNoData + "\n")
+	}
+	for i, c := range categories {
+		sb.WriteString(fmt.Sprintf("%d. %s — %d (%s)\n", i+1, loc.CategoryTitle(c.Category), c.Count, loc.Amount(c.Amount)))
+	}
+
+	sb.WriteString("\n" + fmt.Sprintf(loc.StatsRetention, retentionWeeks) + "\n")
+	if len(cohorts) == 0 {
+		sb.WriteString(loc.StatsNoData + "\n")
+	}
+	for _, cohort := range cohorts {
+		sb.WriteString(formatCohort(loc, cohort, today))
+	}
+
+	// Моноширинный шрифт выравнивает столбцы графиков
+	return "<pre>" + html.EscapeString(strings.TrimSpace(sb.String())) + "</pre>", nil
+}
+
+// fillDays раскладывает значения по n интервалам длиной step дней начиная с start, пропуски заполняются нулями
+func fillDays(counts []repository.DayCount, start time.Time, n int, step int) []repository.DayCount {
+	filled := make([]repository.DayCount, n)
+	for i := range filled {
+		filled[i].Day = start.AddDate(0, 0, i*step)
+	}
+
+	for _, c := range counts {
+		days := int(c.Day.Sub(start).Hours()+12) / 24
+		if i := days / step; days >= 0 && i < n {
+			filled[i].Count += c.Count
+		}
+	}
+
+	return filled
+}
+
+// formatChart рисует горизонтальный столбчатый график
+func formatChart(counts []repository.DayCount, layout string) string {
+	maxCount := 0
+	for _, c := range counts {
+		maxCount = max(maxCount, c.Count)
+	}
+
+	var sb strings.Builder
+	for _, c := range counts {
+		width := 0
+		if maxCount > 0 {
+			width = (c.Count*statsBarWidth + maxCount - 1) / maxCount
+		}
+		sb.WriteString(fmt.Sprintf("%s %-*s %d\n", c.Day.Format(layout), statsBarWidth, strings.Repeat("█", width), c.Count))
+	}
+
+	return sb.String()
+}
+
+// formatCohort выводит долю вернувшихся пользователей когорты по неделям; недели, которые еще не прошли, не показываются
+func formatCohort(loc *bot.Locale, cohort repository.Cohort, today time.Time) string {
+	parts := make([]string, len(cohort.Retained))
+	for i, retained := range cohort.Retained {
+		if cohort.Start.AddDate(0, 0, 7*(i+1)).After(today) {
+			parts[i] = "   —"
+			continue
+		}
+		parts[i] = fmt.Sprintf("%3d%%", retained*100/cohort.Users)
+	}
+
+	return fmt.Sprintf("%s %4d: %s\n", loc.ShortDate(cohort.Start), cohort.Users, strings.Join(parts, " "))
+}
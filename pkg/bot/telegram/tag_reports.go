@@ -0,0 +1,111 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/bot"
+)
+
+// Период отчетов по тегам, если он не указан в команде
+const defaultTagPeriod = "period_month"
+
+// Обработчик команды /tags [период] — суммы расходов по тегам
+func cmdTagsReport(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		logger.L.Info("Команда /tags", "user_id", m.Sender.ID, "user_name", m.Sender.Username)
+
+		deleteBotMessage(e, m)
+
+		periodKey := defaultTagPeriod
+		if m.Payload != "" {
+			key, ok := bot.ParsePeriod(m.Payload)
+			if !ok {
+				sendBotMessage(e, m, loc.TagUsage)
+				sendMainMenu(e, m)
+				return
+			}
+			periodKey = key
+		}
+		wallet, ok := getWallet(e, m.Sender.ID)
+		if !ok {
+			sendBotMessage(e, m, loc.ErrorReg)
+			return
+		}
+
+		startDate, endDate := e.periodDates(m.Sender.ID, periodKey)
+		period := periodHeader(loc, periodKey, startDate, endDate)
+		totals, err := e.repo.GetTagTotalsUnix(wallet.ID, startDate, endDate)
+		if err != nil {
+			logger.L.Error("Ошибка при получении расходов по тегам:", err)
+		}
+
+		if len(totals) == 0 {
+			sendBotMessage(e, m, fmt.Sprintf(loc.TagsEmpty, period))
+		} else {
+			header := fmt.Sprintf(loc.TagsReport, period) + "\n"
+			sendBotMessage(e, m, formatTotalsReport(loc, header, prefixTags(totals)))
+		}
+
+		sendMainMenu(e, m)
+	}
+}
+
+// Обработчик команды /tag #тег [период] — расходы с тегом по категориям
+func cmdTagReport(e *ExpenseBot) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		logger.L.Info("Команда /tag", "user_id", m.Sender.ID, "user_name", m.Sender.Username)
+
+		deleteBotMessage(e, m)
+
+		args := strings.Fields(m.Payload)
+		if len(args) == 0 || bot.NormalizeTag(args[0]) == "" {
+			sendBotMessage(e, m, loc.TagUsage)
+			sendMainMenu(e, m)
+			return
+		}
+		tag := bot.NormalizeTag(args[0])
+
+		periodKey := defaultTagPeriod
+		if len(args) > 1 {
+			key, ok := bot.ParsePeriod(strings.Join(args[1:], " "))
+			if !ok {
+				sendBotMessage(e, m, loc.TagUsage)
+				sendMainMenu(e, m)
+				return
+			}
+			periodKey = key
+		}
+		wallet, ok := getWallet(e, m.Sender.ID)
+		if !ok {
+			sendBotMessage(e, m, loc.ErrorReg)
+			return
+		}
+
+		startDate, endDate := e.periodDates(m.Sender.ID, periodKey)
+		period := periodHeader(loc, periodKey, startDate, endDate)
+		expenses, err := e.repo.GetExpensesByTagUnix(wallet.ID, tag, startDate, endDate)
+		if err != nil {
+			logger.L.Error("Ошибка при получении расходов по тегу:", err)
+		}
+
+		header := fmt.Sprintf(loc.TagReport, tag, period) + "\n"
+		sendBotMessage(e, m, formatTotalsReport(loc, header, categoryTitles(loc, expenses)))
+
+		sendMainMenu(e, m)
+	}
+}
+
+func prefixTags(totals map[string]float64) map[string]float64 {
+	prefixed := make(map[string]float64, len(totals))
+	for tag, sum := range totals {
+		prefixed["#"+tag] = sum
+	}
+
+	return prefixed
+}
@@ -2,39 +2,110 @@ package telegram
 
 import (
 	"fmt"
-	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/tucnak/telebot"
 
 	"expense_accounting_bot/internal/utils/logger"
 	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/receipt"
 	"expense_accounting_bot/pkg/repository"
 )
 
 // ExpenseBot структура для бота с телеграмом
 type ExpenseBot struct {
-	bot     *telebot.Bot
-	repo    repository.ExpenseRepository
-	adminID int
+	bot  *telebot.Bot
+	repo repository.ExpenseRepository
+	// Кто может зарегистрироваться в боте
+	registration Registration
+
+	mu sync.Mutex
+	// Чеки, ожидающие выбора категории
+	receipts map[int]pendingReceipt
+	// Открытые пользователями списки расходов
+	lists map[int]expenseList
+	// Обработчики текста, который бот ждет от пользователей: сумма, название кошелька
+	inputs map[int]func(*telebot.Message)
+	// День последней записанной активности пользователей
+	active map[int]string
+	// Языки пользователей
+	languages map[int]userLanguage
+	// Ограничение частоты обновлений от пользователей
+	limiter *userLimiter
+	// Виды зарегистрированных обработчиков: по ним AcceptUpdate отличает обновления, которые дойдут до обработчика
+	handlers map[string]int
+	// Бот запущен: обработчики больше не регистрируются
+	started bool
+
+	// Выполняющиеся обработчики, которых нужно дождаться при остановке
+	tasks tasks
+	// Закрывается, когда бот перестал получать обновления
+	done chan struct{}
+	// Закрывается в начале остановки, чтобы фоновые задачи прервали работу
+	stopping chan struct{}
+	stopOnce sync.Once
 }
 
 // NewExpenseBot создает нового ExpenseBot
-func NewExpenseBot(bot *telebot.Bot, repo repository.ExpenseRepository, adminID int) *ExpenseBot {
-	return &ExpenseBot{bot: bot, repo: repo, adminID: adminID}
+func NewExpenseBot(bot *telebot.Bot, repo repository.ExpenseRepository, registration Registration) *ExpenseBot {
+	// Источник отдает обновление, только когда telebot его принял: так после остановки источника
+	// все учтенные обновления уже переданы обработчикам, и очередь дожидаться не нужно
+	bot.Updates = make(chan telebot.Update)
+
+	return &ExpenseBot{
+		bot:          bot,
+		repo:         repo,
+		registration: registration,
+		receipts:     make(map[int]pendingReceipt),
+		lists:        make(map[int]expenseList),
+		inputs:       make(map[int]func(*telebot.Message)),
+		active:       make(map[int]string),
+		languages:    make(map[int]userLanguage),
+		limiter:      newUserLimiter(),
+		handlers:     make(map[string]int),
+		done:         make(chan struct{}),
+		stopping:     make(chan struct{}),
+	}
 }
 
-// Start запускает обработку сообщений
+// Start запускает обработку сообщений и блокируется до остановки бота
 func (e *ExpenseBot) Start() {
-	// Создаем клавиатуру с кнопками
-	menu := &telebot.ReplyMarkup{ResizeReplyKeyboard: true}
-
-	e.bot.Handle("/countusers", cmdSendUserCount(e, menu))
-	e.bot.Handle("/logs", cmdSendLogFile(e, menu))
+	// Команды администрирования с минимальной ролью, которой они доступны
+	e.guardMessage("/countusers", repository.UserRoleSupport, cmdSendUserCount(e))
+	e.guardMessage("/stats", repository.UserRoleSupport, cmdStats(e))
+	e.guardMessage("/logs", repository.UserRoleSupport, cmdSendLogFile(e))
+	e.guardMessage("/broadcast", repository.UserRoleAdmin, cmdBroadcast(e))
+	e.guardMessage("/roles", repository.UserRoleAdmin, cmdRoles(e))
+	e.guardMessage("/grant", repository.UserRoleAdmin, cmdGrant(e))
+	e.guardMessage("/revoke", repository.UserRoleAdmin, cmdRevoke(e))
+	e.guardMessage("/audit", repository.UserRoleAdmin, cmdAudit(e))
+	e.guardMessage("/invite", repository.UserRoleAdmin, cmdInvite(e))
+	e.guardMessage("/ban", repository.UserRoleAdmin, cmdBan(e))
+	e.guardMessage("/unban", repository.UserRoleAdmin, cmdUnban(e))
+
+	e.handle(telebot.OnDocument, handleOnDocument(e))
+	e.handle("/tags", cmdTagsReport(e))
+	e.handle("/tag", cmdTagReport(e))
+	e.handle("/find", cmdFind(e))
+	e.handle("/split", cmdSplit(e))
+	e.handle("/balance", cmdBalance(e))
+	e.handle("/settle", cmdSettle(e))
+	e.handle("/help", cmdHelp(e))
+	e.handle("/add", cmdAdd(e))
+	e.handle("/report", cmdReport(e))
+	e.handle("/rename", cmdRename(e))
+	e.handle("/language", cmdLanguage(e))
+	e.handle("/weekstart", cmdWeekStart(e))
+	e.handle("/monthstart", cmdMonthStart(e))
+	e.handle("/recurring", cmdRecurring(e))
+	e.handle(telebot.OnQuery, handleInlineQuery(e))
 
 	// Обработчик команды /start
-	e.bot.Handle("/start", func(m *telebot.Message) {
-		logger.L.Info(fmt.Sprintf("Команда /start от пользователя %s", m.Sender.Username))
+	e.handle("/start", func(m *telebot.Message) {
+		logger.L.Info("Команда /start", "user_id", m.Sender.ID, "user_name", m.Sender.Username)
+		loc := e.tr(m.Sender.ID)
 
 		userID := m.Sender.ID
 		userName := m.Sender.Username
@@ -42,94 +113,164 @@ func (e *ExpenseBot) Start() {
 		// Проверяем, зарегистрирован ли пользователь
 		isRegistered, dateReg, err := e.repo.IsUserRegistered(userID)
 		if err != nil {
-			logger.L.Error(bot.MessagesList.ErrorReg, err)
-			sendBotMessage(e, m, bot.MessagesList.ErrorReg)
+			logger.L.Error("Ошибка при проверке регистрации:", err)
+			sendBotMessage(e, m, loc.ErrorReg)
 			return
 		}
 
 		if isRegistered {
-			deleteBotMessage(e, userID)
+			deleteBotMessage(e, m)
 
-			sendBotMessage(e, m, fmt.Sprintf(bot.MessagesList.UserRegistered, userName, dateReg))
-			sendBotMessageWithMenu(e, m, bot.MessagesList.SelectAction, menu)
+			sendBotMessage(e, m, fmt.Sprintf(loc.UserRegistered, userName, dateReg))
+			if strings.HasPrefix(m.Payload, walletInvitePrefix) {
+				acceptWalletInvite(e, m)
+			}
+			sendMainMenu(e, m)
 
 			return
 		}
 
-		// Регистрируем нового пользователя
-		err = e.repo.AddUser(userID, userName)
+		// Регистрируем нового пользователя. Когда регистрация закрыта, зарегистрироваться можно только
+		// по приглашению или из списка разрешенных
+		registered, err := registerUser(e, m)
 		if err != nil {
-			logger.L.Error(bot.MessagesList.ErrorReg, err)
-			sendBotMessage(e, m, bot.MessagesList.ErrorReg)
+			logger.L.Error("Ошибка при регистрации пользователя:", err)
+			sendBotMessage(e, m, loc.ErrorReg)
+			return
+		}
+		if !registered {
+			sendBotMessage(e, m, loc.RegistrationClosed)
 			return
 		}
+		// Язык интерфейса мог прийти раньше, чем появилась запись пользователя
+		if err = e.repo.SetUserLanguageCode(userID, e.userLanguage(userID).detected); err != nil {
+			logger.L.Error("Ошибка при сохранении языка пользователя:", err)
+		}
 
-		msg := fmt.Sprintf(bot.MessagesList.Welcome, m.Sender.FirstName, m.Sender.LastName)
+		msg := fmt.Sprintf(loc.Welcome, m.Sender.FirstName, m.Sender.LastName)
 		sendBotMessage(e, m, msg)
 
-		sendBotMessageWithMenu(e, m, bot.MessagesList.SelectAction, menu)
-	})
+		if strings.HasPrefix(m.Payload, walletInvitePrefix) {
+			acceptWalletInvite(e, m)
+		}
 
-	createButtonsMainMenu(e, menu)
+		sendMainMenu(e, m)
+	})
 
-	// Запуск бота
+	e.handle(telebot.OnText, handleOnText(e))
+
+	// Кнопки регистрируются один раз до запуска: telebot не защищает свои обработчики от изменения
+	// во время работы, поэтому все, что отличает одно нажатие от другого, передается в данных кнопки.
+	// Так же кнопки работают и в сообщениях, отправленных до перезапуска
+	handleMenuButtons(e)
+	handleCategoryButtons(e)
+	handleReceiptButtons(e)
+	handlePeriodButtons(e)
+	handleExpenseListButtons(e)
+	handleWalletButtons(e)
+	handleLanguageButtons(e)
+	handleAnomalyButtons(e)
+	handleBroadcastButtons(e)
+	handleRecurringButtons(e)
+
+	// Рассылки, прерванные прошлой остановкой, продолжаются с оставшихся получателей
+	resumeBroadcasts(e)
+	// Регулярные расходы записываются и ищутся в фоне до остановки бота
+	e.tasks.spawn(e.runRecurringJobs)
+
+	e.mu.Lock()
+	e.started = true
+	e.mu.Unlock()
+
+	// Запуск бота, Start telebot возвращается после остановки получения обновлений
 	e.bot.Start()
+	close(e.done)
+}
+
+// Обработчики кнопок главного меню и кнопки "Назад"
+func handleMenuButtons(e *ExpenseBot) {
+	e.handle(&telebot.InlineButton{Unique: "btn_schedule"}, btnNewExpenseFunc(e))
+	e.handle(&telebot.InlineButton{Unique: "btn_services"}, btnMyExpensesFunc(e))
+	e.handle(&telebot.InlineButton{Unique: "btn_wallets"}, btnWalletsFunc(e))
+	e.handle(&telebot.InlineButton{Unique: "btn_back"}, btnBackFunc(e))
 }
 
-func createButtonsMainMenu(e *ExpenseBot, menu *telebot.ReplyMarkup) {
+// mainMenu создает клавиатуру главного меню на языке пользователя
+func mainMenu(e *ExpenseBot, loc *bot.Locale) *telebot.ReplyMarkup {
 	// Создаем кнопки
 	btnNewExpense := telebot.InlineButton{
 		Unique: "btn_schedule",
-		Text:   bot.BtnTitlesList.BtnNewExpense,
+		Text:   loc.BtnNewExpense,
 	}
 	btnMyExpenses := telebot.InlineButton{
 		Unique: "btn_services",
-		Text:   bot.BtnTitlesList.BtnMyExpenses,
+		Text:   loc.BtnMyExpenses,
+	}
+	btnWallets := telebot.InlineButton{
+		Unique: "btn_wallets",
+		Text:   loc.BtnWallets,
 	}
 
 	// Обработчики для кнопок
-	e.bot.Handle(&btnNewExpense, btnNewExpenseFunc(e, menu))
-	e.bot.Handle(&btnMyExpenses, btnMyExpensesFunc(e, menu))
 
-	menu.InlineKeyboard = [][]telebot.InlineButton{
-		{btnNewExpense},
-		{btnMyExpenses},
+	return &telebot.ReplyMarkup{
+		ResizeReplyKeyboard: true,
+		InlineKeyboard: [][]telebot.InlineButton{
+			{btnNewExpense},
+			{btnMyExpenses},
+			{btnWallets},
+		},
 	}
-
-	e.bot.Handle(telebot.OnText, handleOnText(e, menu))
 }
 
-func handleOnText(e *ExpenseBot, menu *telebot.ReplyMarkup) func(*telebot.Message) {
+// Единственный обработчик текста: ожидаемый ввод передается начавшему его обработчику,
+// остальной текст разбирается как чек или считается неизвестным действием
+func handleOnText(e *ExpenseBot) func(*telebot.Message) {
 	return func(m *telebot.Message) {
-		userID := m.Sender.ID
-		deleteBotMessage(e, userID)
+		// Обычные сообщения участников группы к боту не относятся
+		if !m.Private() {
+			return
+		}
 
-		// Перехват сообщения от пользователя
-		if m.Text == "/help" {
-			sendBotMessage(e, m, bot.MessagesList.Help)
-		} else {
-			sendBotMessage(e, m, bot.MessagesList.UnknownAction)
+		if handler, ok := e.pendingInput(m.Sender.ID); ok {
+			handler(m)
+			return
 		}
 
-		createButtonsMainMenu(e, menu)
-		sendBotMessageWithMenu(e, m, bot.MessagesList.SelectAction, menu)
+		deleteBotMessage(e, m)
+
+		if receipt.IsReceipt(m.Text) {
+			handleReceipt(e, m)
+			return
+		}
+
+		loc := e.tr(m.Sender.ID)
+		sendBotMessage(e, m, loc.UnknownAction)
+
+		sendBotMessageWithMenu(e, m, loc.SelectAction, mainMenu(e, loc))
 	}
 }
 
-func btnBackFunc(e *ExpenseBot, menu *telebot.ReplyMarkup, backTo string) func(*telebot.Callback) {
+// backButton кнопка "Назад" к экрану backTo
+func backButton(loc *bot.Locale, backTo string) telebot.InlineButton {
+	return telebot.InlineButton{
+		Unique: "btn_back",
+		Text:   loc.BtnBack,
+		Data:   backTo,
+	}
+}
+
+// Обработчик кнопки "Назад": экран, к которому она ведет, передается в данных кнопки
+func btnBackFunc(e *ExpenseBot) func(*telebot.Callback) {
 	return func(c *telebot.Callback) {
-		logger.L.Info(fmt.Sprintf("Нажата кнопка '%s' пользователем %s", bot.BtnTitlesList.BtnBack, c.Sender.Username))
-
-		menu.InlineKeyboard = nil
-		msg := ""
-
-		switch backTo {
-		case "MainMenu":
-			createButtonsMainMenu(e, menu)
-			msg = bot.MessagesList.SelectAction
-		case "SelectCategory":
-			createButtonsOfCategories(e, menu)
-			msg = bot.MessagesList.SelectCategory
+		loc := e.tr(c.Sender.ID)
+		logger.L.Info("Нажата кнопка", "button", loc.BtnBack, "user_id", c.Sender.ID, "user_name", c.Sender.Username)
+
+		menu := mainMenu(e, loc)
+		msg := loc.SelectAction
+		if c.Data == "SelectCategory" {
+			menu = createButtonsOfCategories(e, loc)
+			msg = loc.SelectCategory
 		}
 
 		editBotMessageWithMenu(e, c, msg, menu)
@@ -137,21 +278,23 @@ func btnBackFunc(e *ExpenseBot, menu *telebot.ReplyMarkup, backTo string) func(*
 }
 
 func sendBotMessage(e *ExpenseBot, m *telebot.Message, msg string) {
-	_, err := e.bot.Send(m.Sender, msg)
+	var err error
+	if m.Private() {
+		_, err = e.bot.Send(m.Chat, msg)
+	} else {
+		// В группе отвечаем на сообщение участника, чтобы было видно, кому адресован ответ
+		_, err = e.bot.Send(m.Chat, msg, &telebot.SendOptions{ReplyTo: m})
+	}
 	if err != nil {
 		logger.L.ErrorSendMessage(err)
 	}
-
-	//err = e.repo.SetLastBotMsgID(m.Sender.ID, sentMessage.ID, m.Chat.ID)
-	//if err != nil {
-	//	logger.L.ErrorSendMessage(err)
-	//}
 }
 
 func sendBotMessageWithMenu(e *ExpenseBot, m *telebot.Message, msg string, menu *telebot.ReplyMarkup) {
-	sentMessage, err := e.bot.Send(m.Sender, msg, menu)
+	sentMessage, err := e.bot.Send(m.Chat, msg, menu)
 	if err != nil {
 		logger.L.ErrorSendMessage(err)
+		return
 	}
 
 	err = e.repo.SetLastBotMsgID(m.Sender.ID, sentMessage.ID, m.Chat.ID)
@@ -160,6 +303,17 @@ func sendBotMessageWithMenu(e *ExpenseBot, m *telebot.Message, msg string, menu
 	}
 }
 
+// Отправка главного меню
+func sendMainMenu(e *ExpenseBot, m *telebot.Message) {
+	// Меню с кнопками работает только в личном чате
+	if !m.Private() {
+		return
+	}
+
+	loc := e.tr(m.Sender.ID)
+	sendBotMessageWithMenu(e, m, loc.SelectAction, mainMenu(e, loc))
+}
+
 func editBotMessageWithMenu(e *ExpenseBot, c *telebot.Callback, msg string, menu *telebot.ReplyMarkup) {
 	sentMessage, err := e.bot.Edit(c.Message, msg, menu)
 	if err != nil {
@@ -172,10 +326,11 @@ func editBotMessageWithMenu(e *ExpenseBot, c *telebot.Callback, msg string, menu
 	}
 }
 
-func deleteBotMessage(e *ExpenseBot, userID int) {
+// Удаление последнего сообщения бота пользователю в чате сообщения m
+func deleteBotMessage(e *ExpenseBot, m *telebot.Message) {
 	msg := "Ошибка при удалении сообщения:"
 
-	lastBotMsg, err := getUserMessage(e, userID)
+	lastBotMsg, err := getUserMessage(e, m.Sender.ID, m.Chat.ID)
 	if err != nil {
 		logger.L.Error(msg, err)
 		return
@@ -189,14 +344,14 @@ func deleteBotMessage(e *ExpenseBot, userID int) {
 	}
 }
 
-// Получение сообщения по user_id
-func getUserMessage(e *ExpenseBot, userID int) (*telebot.Message, error) {
-	messageID, chatID, err := e.repo.GetLastBotMsgID(userID)
+// Получение последнего сообщения бота пользователю в чате
+func getUserMessage(e *ExpenseBot, userID int, chatID int64) (*telebot.Message, error) {
+	messageID, err := e.repo.GetLastBotMsgID(userID, chatID)
 	if err != nil {
 		return nil, err
 	}
 
-	if messageID == 0 || chatID == 0 {
+	if messageID == 0 {
 		return nil, nil
 	}
 
@@ -208,7 +363,7 @@ func getUserMessage(e *ExpenseBot, userID int) (*telebot.Message, error) {
 	return message, nil
 }
 
-func getUserCountReport(e *ExpenseBot) string {
+func getUserCountReport(e *ExpenseBot, loc *bot.Locale) string {
 	// Получаем количество пользователей из базы данных
 	userCount, err := e.repo.GetUserCount() // функция для подсчета пользователей
 	if err != nil {
@@ -216,26 +371,15 @@ func getUserCountReport(e *ExpenseBot) string {
 	}
 
 	// Формируем сообщение
-	return fmt.Sprintf("На %s количество пользователей: %d", time.Now().Format("02/01/2006"), userCount)
+	return fmt.Sprintf(loc.UserCount, loc.Date(time.Now()), userCount)
 }
 
-func cmdSendUserCount(e *ExpenseBot, menu *telebot.ReplyMarkup) func(*telebot.Message) {
+func cmdSendUserCount(e *ExpenseBot) func(*telebot.Message) {
 	return func(m *telebot.Message) {
-		userID := m.Sender.ID
-		if userID != e.adminID {
-			deleteBotMessage(e, userID)
-
-			msg := fmt.Sprintf("Команда доступна только для админа id:%v, Ваш id:%v", e.adminID, userID)
-			sendBotMessage(e, m, msg)
-			createButtonsMainMenu(e, menu)
-			sendBotMessageWithMenu(e, m, bot.MessagesList.SelectAction, menu)
+		loc := e.tr(m.Sender.ID)
+		logger.L.Info("Нажата кнопка", "button", loc.BtnNewExpense, "user_id", m.Sender.ID, "user_name", m.Sender.Username)
 
-			return
-		}
-
-		logger.L.Info(fmt.Sprintf("Нажата кнопка '%s' пользователем %s", bot.BtnTitlesList.BtnNewExpense, m.Sender.Username))
-
-		message := getUserCountReport(e)
+		message := getUserCountReport(e, loc)
 
 		_, err := e.bot.Send(m.Sender, message)
 		if err != nil {
@@ -243,45 +387,3 @@ func cmdSendUserCount(e *ExpenseBot, menu *telebot.ReplyMarkup) func(*telebot.Me
 		}
 	}
 }
-
-func cmdSendLogFile(e *ExpenseBot, menu *telebot.ReplyMarkup) func(*telebot.Message) {
-	return func(m *telebot.Message) {
-		userID := m.Sender.ID
-		if m.Sender.ID != e.adminID {
-			deleteBotMessage(e, userID)
-
-			msg := fmt.Sprintf("Команда доступна только для админа id:%v, Ваш id:%v", e.adminID, userID)
-			sendBotMessage(e, m, msg)
-			createButtonsMainMenu(e, menu)
-			sendBotMessageWithMenu(e, m, bot.MessagesList.SelectAction, menu)
-
-			return
-		}
-
-		data, err := os.ReadFile("bot.log")
-		if err != nil {
-			_, err = e.bot.Send(m.Sender, "Не удалось прочитать файл лога: "+err.Error())
-			if err != nil {
-				logger.L.ErrorSendMessage(err)
-			}
-			return
-		}
-
-		// Ограничение размера сообщения (Telegram ограничивает размер сообщения)
-		messageSizeLimit := 4096
-		logContent := string(data)
-		// Если лог-файл слишком большой, разделим его на части
-		for i := 0; i < len(logContent); i += messageSizeLimit {
-			end := i + messageSizeLimit
-			if end > len(logContent) {
-				end = len(logContent)
-			}
-
-			// Отправка частей файла в сообщениях
-			_, err = e.bot.Send(m.Sender, logContent[i:end])
-			if err != nil {
-				logger.L.ErrorSendMessage(err)
-			}
-		}
-	}
-}
@@ -0,0 +1,272 @@
+package telegram
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+	"expense_accounting_bot/pkg/bot"
+	"expense_accounting_bot/pkg/repository"
+)
+
+const (
+	// Префикс параметра /start для приглашений в кошелек
+	walletInvitePrefix = "w_"
+	// Срок действия приглашения в кошелек
+	walletInviteTTL = 7 * 24 * time.Hour
+)
+
+// Текущий кошелек пользователя, ошибка логируется
+func getWallet(e *ExpenseBot, userID int) (repository.Wallet, bool) {
+	wallet, err := e.repo.GetCurrentWallet(userID)
+	if err != nil {
+		logger.L.Error("Ошибка при получении кошелька пользователя:", err)
+		return repository.Wallet{}, false
+	}
+
+	return wallet, true
+}
+
+// getUserWallet возвращает кошелек с ролью пользователя, если он все еще в нем состоит
+func getUserWallet(e *ExpenseBot, userID int, walletID int64) (repository.Wallet, bool) {
+	wallets, err := e.repo.GetUserWallets(userID)
+	if err != nil {
+		logger.L.Error("Ошибка при получении кошельков пользователя:", err)
+		return repository.Wallet{}, false
+	}
+
+	for _, wallet := range wallets {
+		if wallet.ID == walletID {
+			return wallet, true
+		}
+	}
+
+	return repository.Wallet{}, false
+}
+
+func walletName(loc *bot.Locale, wallet repository.Wallet) string {
+	if wallet.Personal {
+		return loc.PersonalWallet
+	}
+
+	return wallet.Name
+}
+
+func roleName(loc *bot.Locale, role string) string {
+	switch role {
+	case repository.RoleOwner:
+		return loc.RoleOwner
+	case repository.RoleMember:
+		return loc.RoleMember
+	default:
+		return loc.RoleViewer
+	}
+}
+
+// Обработчик нажатия кнопки "Кошельки"
+func btnWalletsFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		loc := e.tr(c.Sender.ID)
+		logger.L.Info("Нажата кнопка", "button", loc.BtnWallets, "user_id", c.Sender.ID, "user_name", c.Sender.Username)
+
+		e.bot.Respond(c)
+
+		msg, menu := createButtonsOfWallets(e, c.Sender.ID)
+		editBotMessageWithMenu(e, c, msg, menu)
+	}
+}
+
+// Обработчики кнопок экрана кошельков; кнопка возврата к нему обрабатывается как кнопка главного меню
+func handleWalletButtons(e *ExpenseBot) {
+	e.handle(&telebot.InlineButton{Unique: "wallet_switch"}, btnWalletSwitchFunc(e))
+	e.handle(&telebot.InlineButton{Unique: "wallet_invite"}, btnWalletInviteFunc(e))
+	e.handle(&telebot.InlineButton{Unique: "wallet_create"}, btnWalletCreateFunc(e))
+}
+
+// Экран выбора кошелька, возвращает текст сообщения и клавиатуру
+func createButtonsOfWallets(e *ExpenseBot, userID int) (string, *telebot.ReplyMarkup) {
+	loc := e.tr(userID)
+
+	current, ok := getWallet(e, userID)
+	if !ok {
+		return loc.ErrorReg, mainMenu(e, loc)
+	}
+
+	menu := &telebot.ReplyMarkup{}
+
+	wallets, err := e.repo.GetUserWallets(userID)
+	if err != nil {
+		logger.L.Error("Ошибка при получении кошельков пользователя:", err)
+	}
+
+	for _, wallet := range wallets {
+		title := walletName(loc, wallet)
+		if wallet.ID == current.ID {
+			title = "✅ " + title
+		}
+
+		btnWallet := telebot.InlineButton{
+			Unique: "wallet_switch",
+			Text:   title,
+			Data:   strconv.FormatInt(wallet.ID, 10),
+		}
+		menu.InlineKeyboard = append(menu.InlineKeyboard, []telebot.InlineButton{btnWallet})
+	}
+
+	if current.Role == repository.RoleOwner {
+		btnInviteMember := telebot.InlineButton{
+			Unique: "wallet_invite",
+			Text:   loc.BtnInviteMember,
+			Data:   repository.RoleMember,
+		}
+		btnInviteViewer := telebot.InlineButton{
+			Unique: "wallet_invite",
+			Text:   loc.BtnInviteViewer,
+			Data:   repository.RoleViewer,
+		}
+		menu.InlineKeyboard = append(menu.InlineKeyboard, []telebot.InlineButton{btnInviteMember, btnInviteViewer})
+	}
+
+	btnCreate := telebot.InlineButton{
+		Unique: "wallet_create",
+		Text:   loc.BtnCreateWallet,
+	}
+	menu.InlineKeyboard = append(menu.InlineKeyboard, []telebot.InlineButton{btnCreate})
+
+	addBackToMenuButton(loc, menu)
+
+	return fmt.Sprintf(loc.WalletInfo, walletName(loc, current), roleName(loc, current.Role), current.Members), menu
+}
+
+// Обработчик выбора кошелька
+func btnWalletSwitchFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		walletID, _ := strconv.ParseInt(c.Data, 10, 64)
+		if err := e.repo.SetCurrentWallet(c.Sender.ID, walletID); err != nil {
+			logger.L.Error("Ошибка при переключении кошелька:", err)
+		}
+		e.bot.Respond(c)
+
+		msg, menu := createButtonsOfWallets(e, c.Sender.ID)
+		editBotMessageWithMenu(e, c, msg, menu)
+	}
+}
+
+// Обработчик кнопок приглашения в текущий кошелек
+func btnWalletInviteFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		loc := e.tr(c.Sender.ID)
+		wallet, ok := getWallet(e, c.Sender.ID)
+		if !ok || wallet.Role != repository.RoleOwner {
+			e.bot.Respond(c, &telebot.CallbackResponse{Text: loc.NoWalletRights, ShowAlert: true})
+			return
+		}
+		e.bot.Respond(c)
+
+		role := repository.RoleMember
+		if c.Data == repository.RoleViewer {
+			role = repository.RoleViewer
+		}
+
+		expires := time.Now().Add(walletInviteTTL)
+		token, err := generateToken()
+		if err == nil {
+			err = e.repo.CreateWalletInvite(wallet.ID, c.Sender.ID, role, token, expires)
+		}
+		if err != nil {
+			logger.L.Error("Ошибка при создании приглашения в кошелек:", err)
+			return
+		}
+
+		link := fmt.Sprintf("https://t.me/%s?start=%s%s", e.bot.Me.Username, walletInvitePrefix, token)
+		msg := fmt.Sprintf(loc.WalletInvite, walletName(loc, wallet), roleName(loc, role), loc.DateTime(expires), link)
+
+		btnBack := telebot.InlineButton{
+			Unique: "btn_wallets",
+			Text:   loc.BtnBack,
+		}
+		menu := &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{btnBack}}}
+
+		editBotMessageWithMenu(e, c, msg, menu)
+	}
+}
+
+// Обработчик кнопки создания общего кошелька
+func btnWalletCreateFunc(e *ExpenseBot) func(*telebot.Callback) {
+	return func(c *telebot.Callback) {
+		loc := e.tr(c.Sender.ID)
+		e.bot.Respond(c)
+
+		btnBack := telebot.InlineButton{
+			Unique: "btn_wallets",
+			Text:   loc.BtnBack,
+		}
+		menu := &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{btnBack}}}
+
+		editBotMessageWithMenu(e, c, loc.EnterWalletName, menu)
+
+		e.expectInput(c.Sender.ID, createWallet(e, c))
+	}
+}
+
+func createWallet(e *ExpenseBot, c *telebot.Callback) func(m *telebot.Message) {
+	return func(m *telebot.Message) {
+		loc := e.tr(m.Sender.ID)
+		name := strings.TrimSpace(m.Text)
+		if name == "" {
+			sendBotMessage(e, m, loc.EnterWalletName)
+			return
+		}
+		e.finishInput(m.Sender.ID)
+
+		walletID, err := e.repo.CreateWallet(m.Sender.ID, name)
+		if err == nil {
+			err = e.repo.SetCurrentWallet(m.Sender.ID, walletID)
+		}
+		if err != nil {
+			logger.L.Error("Ошибка при создании кошелька:", err)
+		} else {
+			sendBotMessage(e, m, fmt.Sprintf(loc.WalletCreated, name))
+		}
+
+		editBotMessageWithMenu(e, c, loc.EnterWalletName, &telebot.ReplyMarkup{})
+
+		sendMainMenu(e, m)
+	}
+}
+
+// Вступление в кошелек по ссылке-приглашению /start w_<token>
+func acceptWalletInvite(e *ExpenseBot, m *telebot.Message) {
+	token := strings.TrimPrefix(m.Payload, walletInvitePrefix)
+	loc := e.tr(m.Sender.ID)
+
+	wallet, err := e.repo.AcceptWalletInvite(m.Sender.ID, token)
+	if errors.Is(err, repository.ErrInviteNotFound) {
+		sendBotMessage(e, m, loc.InviteNotFound)
+		return
+	}
+	if err != nil {
+		logger.L.Error("Ошибка при вступлении в кошелек:", err)
+		sendBotMessage(e, m, loc.InviteNotFound)
+		return
+	}
+
+	logger.L.Info("Пользователь вступил в кошелек", "user_id", m.Sender.ID, "user_name", m.Sender.Username, "wallet_id", wallet.ID)
+	sendBotMessage(e, m, fmt.Sprintf(loc.WalletJoined, walletName(loc, wallet), roleName(loc, wallet.Role)))
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,194 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/tucnak/telebot"
+
+	"expense_accounting_bot/internal/utils/logger"
+)
+
+const (
+	// Заголовок, в котором Telegram передает секрет, заданный при установке webhook
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+	// Ограничение размера тела запроса с обновлением
+	maxUpdateSize = 1 << 20
+	// Время на завершение обработки запросов при остановке сервера
+	webhookShutdownTimeout = 5 * time.Second
+)
+
+// Webhook получает обновления от Telegram через встроенный HTTP-сервер
+type Webhook struct {
+	updateHandlers
+
+	// Адрес, на котором слушает сервер, например ":3000"
+	Listen string
+	// Публичный адрес, который регистрируется в Telegram; его путь обслуживает сервер
+	URL string
+	// Секрет для проверки, что запрос пришел от Telegram
+	Secret string
+	// Сертификат и ключ, если TLS завершается самим ботом
+	TLSCert string
+	TLSKey  string
+
+	mu sync.Mutex
+	// Остановка началась: новые обновления не принимаются, и Telegram пришлет их повторно
+	stopping bool
+	// Запросы, обновления которых еще не переданы боту
+	inflight sync.WaitGroup
+}
+
+// NewWebhook создает Webhook; если секрет не задан, он генерируется при каждом запуске
+func NewWebhook(listen, publicURL, secret, tlsCert, tlsKey string) (*Webhook, error) {
+	if _, err := url.ParseRequestURI(publicURL); err != nil {
+		return nil, fmt.Errorf("некорректный адрес webhook: %w", err)
+	}
+
+	if secret == "" {
+		token, err := generateToken()
+		if err != nil {
+			return nil, err
+		}
+		secret = token
+	}
+
+	return &Webhook{
+		Listen:  listen,
+		URL:     publicURL,
+		Secret:  secret,
+		TLSCert: tlsCert,
+		TLSKey:  tlsKey,
+	}, nil
+}
+
+// Register устанавливает webhook в Telegram. Вызывается до запуска бота: без webhook Telegram
+// не присылает обновления, и ошибку нужно показать сразу, а не после молчаливой остановки
+func (w *Webhook) Register(b *telebot.Bot) error {
+	err := rawCall(b, "setWebhook", map[string]string{
+		"url":          w.URL,
+		"secret_token": w.Secret,
+	})
+	if err != nil {
+		return err
+	}
+	logger.L.Info("Webhook установлен", "url", w.URL)
+
+	return nil
+}
+
+// Poll принимает обновления от Telegram, пока бот не будет остановлен; webhook должен быть установлен через Register
+func (w *Webhook) Poll(b *telebot.Bot, dest chan telebot.Update, stop chan struct{}) {
+	// Закрытие stop сообщает боту, что получение обновлений завершено
+	defer close(stop)
+
+	publicURL, _ := url.Parse(w.URL)
+	path := publicURL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, w.handleUpdate(dest))
+	server := &http.Server{
+		Addr:              w.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	logger.L.Info("Сервер webhook слушает", "addr", w.Listen)
+
+	served := make(chan struct{})
+	drained := make(chan struct{})
+	go func() {
+		select {
+		case <-stop:
+		case <-served:
+			return
+		}
+
+		w.mu.Lock()
+		w.stopping = true
+		w.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.L.Error("Ошибка при остановке сервера webhook:", err)
+		}
+
+		// Принятые обновления уже подтверждены Telegram, поэтому они передаются боту, пока он еще читает dest
+		w.inflight.Wait()
+		close(drained)
+	}()
+
+	var err error
+	if w.TLSCert != "" {
+		err = server.ListenAndServeTLS(w.TLSCert, w.TLSKey)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.L.Error("Ошибка сервера webhook:", err)
+		close(served)
+		return
+	}
+
+	// Сервер перестает слушать сразу после начала остановки, а запросы в обработке дожидаются здесь
+	<-drained
+}
+
+// begin учитывает запрос с обновлением; после начала остановки обновления не принимаются
+func (w *Webhook) begin() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stopping {
+		return false
+	}
+	w.inflight.Add(1)
+
+	return true
+}
+
+// Обработчик запросов Telegram с обновлениями
+func (w *Webhook) handleUpdate(dest chan telebot.Update) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		secret := r.Header.Get(secretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(w.Secret)) != 1 {
+			logger.L.Warning("Запрос к webhook с неверным секретом", "remote_addr", r.RemoteAddr)
+			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		var update rawUpdate
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxUpdateSize)).Decode(&update); err != nil {
+			logger.L.Error("Ошибка при разборе обновления webhook:", err)
+			http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		// Обновление, на которое бот не ответил успехом, Telegram пришлет повторно
+		if !w.begin() {
+			http.Error(rw, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		defer w.inflight.Done()
+
+		// Telegram не пришлет принятое обновление повторно, поэтому ответ отправляется, когда бот его принял
+		w.dispatch(update, dest)
+		rw.WriteHeader(http.StatusOK)
+	}
+}
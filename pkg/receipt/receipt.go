@@ -0,0 +1,84 @@
+package receipt
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotReceipt  = errors.New("строка не является QR-кодом чека")
+	ErrNotPurchase = errors.New("чек не является чеком прихода")
+)
+
+// Тип расчета "приход" в QR-коде чека
+const operationPurchase = 1
+
+// Receipt данные из QR-кода кассового чека (ФНС)
+type Receipt struct {
+	Date time.Time
+	Sum  float64
+	// Номер фискального накопителя
+	FN string
+	// Номер фискального документа
+	FD string
+	// Фискальный признак документа
+	FP string
+}
+
+// IsReceipt проверяет, похожа ли строка на содержимое QR-кода чека
+func IsReceipt(s string) bool {
+	s = strings.TrimSpace(s)
+	return strings.HasPrefix(s, "t=") && strings.Contains(s, "fn=") && strings.Contains(s, "fp=")
+}
+
+// Parse разбирает строку вида t=20240115T1230&s=1234.50&fn=...&i=...&fp=...&n=1
+func Parse(s string) (Receipt, error) {
+	if !IsReceipt(s) {
+		return Receipt{}, ErrNotReceipt
+	}
+
+	values, err := url.ParseQuery(strings.TrimSpace(s))
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	if n := values.Get("n"); n != "" && n != strconv.Itoa(operationPurchase) {
+		return Receipt{}, ErrNotPurchase
+	}
+
+	date, err := parseDate(values.Get("t"))
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	sum, err := strconv.ParseFloat(values.Get("s"), 64)
+	if err != nil || sum <= 0 {
+		return Receipt{}, ErrNotReceipt
+	}
+
+	r := Receipt{
+		Date: date,
+		Sum:  sum,
+		FN:   values.Get("fn"),
+		FD:   values.Get("i"),
+		FP:   values.Get("fp"),
+	}
+	if r.FN == "" || r.FD == "" || r.FP == "" {
+		return Receipt{}, ErrNotReceipt
+	}
+
+	return r, nil
+}
+
+// Дата в чеке бывает с секундами и без
+func parseDate(s string) (time.Time, error) {
+	date, err := time.ParseInLocation("20060102T150405", s, time.Local)
+	if err == nil {
+		return date, nil
+	}
+
+	return time.ParseInLocation("20060102T1504", s, time.Local)
+}
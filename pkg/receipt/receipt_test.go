@@ -0,0 +1,91 @@
+package receipt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readFixture(t *testing.T, file string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestParseFiles(t *testing.T) {
+	tests := []struct {
+		file string
+		want Receipt
+	}{
+		{
+			file: "seconds.txt",
+			want: Receipt{Date: time.Date(2024, 1, 15, 12, 30, 45, 0, time.Local), Sum: 1234.5, FN: "9960440301234567", FD: "12345", FP: "1234567890"},
+		},
+		{
+			file: "minutes.txt",
+			want: Receipt{Date: time.Date(2024, 1, 15, 12, 30, 0, 0, time.Local), Sum: 99, FN: "9289000100405123", FD: "4321", FP: "3826718297"},
+		},
+		{
+			// Без типа расчета, с пробелами вокруг строки и целой суммой
+			file: "no_operation.txt",
+			want: Receipt{Date: time.Date(2024, 3, 1, 9, 5, 0, 0, time.Local), Sum: 500, FN: "7281440500123456", FD: "77", FP: "2553219870"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			text := readFixture(t, tt.file)
+			if !IsReceipt(text) {
+				t.Fatal("строка не распознана как чек")
+			}
+
+			got, err := Parse(text)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !got.Date.Equal(tt.want.Date) || got.Sum != tt.want.Sum || got.FN != tt.want.FN ||
+				got.FD != tt.want.FD || got.FP != tt.want.FP {
+				t.Errorf("Parse = %+v, ожидалось %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalidFiles(t *testing.T) {
+	tests := []struct {
+		file string
+		want error
+	}{
+		{"refund.txt", ErrNotPurchase},
+		{"no_fd.txt", ErrNotReceipt},
+		{"zero_sum.txt", ErrNotReceipt},
+		{"url.txt", ErrNotReceipt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if _, err := Parse(readFixture(t, tt.file)); !errors.Is(err, tt.want) {
+				t.Errorf("ошибка %v, ожидалась %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Parse(readFixture(t, "bad_date.txt")); err == nil {
+		t.Error("чек с некорректной датой разобран без ошибки")
+	}
+}
+
+func TestIsReceipt(t *testing.T) {
+	for _, text := range []string{"", "500 продукты", "t=20240115T1230&s=100", "fn=1&fp=2&t=20240115T1230"} {
+		if IsReceipt(text) {
+			t.Errorf("IsReceipt(%q) = true", text)
+		}
+	}
+}
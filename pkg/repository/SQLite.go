@@ -2,6 +2,8 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -57,17 +59,270 @@ func (r *SQLiteExpenseRepository) InitSchema() error {
     );
     CREATE INDEX IF NOT EXISTS idx_user ON user_categories (user_id);`
 	_, err = r.db.Exec(query)
-	return err
+	if err != nil {
+		return err
+	}
+
+	query = `
+    CREATE TABLE IF NOT EXISTS receipts (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        wallet_id INTEGER NOT NULL,
+        expense_id INTEGER NOT NULL,
+        fn TEXT NOT NULL,
+        fd TEXT NOT NULL,
+        fp TEXT NOT NULL,
+        UNIQUE(wallet_id, fn, fd, fp),
+        FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE
+    );`
+	_, err = r.db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	query = `
+    CREATE TABLE IF NOT EXISTS tags (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        name TEXT NOT NULL,
+        UNIQUE(user_id, name)
+    );
+    CREATE TABLE IF NOT EXISTS expense_tags (
+        expense_id INTEGER NOT NULL,
+        tag_id INTEGER NOT NULL,
+        PRIMARY KEY (expense_id, tag_id),
+        FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE,
+        FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+    );
+    CREATE INDEX IF NOT EXISTS idx_expense_tags_tag ON expense_tags (tag_id);`
+	_, err = r.db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	query = `
+    CREATE TABLE IF NOT EXISTS wallets (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name TEXT NOT NULL DEFAULT '',
+        owner_id INTEGER NOT NULL,
+        personal INTEGER NOT NULL DEFAULT 0,
+        created TEXT
+    );
+    CREATE TABLE IF NOT EXISTS wallet_members (
+        wallet_id INTEGER NOT NULL,
+        user_id INTEGER NOT NULL,
+        role TEXT NOT NULL,
+        PRIMARY KEY (wallet_id, user_id),
+        FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE
+    );
+    CREATE INDEX IF NOT EXISTS idx_wallet_members_user ON wallet_members (user_id);
+    CREATE TABLE IF NOT EXISTS wallet_invites (
+        token TEXT PRIMARY KEY,
+        wallet_id INTEGER NOT NULL,
+        role TEXT NOT NULL,
+        created_by INTEGER NOT NULL,
+        expires_ms INTEGER NOT NULL,
+        created TEXT,
+        FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE
+    );`
+	_, err = r.db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	query = `
+    CREATE TABLE IF NOT EXISTS splits (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        expense_id INTEGER NOT NULL DEFAULT 0,
+        creditor_id INTEGER NOT NULL,
+        debtor_id INTEGER NOT NULL,
+        amount REAL NOT NULL,
+        date_ms INTEGER NOT NULL
+    );
+    CREATE INDEX IF NOT EXISTS idx_splits_creditor ON splits (creditor_id);
+    CREATE INDEX IF NOT EXISTS idx_splits_debtor ON splits (debtor_id);
+    CREATE TABLE IF NOT EXISTS settlements (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        from_id INTEGER NOT NULL,
+        to_id INTEGER NOT NULL,
+        amount REAL NOT NULL,
+        date_ms INTEGER NOT NULL
+    );
+    CREATE INDEX IF NOT EXISTS idx_settlements_from ON settlements (from_id);
+    CREATE INDEX IF NOT EXISTS idx_settlements_to ON settlements (to_id);
+    CREATE TABLE IF NOT EXISTS bot_messages (
+        user_id INTEGER NOT NULL,
+        chat_id INTEGER NOT NULL,
+        msg_id INTEGER NOT NULL,
+        PRIMARY KEY (user_id, chat_id)
+    );
+    CREATE TABLE IF NOT EXISTS user_activity (
+        user_id INTEGER NOT NULL,
+        day TEXT NOT NULL,
+        PRIMARY KEY (user_id, day)
+    );
+    CREATE INDEX IF NOT EXISTS idx_user_activity_day ON user_activity (day);
+    CREATE TABLE IF NOT EXISTS broadcasts (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        text TEXT NOT NULL,
+        created_by INTEGER NOT NULL,
+        status TEXT NOT NULL,
+        created TEXT
+    );
+    CREATE TABLE IF NOT EXISTS broadcast_deliveries (
+        broadcast_id INTEGER NOT NULL,
+        user_id INTEGER NOT NULL,
+        chat_id INTEGER NOT NULL,
+        status TEXT NOT NULL,
+        error TEXT NOT NULL DEFAULT '',
+        sent_ms INTEGER NOT NULL DEFAULT 0,
+        PRIMARY KEY (broadcast_id, user_id),
+        FOREIGN KEY (broadcast_id) REFERENCES broadcasts(id) ON DELETE CASCADE
+    );
+    CREATE INDEX IF NOT EXISTS idx_broadcast_deliveries_status ON broadcast_deliveries (broadcast_id, status);
+    CREATE TABLE IF NOT EXISTS user_roles (
+        user_id INTEGER PRIMARY KEY,
+        role TEXT NOT NULL,
+        granted_by INTEGER NOT NULL DEFAULT 0,
+        granted TEXT
+    );
+    CREATE TABLE IF NOT EXISTS audit_log (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        actor_id INTEGER NOT NULL,
+        action TEXT NOT NULL,
+        target_id INTEGER NOT NULL DEFAULT 0,
+        details TEXT NOT NULL DEFAULT '',
+        date_ms INTEGER NOT NULL
+    );
+    CREATE TABLE IF NOT EXISTS registration_invites (
+        code TEXT PRIMARY KEY,
+        created_by INTEGER NOT NULL,
+        uses_left INTEGER NOT NULL,
+        expires_ms INTEGER NOT NULL,
+        created TEXT
+    );
+    CREATE TABLE IF NOT EXISTS recurring_rules (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        wallet_id INTEGER NOT NULL,
+        category TEXT NOT NULL,
+        amount REAL NOT NULL,
+        note TEXT NOT NULL DEFAULT '',
+        interval TEXT NOT NULL,
+        day INTEGER NOT NULL,
+        next_ms INTEGER NOT NULL
+    );
+    CREATE INDEX IF NOT EXISTS idx_recurring_rules_next ON recurring_rules (next_ms);
+    CREATE TABLE IF NOT EXISTS recurring_detections (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        wallet_id INTEGER NOT NULL,
+        key TEXT NOT NULL,
+        category TEXT NOT NULL,
+        amount REAL NOT NULL,
+        note TEXT NOT NULL DEFAULT '',
+        interval TEXT NOT NULL,
+        day INTEGER NOT NULL,
+        next_ms INTEGER NOT NULL,
+        count INTEGER NOT NULL,
+        status TEXT NOT NULL,
+        UNIQUE (user_id, wallet_id, key)
+    );`
+	_, err = r.db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	return r.migrate()
+}
+
+// migrate добавляет новые колонки в таблицы, созданные предыдущими версиями бота
+func (r *SQLiteExpenseRepository) migrate() error {
+	if err := r.addColumnIfNotExists("expenses", "note", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := r.addColumnIfNotExists("expenses", "wallet_id", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := r.addColumnIfNotExists("users", "wallet_id", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := r.addColumnIfNotExists("wallets", "chat_id", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := r.addColumnIfNotExists("users", "active", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
+	if err := r.addColumnIfNotExists("users", "language", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := r.addColumnIfNotExists("users", "language_code", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	// День недели в нумерации time.Weekday: 1 — понедельник
+	if err := r.addColumnIfNotExists("users", "week_start", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
+	if err := r.addColumnIfNotExists("users", "month_start", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
+	// Последние сообщения бота раньше хранились только в таблице users
+	_, err := r.db.Exec(`
+        INSERT OR IGNORE INTO bot_messages (user_id, chat_id, msg_id)
+        SELECT user_id, chat_id, last_bot_msg_id FROM users WHERE last_bot_msg_id > 0 AND chat_id != 0
+    `)
+	if err != nil {
+		return err
+	}
+	if err := r.migrateWallets(); err != nil {
+		return err
+	}
+	if err := r.migrateActivity(); err != nil {
+		return err
+	}
+
+	return r.initSearchIndex()
+}
+
+func (r *SQLiteExpenseRepository) addColumnIfNotExists(table, column, definition string) error {
+	var count int
+	err := r.db.QueryRow(`SELECT count(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
 
-	return nil
+	_, err = r.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
 }
 
+// AddUser регистрирует пользователя и создает ему личный кошелек
 func (r *SQLiteExpenseRepository) AddUser(userID int, userName string) error {
-	date := time.Now()
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	_, err := r.db.Exec(`
+	if err = insertUser(tx, userID, userName); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertUser добавляет пользователя вместе с его личным кошельком
+func insertUser(tx *sql.Tx, userID int, userName string) error {
+	_, err := tx.Exec(`
         INSERT INTO users (user_id, user_name, registered) VALUES (?, ?, ?)
-    `, userID, userName, date.Format("2006-01-02 15:04:05"))
+    `, userID, userName, time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return err
+	}
+
+	_, err = createPersonalWallet(tx, userID)
 
 	return err
 }
@@ -88,7 +343,17 @@ func (r *SQLiteExpenseRepository) GetUserCount() (int, error) {
 	return count, nil
 }
 
+// SetLastBotMsgID запоминает последнее сообщение бота пользователю в чате.
+// Для личного чата (его ID положительный) также обновляется chat_id пользователя для уведомлений
 func (r *SQLiteExpenseRepository) SetLastBotMsgID(userID int, msgID int, chatID int64) error {
+	_, err := r.db.Exec(`
+        INSERT INTO bot_messages (user_id, chat_id, msg_id) VALUES (?, ?, ?)
+        ON CONFLICT (user_id, chat_id) DO UPDATE SET msg_id = excluded.msg_id
+    `, userID, chatID, msgID)
+	if err != nil || chatID < 0 {
+		return err
+	}
+
 	query := `
         UPDATE users 
         SET last_bot_msg_id = ?, chat_id = ?
@@ -96,28 +361,31 @@ func (r *SQLiteExpenseRepository) SetLastBotMsgID(userID int, msgID int, chatID
     `
 
 	// Выполнение запроса
-	_, err := r.db.Exec(query, msgID, chatID, userID)
+	_, err = r.db.Exec(query, msgID, chatID, userID)
 
 	return err
 }
 
-func (r *SQLiteExpenseRepository) GetLastBotMsgID(userID int) (int, int64, error) {
+// GetLastBotMsgID возвращает последнее сообщение бота пользователю в чате, 0 если его нет
+func (r *SQLiteExpenseRepository) GetLastBotMsgID(userID int, chatID int64) (int, error) {
 	query := `
-		SELECT last_bot_msg_id, chat_id 
-		FROM users 
-		WHERE user_id = ?
+		SELECT msg_id 
+		FROM bot_messages 
+		WHERE user_id = ? AND chat_id = ?
 	`
 
-	row := r.db.QueryRow(query, userID)
+	row := r.db.QueryRow(query, userID, chatID)
 
 	// Выполнение запроса
 	var msgID int
-	var chatID int64
-	if err := row.Scan(&msgID, &chatID); err != nil {
-		return 0, 0, err
+	if err := row.Scan(&msgID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
 	}
 
-	return msgID, chatID, nil
+	return msgID, nil
 }
 
 func (r *SQLiteExpenseRepository) IsUserRegistered(userID int) (bool, string, error) {
@@ -142,15 +410,109 @@ func (r *SQLiteExpenseRepository) IsUserRegistered(userID int) (bool, string, er
 	return isReg, registered, nil
 }
 
-// AddExpense добавляет новый расход в таблицу
-func (r *SQLiteExpenseRepository) AddExpense(expense Expense) error {
+// AddExpense добавляет новый расход в таблицу и возвращает его идентификатор
+func (r *SQLiteExpenseRepository) AddExpense(expense Expense) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	expenseID, err := insertExpense(tx, expense)
+	if err != nil {
+		return 0, err
+	}
+
+	return expenseID, tx.Commit()
+}
+
+// AddExpenses добавляет несколько расходов одной транзакцией
+func (r *SQLiteExpenseRepository) AddExpenses(expenses []Expense) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, expense := range expenses {
+		if _, err = insertExpense(tx, expense); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// insertExpense добавляет расход вместе с его тегами в рамках транзакции
+func insertExpense(tx *sql.Tx, expense Expense) (int64, error) {
 	date := expense.Date
 	dateMs := date.UnixMilli()
 
-	_, err := r.db.Exec(`
-        INSERT INTO expenses (user_id, date, date_ms, category, amount) VALUES (?, ?, ?, ?, ?)
-    `, expense.UserID, date.Format("2006-01-02 15:04:05"), dateMs, expense.Category, expense.Amount)
-	return err
+	res, err := tx.Exec(`
+        INSERT INTO expenses (user_id, wallet_id, date, date_ms, category, amount, note) VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, expense.UserID, expense.WalletID, date.Format("2006-01-02 15:04:05"), dateMs, expense.Category, expense.Amount, expense.Note)
+	if err != nil {
+		return 0, err
+	}
+
+	expenseID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err = insertExpenseTags(tx, expense.UserID, expenseID, expense.Tags); err != nil {
+		return 0, err
+	}
+
+	return expenseID, nil
+}
+
+// ErrReceiptAdded чек уже добавлен в кошелек, например другим участником между проверкой и добавлением
+var ErrReceiptAdded = errors.New("чек уже добавлен в кошелек")
+
+// IsReceiptAdded проверяет, добавлен ли в кошелек расход по этому чеку
+func (r *SQLiteExpenseRepository) IsReceiptAdded(walletID int64, fiscal FiscalData) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`
+        SELECT count(*) FROM receipts WHERE wallet_id = ? AND fn = ? AND fd = ? AND fp = ?
+    `, walletID, fiscal.FN, fiscal.FD, fiscal.FP).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// AddReceiptExpense добавляет расход по чеку вместе с его фискальными признаками;
+// если чек уже есть в кошельке, расход не добавляется и возвращается ErrReceiptAdded
+func (r *SQLiteExpenseRepository) AddReceiptExpense(expense Expense, fiscal FiscalData) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	expenseID, err := insertExpense(tx, expense)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`
+        INSERT INTO receipts (user_id, wallet_id, expense_id, fn, fd, fp) VALUES (?, ?, ?, ?, ?, ?)
+        ON CONFLICT (wallet_id, fn, fd, fp) DO NOTHING
+    `, expense.UserID, expense.WalletID, expenseID, fiscal.FN, fiscal.FD, fiscal.FP)
+	if err != nil {
+		return err
+	}
+	added, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if added == 0 {
+		return ErrReceiptAdded
+	}
+
+	return tx.Commit()
 }
 
 // Функция запроса расходов за определенный период из базы данных
@@ -180,14 +542,14 @@ func (r *SQLiteExpenseRepository) GetExpensesByPeriod(userID int, startDate, end
 	return expenses, nil
 }
 
-// Метод для получения расходов за период на основе Unix меток времени
-func (r *SQLiteExpenseRepository) GetExpensesByPeriodUnix(userID int, startUnixMilli, endUnixMilli int64) (map[string]float64, error) {
+// Метод для получения расходов кошелька за период на основе Unix меток времени
+func (r *SQLiteExpenseRepository) GetExpensesByPeriodUnix(walletID int64, startUnixMilli, endUnixMilli int64) (map[string]float64, error) {
 	rows, err := r.db.Query(`
         SELECT category, SUM(amount) as total
         FROM expenses
-        WHERE user_id = ? AND date_ms >= ? AND date_ms <= ?
+        WHERE wallet_id = ? AND date_ms >= ? AND date_ms <= ?
         GROUP BY category
-    `, userID, startUnixMilli, endUnixMilli)
+    `, walletID, startUnixMilli, endUnixMilli)
 	if err != nil {
 		return nil, err
 	}
@@ -207,30 +569,50 @@ func (r *SQLiteExpenseRepository) GetExpensesByPeriodUnix(userID int, startUnixM
 	return expenses, nil
 }
 
+// GetExpenseRowsUnix возвращает все расходы кошелька за период по возрастанию даты
+func (r *SQLiteExpenseRepository) GetExpenseRowsUnix(walletID int64, startUnixMilli, endUnixMilli int64) ([]Expense, error) {
+	rows, err := r.db.Query(`
+        SELECT e.id, e.user_id, e.wallet_id, e.date_ms, e.category, e.amount, e.note,
+            (SELECT group_concat(t.name, ' ') FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id)
+        FROM expenses e
+        WHERE e.wallet_id = ? AND e.date_ms >= ? AND e.date_ms <= ?
+        ORDER BY e.date_ms, e.id
+    `, walletID, startUnixMilli, endUnixMilli)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanExpenses(rows)
+}
+
+// AddUserCategory добавляет пользователю собственную категорию
 func (r *SQLiteExpenseRepository) AddUserCategory(userID int, category string) error {
 	_, err := r.db.Exec(`
-        INSERT INTO user_categories (user_id, category) VALUES (?, ?)
+        INSERT OR IGNORE INTO user_categories (user_id, category) VALUES (?, ?)
     `, userID, category)
 
 	return err
 }
 
+// GetUserCategories возвращает собственные категории пользователя в порядке добавления
 func (r *SQLiteExpenseRepository) GetUserCategories(userID int) ([]string, error) {
 	var categories []string
 
 	rows, err := r.db.Query(`
-        SELECT registered FROM users WHERE user_id = ?
+        SELECT category FROM user_categories WHERE user_id = ? ORDER BY id
     `, userID)
 	if err != nil {
 		return categories, err
 	}
 	defer rows.Close()
 
-	if rows.Next() {
-		if err = rows.Scan(&categories); err != nil {
+	for rows.Next() {
+		var category string
+		if err = rows.Scan(&category); err != nil {
 			return categories, err
 		}
+		categories = append(categories, category)
 	}
 
-	return categories, nil
+	return categories, rows.Err()
 }
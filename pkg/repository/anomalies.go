@@ -0,0 +1,42 @@
+package repository
+
+// GetCategoryAmounts возвращает суммы расходов пользователя в категории за период, кроме расхода excludeID
+func (r *SQLiteExpenseRepository) GetCategoryAmounts(userID int, category string, excludeID int64, startUnixMilli, endUnixMilli int64) ([]float64, error) {
+	rows, err := r.db.Query(`
+        SELECT amount FROM expenses
+        WHERE user_id = ? AND category = ? AND id != ? AND date_ms >= ? AND date_ms <= ?
+    `, userID, category, excludeID, startUnixMilli, endUnixMilli)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var amounts []float64
+	for rows.Next() {
+		var amount float64
+		if err = rows.Scan(&amount); err != nil {
+			return nil, err
+		}
+		amounts = append(amounts, amount)
+	}
+
+	return amounts, rows.Err()
+}
+
+// GetSimilarExpenses возвращает другие расходы пользователя в том же кошельке с той же категорией и суммой
+// за период, новые сверху
+func (r *SQLiteExpenseRepository) GetSimilarExpenses(expense Expense, startUnixMilli, endUnixMilli int64) ([]Expense, error) {
+	rows, err := r.db.Query(`
+        SELECT e.id, e.user_id, e.wallet_id, e.date_ms, e.category, e.amount, e.note,
+            (SELECT group_concat(t.name, ' ') FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id)
+        FROM expenses e
+        WHERE e.user_id = ? AND e.wallet_id = ? AND e.category = ? AND e.amount = ? AND e.id != ?
+            AND e.date_ms >= ? AND e.date_ms <= ?
+        ORDER BY e.date_ms DESC, e.id DESC
+    `, expense.UserID, expense.WalletID, expense.Category, expense.Amount, expense.ID, startUnixMilli, endUnixMilli)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanExpenses(rows)
+}
@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Состояния рассылки
+const (
+	BroadcastDraft    = "draft"
+	BroadcastSending  = "sending"
+	BroadcastDone     = "done"
+	BroadcastCanceled = "canceled"
+)
+
+// Состояния доставки рассылки пользователю
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	DeliveryBlocked = "blocked"
+)
+
+var ErrBroadcastNotFound = errors.New("рассылка не найдена или уже запущена")
+
+// Broadcast рассылка сообщения всем пользователям
+type Broadcast struct {
+	ID        int64
+	Text      string
+	CreatedBy int
+	Status    string
+}
+
+// Delivery получатель рассылки
+type Delivery struct {
+	UserID int
+	ChatID int64
+}
+
+// BroadcastStats количество доставок рассылки по состояниям
+type BroadcastStats struct {
+	Pending int
+	Sent    int
+	Failed  int
+	Blocked int
+}
+
+// CreateBroadcast сохраняет черновик рассылки до подтверждения
+func (r *SQLiteExpenseRepository) CreateBroadcast(text string, createdBy int) (int64, error) {
+	res, err := r.db.Exec(`
+        INSERT INTO broadcasts (text, created_by, status, created) VALUES (?, ?, ?, ?)
+    `, text, createdBy, BroadcastDraft, time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+// GetBroadcast возвращает рассылку по ID
+func (r *SQLiteExpenseRepository) GetBroadcast(broadcastID int64) (Broadcast, error) {
+	var b Broadcast
+	err := r.db.QueryRow(`
+        SELECT id, text, created_by, status FROM broadcasts WHERE id = ?
+    `, broadcastID).Scan(&b.ID, &b.Text, &b.CreatedBy, &b.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Broadcast{}, ErrBroadcastNotFound
+	}
+
+	return b, err
+}
+
+// GetBroadcastsByStatus возвращает рассылки в состоянии status
+func (r *SQLiteExpenseRepository) GetBroadcastsByStatus(status string) ([]Broadcast, error) {
+	rows, err := r.db.Query(`
+        SELECT id, text, created_by, status FROM broadcasts WHERE status = ? ORDER BY id
+    `, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var broadcasts []Broadcast
+	for rows.Next() {
+		var b Broadcast
+		if err = rows.Scan(&b.ID, &b.Text, &b.CreatedBy, &b.Status); err != nil {
+			return nil, err
+		}
+		broadcasts = append(broadcasts, b)
+	}
+
+	return broadcasts, rows.Err()
+}
+
+// GetActiveUserTotal возвращает количество пользователей, которые не заблокировали бота
+// и не заблокированы в нем сами
+func (r *SQLiteExpenseRepository) GetActiveUserTotal() (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+        SELECT count(*) FROM users
+        WHERE active = 1 AND user_id NOT IN (SELECT user_id FROM user_roles WHERE role = ?)
+    `, UserRoleBanned).Scan(&count)
+
+	return count, err
+}
+
+// StartBroadcast переводит черновик в отправку и ставит в очередь всех активных незаблокированных пользователей.
+// Для пользователей без сохраненного личного чата используется их ID, он совпадает с ID личного чата
+func (r *SQLiteExpenseRepository) StartBroadcast(broadcastID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+        UPDATE broadcasts SET status = ? WHERE id = ? AND status = ?
+    `, BroadcastSending, broadcastID, BroadcastDraft)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrBroadcastNotFound
+	}
+
+	_, err = tx.Exec(`
+        INSERT OR IGNORE INTO broadcast_deliveries (broadcast_id, user_id, chat_id, status)
+        SELECT ?, user_id, CASE WHEN chat_id > 0 THEN chat_id ELSE user_id END, ?
+        FROM users
+        WHERE active = 1 AND user_id NOT IN (SELECT user_id FROM user_roles WHERE role = ?)
+    `, broadcastID, DeliveryPending, UserRoleBanned)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CancelBroadcast отменяет черновик или останавливает идущую рассылку
+func (r *SQLiteExpenseRepository) CancelBroadcast(broadcastID int64) error {
+	res, err := r.db.Exec(`
+        UPDATE broadcasts SET status = ? WHERE id = ? AND status IN (?, ?)
+    `, BroadcastCanceled, broadcastID, BroadcastDraft, BroadcastSending)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrBroadcastNotFound
+	}
+
+	return nil
+}
+
+// FinishBroadcast отмечает рассылку завершенной, если ее не отменили
+func (r *SQLiteExpenseRepository) FinishBroadcast(broadcastID int64) error {
+	_, err := r.db.Exec(`
+        UPDATE broadcasts SET status = ? WHERE id = ? AND status = ?
+    `, BroadcastDone, broadcastID, BroadcastSending)
+	return err
+}
+
+// GetPendingDeliveries возвращает до limit получателей, которым рассылка еще не отправлена
+func (r *SQLiteExpenseRepository) GetPendingDeliveries(broadcastID int64, limit int) ([]Delivery, error) {
+	rows, err := r.db.Query(`
+        SELECT user_id, chat_id FROM broadcast_deliveries
+        WHERE broadcast_id = ? AND status = ?
+        ORDER BY user_id
+        LIMIT ?
+    `, broadcastID, DeliveryPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		var d Delivery
+		if err = rows.Scan(&d.UserID, &d.ChatID); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// SetDeliveryStatus записывает результат отправки рассылки пользователю.
+// Пользователь, заблокировавший бота, помечается неактивным и не попадает в следующие рассылки
+func (r *SQLiteExpenseRepository) SetDeliveryStatus(broadcastID int64, userID int, status string, errText string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+        UPDATE broadcast_deliveries SET status = ?, error = ?, sent_ms = ?
+        WHERE broadcast_id = ? AND user_id = ?
+    `, status, errText, time.Now().UnixMilli(), broadcastID, userID)
+	if err != nil {
+		return err
+	}
+
+	if status == DeliveryBlocked {
+		if _, err = tx.Exec(`UPDATE users SET active = 0 WHERE user_id = ?`, userID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetBroadcastStats возвращает количество доставок рассылки по состояниям
+func (r *SQLiteExpenseRepository) GetBroadcastStats(broadcastID int64) (BroadcastStats, error) {
+	rows, err := r.db.Query(`
+        SELECT status, count(*) FROM broadcast_deliveries WHERE broadcast_id = ? GROUP BY status
+    `, broadcastID)
+	if err != nil {
+		return BroadcastStats{}, err
+	}
+	defer rows.Close()
+
+	var stats BroadcastStats
+	for rows.Next() {
+		var status string
+		var count int
+		if err = rows.Scan(&status, &count); err != nil {
+			return BroadcastStats{}, err
+		}
+
+		switch status {
+		case DeliveryPending:
+			stats.Pending = count
+		case DeliverySent:
+			stats.Sent = count
+		case DeliveryFailed:
+			stats.Failed = count
+		case DeliveryBlocked:
+			stats.Blocked = count
+		}
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// GetUserLanguage возвращает язык, выбранный пользователем, и язык его интерфейса Telegram.
+// Пустая строка означает, что язык не выбран или не известен
+func (r *SQLiteExpenseRepository) GetUserLanguage(userID int) (string, string, error) {
+	var language, languageCode string
+	err := r.db.QueryRow(`
+        SELECT language, language_code FROM users WHERE user_id = ?
+    `, userID).Scan(&language, &languageCode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", nil
+	}
+
+	return language, languageCode, err
+}
+
+// SetUserLanguage сохраняет язык, выбранный пользователем; пустая строка возвращает язык интерфейса Telegram
+func (r *SQLiteExpenseRepository) SetUserLanguage(userID int, language string) error {
+	_, err := r.db.Exec(`UPDATE users SET language = ? WHERE user_id = ?`, language, userID)
+	return err
+}
+
+// SetUserLanguageCode сохраняет язык интерфейса Telegram пользователя
+func (r *SQLiteExpenseRepository) SetUserLanguageCode(userID int, languageCode string) error {
+	_, err := r.db.Exec(`UPDATE users SET language_code = ? WHERE user_id = ?`, languageCode, userID)
+	return err
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// GetUserPeriodStart возвращает, с какого дня у пользователя начинается неделя (0 — воскресенье, 1 — понедельник, ...)
+// и с какого числа — месяц. Для неизвестного пользователя возвращаются календарные периоды с понедельника
+func (r *SQLiteExpenseRepository) GetUserPeriodStart(userID int) (int, int, error) {
+	var weekStart, monthStart int
+	err := r.db.QueryRow(`
+        SELECT week_start, month_start FROM users WHERE user_id = ?
+    `, userID).Scan(&weekStart, &monthStart)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 1, 1, nil
+	}
+
+	return weekStart, monthStart, err
+}
+
+// SetUserWeekStart сохраняет день, с которого у пользователя начинается неделя
+func (r *SQLiteExpenseRepository) SetUserWeekStart(userID int, weekday int) error {
+	_, err := r.db.Exec(`UPDATE users SET week_start = ? WHERE user_id = ?`, weekday, userID)
+	return err
+}
+
+// SetUserMonthStart сохраняет число, с которого у пользователя начинается месяц
+func (r *SQLiteExpenseRepository) SetUserMonthStart(userID int, day int) error {
+	_, err := r.db.Exec(`UPDATE users SET month_start = ? WHERE user_id = ?`, day, userID)
+	return err
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Интервалы регулярных расходов
+const (
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+	IntervalYear  = "year"
+)
+
+// Состояния найденного регулярного платежа
+const (
+	DetectionProposed  = "proposed"
+	DetectionAccepted  = "accepted"
+	DetectionDismissed = "dismissed"
+)
+
+var ErrRecurringNotFound = errors.New("регулярный расход не найден или уже обработан")
+
+// RecurringRule правило, по которому расход записывается автоматически
+type RecurringRule struct {
+	ID       int64
+	UserID   int
+	WalletID int64
+	Category string
+	Amount   float64
+	Note     string
+	Interval string
+	// День месяца платежа: после коротких месяцев платеж возвращается на него
+	Day  int
+	Next time.Time
+}
+
+// RecurringDetection регулярный платеж, найденный в истории расходов
+type RecurringDetection struct {
+	ID       int64
+	UserID   int
+	WalletID int64
+	// Ключ группы расходов: платеж с тем же ключом больше не предлагается
+	Key      string
+	Category string
+	Amount   float64
+	Note     string
+	Interval string
+	Day      int
+	Next     time.Time
+	// Сколько раз платеж встретился в истории
+	Count  int
+	Status string
+}
+
+// GetRepeatedExpensesSince возвращает расходы всех пользователей начиная с startUnixMilli, сумма которых
+// повторилась в той же категории кошелька хотя бы minCount раз. Разовые расходы отсеиваются в базе,
+// чтобы не загружать всю историю; расходы сгруппированы по пользователю и кошельку и упорядочены по дате
+func (r *SQLiteExpenseRepository) GetRepeatedExpensesSince(startUnixMilli int64, minCount int) ([]Expense, error) {
+	rows, err := r.db.Query(`
+        SELECT e.id, e.user_id, e.wallet_id, e.date_ms, e.category, e.amount, e.note,
+            (SELECT group_concat(t.name, ' ') FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id)
+        FROM expenses e
+        JOIN (
+            SELECT user_id, wallet_id, category, round(amount, 2) AS amount
+            FROM expenses
+            WHERE date_ms >= ?
+            GROUP BY user_id, wallet_id, category, round(amount, 2)
+            HAVING count(*) >= ?
+        ) repeated ON repeated.user_id = e.user_id AND repeated.wallet_id = e.wallet_id
+            AND repeated.category = e.category AND repeated.amount = round(e.amount, 2)
+        WHERE e.date_ms >= ?
+        ORDER BY e.user_id, e.wallet_id, e.date_ms, e.id
+    `, startUnixMilli, minCount, startUnixMilli)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanExpenses(rows)
+}
+
+// AddRecurringDetection сохраняет найденный платеж для предложения пользователю. Если платеж с таким
+// ключом уже предлагался, ничего не меняется и возвращается false
+func (r *SQLiteExpenseRepository) AddRecurringDetection(d RecurringDetection) (int64, bool, error) {
+	res, err := r.db.Exec(`
+        INSERT OR IGNORE INTO recurring_detections
+            (user_id, wallet_id, key, category, amount, note, interval, day, next_ms, count, status)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, d.UserID, d.WalletID, d.Key, d.Category, d.Amount, d.Note, d.Interval, d.Day, d.Next.UnixMilli(), d.Count, DetectionProposed)
+	if err != nil {
+		return 0, false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return 0, false, err
+	}
+
+	id, err := res.LastInsertId()
+	return id, err == nil, err
+}
+
+// AcceptRecurringDetection превращает предложенный платеж пользователя в правило с первым платежом next
+func (r *SQLiteExpenseRepository) AcceptRecurringDetection(userID int, detectionID int64, next time.Time) (RecurringRule, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return RecurringRule{}, err
+	}
+	defer tx.Rollback()
+
+	rule := RecurringRule{UserID: userID, Next: next}
+	err = tx.QueryRow(`
+        SELECT wallet_id, category, amount, note, interval, day FROM recurring_detections
+        WHERE id = ? AND user_id = ? AND status = ?
+    `, detectionID, userID, DetectionProposed).Scan(&rule.WalletID, &rule.Category, &rule.Amount, &rule.Note, &rule.Interval, &rule.Day)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RecurringRule{}, ErrRecurringNotFound
+	}
+	if err != nil {
+		return RecurringRule{}, err
+	}
+
+	res, err := tx.Exec(`
+        INSERT INTO recurring_rules (user_id, wallet_id, category, amount, note, interval, day, next_ms)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+    `, rule.UserID, rule.WalletID, rule.Category, rule.Amount, rule.Note, rule.Interval, rule.Day, rule.Next.UnixMilli())
+	if err != nil {
+		return RecurringRule{}, err
+	}
+	if rule.ID, err = res.LastInsertId(); err != nil {
+		return RecurringRule{}, err
+	}
+
+	_, err = tx.Exec(`UPDATE recurring_detections SET status = ? WHERE id = ?`, DetectionAccepted, detectionID)
+	if err != nil {
+		return RecurringRule{}, err
+	}
+
+	return rule, tx.Commit()
+}
+
+// GetRecurringDetection возвращает найденный платеж пользователя
+func (r *SQLiteExpenseRepository) GetRecurringDetection(userID int, detectionID int64) (RecurringDetection, error) {
+	d := RecurringDetection{ID: detectionID, UserID: userID}
+	var nextMs int64
+	err := r.db.QueryRow(`
+        SELECT wallet_id, key, category, amount, note, interval, day, next_ms, count, status
+        FROM recurring_detections WHERE id = ? AND user_id = ?
+    `, detectionID, userID).Scan(&d.WalletID, &d.Key, &d.Category, &d.Amount, &d.Note, &d.Interval, &d.Day, &nextMs, &d.Count, &d.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RecurringDetection{}, ErrRecurringNotFound
+	}
+	d.Next = time.UnixMilli(nextMs)
+
+	return d, err
+}
+
+// DismissRecurringDetection отклоняет предложенный платеж, больше он не предлагается
+func (r *SQLiteExpenseRepository) DismissRecurringDetection(userID int, detectionID int64) error {
+	res, err := r.db.Exec(`
+        UPDATE recurring_detections SET status = ? WHERE id = ? AND user_id = ? AND status = ?
+    `, DetectionDismissed, detectionID, userID, DetectionProposed)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRecurringNotFound
+	}
+
+	return nil
+}
+
+// GetRecurringRules возвращает правила пользователя в порядке ближайших платежей
+func (r *SQLiteExpenseRepository) GetRecurringRules(userID int) ([]RecurringRule, error) {
+	return r.queryRecurringRules(`WHERE user_id = ? ORDER BY next_ms, id`, userID)
+}
+
+// GetDueRecurringRules возвращает правила всех пользователей, платеж по которым наступил к now
+func (r *SQLiteExpenseRepository) GetDueRecurringRules(now time.Time) ([]RecurringRule, error) {
+	return r.queryRecurringRules(`WHERE next_ms <= ? ORDER BY next_ms, id`, now.UnixMilli())
+}
+
+func (r *SQLiteExpenseRepository) queryRecurringRules(where string, args ...any) ([]RecurringRule, error) {
+	rows, err := r.db.Query(`
+        SELECT id, user_id, wallet_id, category, amount, note, interval, day, next_ms FROM recurring_rules
+    `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []RecurringRule
+	for rows.Next() {
+		var rule RecurringRule
+		var nextMs int64
+		err = rows.Scan(&rule.ID, &rule.UserID, &rule.WalletID, &rule.Category, &rule.Amount, &rule.Note, &rule.Interval, &rule.Day, &nextMs)
+		if err != nil {
+			return nil, err
+		}
+		rule.Next = time.UnixMilli(nextMs)
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// DeleteRecurringRule удаляет правило пользователя
+func (r *SQLiteExpenseRepository) DeleteRecurringRule(userID int, ruleID int64) error {
+	res, err := r.db.Exec(`DELETE FROM recurring_rules WHERE id = ? AND user_id = ?`, ruleID, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRecurringNotFound
+	}
+
+	return nil
+}
+
+// AddRecurringExpense записывает расход по правилу и переносит следующий платеж на next одной транзакцией
+func (r *SQLiteExpenseRepository) AddRecurringExpense(ruleID int64, expense Expense, next time.Time) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	expenseID, err := insertExpense(tx, expense)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`UPDATE recurring_rules SET next_ms = ? WHERE id = ?`, next.UnixMilli(), ruleID)
+	if err != nil {
+		return 0, err
+	}
+
+	return expenseID, tx.Commit()
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+// CreateRegistrationInvite сохраняет код приглашения, по которому могут зарегистрироваться uses пользователей до expires
+func (r *SQLiteExpenseRepository) CreateRegistrationInvite(code string, createdBy int, uses int, expires time.Time) error {
+	_, err := r.db.Exec(`
+        INSERT INTO registration_invites (code, created_by, uses_left, expires_ms, created) VALUES (?, ?, ?, ?, ?)
+    `, code, createdBy, uses, expires.UnixMilli(), time.Now().Format("2006-01-02 15:04:05"))
+
+	return err
+}
+
+// AddInvitedUser регистрирует пользователя по коду приглашения. Регистрация по приглашению расходуется
+// в той же транзакции, поэтому при ошибке регистрации место в приглашении не теряется.
+// Для неизвестного, просроченного или исчерпанного кода возвращается ErrInviteNotFound
+func (r *SQLiteExpenseRepository) AddInvitedUser(userID int, userName string, code string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = useRegistrationInvite(tx, code); err != nil {
+		return err
+	}
+	if err = insertUser(tx, userID, userName); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// useRegistrationInvite расходует одну регистрацию по коду приглашения
+func useRegistrationInvite(tx *sql.Tx, code string) error {
+	res, err := tx.Exec(`
+        UPDATE registration_invites SET uses_left = uses_left - 1
+        WHERE code = ? AND uses_left > 0 AND expires_ms > ?
+    `, code, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInviteNotFound
+	}
+
+	// Исчерпанные и просроченные приглашения больше не нужны
+	_, err = tx.Exec(`
+        DELETE FROM registration_invites WHERE uses_left <= 0 OR expires_ms <= ?
+    `, time.Now().UnixMilli())
+
+	return err
+}
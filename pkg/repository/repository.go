@@ -6,10 +6,35 @@ import (
 
 // Expense структура для хранения данных о расходах
 type Expense struct {
+	ID       int64
 	UserID   int
+	WalletID int64
 	Date     time.Time
 	Category string
 	Amount   float64
+	Note     string
+	Tags     []string
+}
+
+// FiscalData фискальные признаки чека, по которым отсекаются дубликаты
+type FiscalData struct {
+	FN string
+	FD string
+	FP string
+}
+
+// ExpenseFilter условия поиска расходов, нулевые значения не ограничивают выборку
+type ExpenseFilter struct {
+	WalletID       int64
+	MinAmount      float64
+	MaxAmount      float64
+	StartUnixMilli int64
+	EndUnixMilli   int64
+	Categories     []string
+	Tags           []string
+	Text           string
+	Limit          int
+	Offset         int
 }
 
 // ExpenseRepository интерфейс для работы с расходами
@@ -17,10 +42,76 @@ type ExpenseRepository interface {
 	InitSchema() error
 	AddUser(userID int, userName string) error
 	GetUserCount() (int, error)
+	TouchUserActivity(userID int, day time.Time) error
+	GetActiveUserCount(since time.Time) (int, error)
+	GetRegistrationsByDay(since time.Time) ([]DayCount, error)
+	GetExpenseCountsByDay(since time.Time) ([]DayCount, error)
+	GetTopCategories(since time.Time, limit int) ([]CategoryCount, error)
+	GetRetentionCohorts(since time.Time, weeks int) ([]Cohort, error)
+	GetActiveUserTotal() (int, error)
+	CreateBroadcast(text string, createdBy int) (int64, error)
+	GetBroadcast(broadcastID int64) (Broadcast, error)
+	GetBroadcastsByStatus(status string) ([]Broadcast, error)
+	StartBroadcast(broadcastID int64) error
+	CancelBroadcast(broadcastID int64) error
+	FinishBroadcast(broadcastID int64) error
+	GetPendingDeliveries(broadcastID int64, limit int) ([]Delivery, error)
+	SetDeliveryStatus(broadcastID int64, userID int, status string, errText string) error
+	GetBroadcastStats(broadcastID int64) (BroadcastStats, error)
+	GetUserRole(userID int) (string, error)
+	SetUserRole(userID int, role string, grantedBy int) error
+	GetUserRoles() ([]UserRole, error)
+	AddAuditEntry(entry AuditEntry) error
+	GetAuditLog(limit int) ([]AuditEntry, error)
+	CreateRegistrationInvite(code string, createdBy int, uses int, expires time.Time) error
+	AddInvitedUser(userID int, userName string, code string) error
+	GetUserLanguage(userID int) (string, string, error)
+	SetUserLanguage(userID int, language string) error
+	SetUserLanguageCode(userID int, languageCode string) error
+	GetUserPeriodStart(userID int) (int, int, error)
+	SetUserWeekStart(userID int, weekday int) error
+	SetUserMonthStart(userID int, day int) error
 	SetLastBotMsgID(userID int, msgID int, chatID int64) error
-	GetLastBotMsgID(userID int) (int, int64, error)
+	GetLastBotMsgID(userID int, chatID int64) (int, error)
 	IsUserRegistered(userID int) (bool, string, error)
-	AddExpense(expense Expense) error
+	AddUserCategory(userID int, category string) error
+	GetUserCategories(userID int) ([]string, error)
+	AddExpense(expense Expense) (int64, error)
+	AddExpenses(expenses []Expense) error
+	IsReceiptAdded(walletID int64, fiscal FiscalData) (bool, error)
+	AddReceiptExpense(expense Expense, fiscal FiscalData) error
+	GetTagTotalsUnix(walletID int64, startUnixMilli, endUnixMilli int64) (map[string]float64, error)
+	GetExpensesByTagUnix(walletID int64, tag string, startUnixMilli, endUnixMilli int64) (map[string]float64, error)
+	SearchExpenses(filter ExpenseFilter) ([]Expense, int, error)
+	GetExpense(walletID int64, expenseID int64) (Expense, error)
+	UpdateExpenseAmount(walletID int64, expenseID int64, amount float64) error
+	DeleteExpense(walletID int64, expenseID int64) error
 	GetExpensesByPeriod(userID int, startDate, endDate time.Time) (map[string]float64, error)
-	GetExpensesByPeriodUnix(userID int, tartUnixMilli, endUnixMilli int64) (map[string]float64, error)
+	GetExpensesByPeriodUnix(walletID int64, tartUnixMilli, endUnixMilli int64) (map[string]float64, error)
+	GetMemberTotalsUnix(walletID int64, startUnixMilli, endUnixMilli int64) (map[string]float64, error)
+	GetExpenseRowsUnix(walletID int64, startUnixMilli, endUnixMilli int64) ([]Expense, error)
+	GetCategoryAmounts(userID int, category string, excludeID int64, startUnixMilli, endUnixMilli int64) ([]float64, error)
+	GetSimilarExpenses(expense Expense, startUnixMilli, endUnixMilli int64) ([]Expense, error)
+	GetRepeatedExpensesSince(startUnixMilli int64, minCount int) ([]Expense, error)
+	AddRecurringDetection(d RecurringDetection) (int64, bool, error)
+	GetRecurringDetection(userID int, detectionID int64) (RecurringDetection, error)
+	AcceptRecurringDetection(userID int, detectionID int64, next time.Time) (RecurringRule, error)
+	DismissRecurringDetection(userID int, detectionID int64) error
+	GetRecurringRules(userID int) ([]RecurringRule, error)
+	GetDueRecurringRules(now time.Time) ([]RecurringRule, error)
+	DeleteRecurringRule(userID int, ruleID int64) error
+	AddRecurringExpense(ruleID int64, expense Expense, next time.Time) (int64, error)
+	GetCurrentWallet(userID int) (Wallet, error)
+	GetUserWallets(userID int) ([]Wallet, error)
+	SetCurrentWallet(userID int, walletID int64) error
+	CreateWallet(ownerID int, name string) (int64, error)
+	CreateWalletInvite(walletID int64, createdBy int, role string, token string, expires time.Time) error
+	AcceptWalletInvite(userID int, token string) (Wallet, error)
+	CreateChatWallet(chatID int64, ownerID int, name string) error
+	GetChatWallet(chatID int64, userID int) (Wallet, error)
+	RenameWallet(walletID int64, name string) error
+	GetUserByName(userName string) (User, error)
+	AddSplitExpense(expense Expense, debts []Debt) error
+	AddSettlement(fromID, toID int, amount float64) error
+	GetBalances(userID int) ([]Balance, error)
 }
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Роли пользователей в боте, от высшей к низшей.
+// Роль user есть у всех, для кого не назначено другой
+const (
+	UserRoleOwner   = "owner"
+	UserRoleAdmin   = "admin"
+	UserRoleSupport = "support"
+	UserRoleUser    = "user"
+	UserRoleBanned  = "banned"
+)
+
+var userRoleLevels = map[string]int{
+	UserRoleBanned:  0,
+	UserRoleUser:    1,
+	UserRoleSupport: 2,
+	UserRoleAdmin:   3,
+	UserRoleOwner:   4,
+}
+
+// UserRoleLevel возвращает уровень роли для сравнения прав; неизвестная роль приравнивается к user
+func UserRoleLevel(role string) int {
+	level, ok := userRoleLevels[role]
+	if !ok {
+		return userRoleLevels[UserRoleUser]
+	}
+
+	return level
+}
+
+// IsUserRole проверяет, что role — одна из ролей пользователей бота
+func IsUserRole(role string) bool {
+	_, ok := userRoleLevels[role]
+	return ok
+}
+
+// UserRole назначенная пользователю роль
+type UserRole struct {
+	UserID int
+	Name   string
+	Role   string
+}
+
+// AuditEntry запись журнала действий с повышенными правами
+type AuditEntry struct {
+	ActorID  int
+	Action   string
+	TargetID int
+	Details  string
+	Date     time.Time
+}
+
+// GetUserRole возвращает роль пользователя, по умолчанию user
+func (r *SQLiteExpenseRepository) GetUserRole(userID int) (string, error) {
+	var role string
+	err := r.db.QueryRow(`SELECT role FROM user_roles WHERE user_id = ?`, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return UserRoleUser, nil
+	}
+
+	return role, err
+}
+
+// SetUserRole назначает пользователю роль; роль user удаляет назначенную ранее
+func (r *SQLiteExpenseRepository) SetUserRole(userID int, role string, grantedBy int) error {
+	if role == UserRoleUser {
+		_, err := r.db.Exec(`DELETE FROM user_roles WHERE user_id = ?`, userID)
+		return err
+	}
+
+	_, err := r.db.Exec(`
+        INSERT INTO user_roles (user_id, role, granted_by, granted) VALUES (?, ?, ?, ?)
+        ON CONFLICT (user_id) DO UPDATE SET role = excluded.role, granted_by = excluded.granted_by, granted = excluded.granted
+    `, userID, role, grantedBy, time.Now().Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// GetUserRoles возвращает пользователей с назначенными ролями, начиная со старших
+func (r *SQLiteExpenseRepository) GetUserRoles() ([]UserRole, error) {
+	rows, err := r.db.Query(`
+        SELECT ur.user_id, COALESCE(u.user_name, ''), ur.role
+        FROM user_roles ur
+        LEFT JOIN users u ON u.user_id = ur.user_id
+        ORDER BY CASE ur.role WHEN ? THEN 0 WHEN ? THEN 1 WHEN ? THEN 2 ELSE 3 END, ur.user_id
+    `, UserRoleOwner, UserRoleAdmin, UserRoleSupport)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []UserRole
+	for rows.Next() {
+		var role UserRole
+		if err = rows.Scan(&role.UserID, &role.Name, &role.Role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// AddAuditEntry записывает действие с повышенными правами в журнал
+func (r *SQLiteExpenseRepository) AddAuditEntry(entry AuditEntry) error {
+	if entry.Date.IsZero() {
+		entry.Date = time.Now()
+	}
+
+	_, err := r.db.Exec(`
+        INSERT INTO audit_log (actor_id, action, target_id, details, date_ms) VALUES (?, ?, ?, ?, ?)
+    `, entry.ActorID, entry.Action, entry.TargetID, entry.Details, entry.Date.UnixMilli())
+	return err
+}
+
+// GetAuditLog возвращает последние limit записей журнала, от новых к старым
+func (r *SQLiteExpenseRepository) GetAuditLog(limit int) ([]AuditEntry, error) {
+	rows, err := r.db.Query(`
+        SELECT actor_id, action, target_id, details, date_ms FROM audit_log ORDER BY id DESC LIMIT ?
+    `, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		var dateMs int64
+		if err = rows.Scan(&entry.ActorID, &entry.Action, &entry.TargetID, &entry.Details, &dateMs); err != nil {
+			return nil, err
+		}
+		entry.Date = time.UnixMilli(dateMs)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// initSearchIndex создает полнотекстовый индекс FTS5 по заметкам расходов
+func (r *SQLiteExpenseRepository) initSearchIndex() error {
+	var count int
+	err := r.db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name = 'expenses_fts'`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	query := `
+    CREATE VIRTUAL TABLE expenses_fts USING fts5(note, content='expenses', content_rowid='id');
+    CREATE TRIGGER IF NOT EXISTS expenses_fts_insert AFTER INSERT ON expenses BEGIN
+        INSERT INTO expenses_fts(rowid, note) VALUES (new.id, new.note);
+    END;
+    CREATE TRIGGER IF NOT EXISTS expenses_fts_delete AFTER DELETE ON expenses BEGIN
+        INSERT INTO expenses_fts(expenses_fts, rowid, note) VALUES ('delete', old.id, old.note);
+    END;
+    CREATE TRIGGER IF NOT EXISTS expenses_fts_update AFTER UPDATE OF note ON expenses BEGIN
+        INSERT INTO expenses_fts(expenses_fts, rowid, note) VALUES ('delete', old.id, old.note);
+        INSERT INTO expenses_fts(rowid, note) VALUES (new.id, new.note);
+    END;
+    INSERT INTO expenses_fts(expenses_fts) VALUES ('rebuild');`
+	_, err = r.db.Exec(query)
+	return err
+}
+
+// SearchExpenses возвращает страницу найденных расходов (новые сверху) и общее количество найденных
+func (r *SQLiteExpenseRepository) SearchExpenses(filter ExpenseFilter) ([]Expense, int, error) {
+	where, args := filterConditions(filter)
+
+	var total int
+	err := r.db.QueryRow(`SELECT count(*) FROM expenses e WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+        SELECT e.id, e.user_id, e.wallet_id, e.date_ms, e.category, e.amount, e.note,
+            (SELECT group_concat(t.name, ' ') FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id)
+        FROM expenses e
+        WHERE ` + where + `
+        ORDER BY e.date_ms DESC, e.id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, filter.Limit, filter.Offset)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	expenses, err := scanExpenses(rows)
+	return expenses, total, err
+}
+
+// GetExpense возвращает расход кошелька по идентификатору
+func (r *SQLiteExpenseRepository) GetExpense(walletID int64, expenseID int64) (Expense, error) {
+	rows, err := r.db.Query(`
+        SELECT e.id, e.user_id, e.wallet_id, e.date_ms, e.category, e.amount, e.note,
+            (SELECT group_concat(t.name, ' ') FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id)
+        FROM expenses e
+        WHERE e.wallet_id = ? AND e.id = ?
+    `, walletID, expenseID)
+	if err != nil {
+		return Expense{}, err
+	}
+
+	expenses, err := scanExpenses(rows)
+	if err != nil {
+		return Expense{}, err
+	}
+	if len(expenses) == 0 {
+		return Expense{}, sql.ErrNoRows
+	}
+
+	return expenses[0], nil
+}
+
+// UpdateExpenseAmount изменяет сумму расхода
+func (r *SQLiteExpenseRepository) UpdateExpenseAmount(walletID int64, expenseID int64, amount float64) error {
+	_, err := r.db.Exec(`
+        UPDATE expenses SET amount = ? WHERE wallet_id = ? AND id = ?
+    `, amount, walletID, expenseID)
+
+	return err
+}
+
+// DeleteExpense удаляет расход вместе с его тегами, чеком и разделением
+func (r *SQLiteExpenseRepository) DeleteExpense(walletID int64, expenseID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM expenses WHERE wallet_id = ? AND id = ?`, walletID, expenseID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err = tx.Exec(`DELETE FROM expense_tags WHERE expense_id = ?`, expenseID); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM receipts WHERE expense_id = ?`, expenseID); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM splits WHERE expense_id = ?`, expenseID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func filterConditions(filter ExpenseFilter) (string, []any) {
+	conditions := []string{"e.wallet_id = ?"}
+	args := []any{filter.WalletID}
+
+	if filter.MinAmount > 0 {
+		conditions = append(conditions, "e.amount >= ?")
+		args = append(args, filter.MinAmount)
+	}
+	if filter.MaxAmount > 0 {
+		conditions = append(conditions, "e.amount <= ?")
+		args = append(args, filter.MaxAmount)
+	}
+	if filter.StartUnixMilli > 0 {
+		conditions = append(conditions, "e.date_ms >= ?")
+		args = append(args, filter.StartUnixMilli)
+	}
+	if filter.EndUnixMilli > 0 {
+		conditions = append(conditions, "e.date_ms <= ?")
+		args = append(args, filter.EndUnixMilli)
+	}
+	if len(filter.Categories) > 0 {
+		conditions = append(conditions, "e.category IN ("+placeholders(len(filter.Categories))+")")
+		for _, category := range filter.Categories {
+			args = append(args, category)
+		}
+	}
+	for _, tag := range filter.Tags {
+		conditions = append(conditions, `e.id IN (
+            SELECT et.expense_id FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+            WHERE t.user_id = e.user_id AND t.name = ?)`)
+		args = append(args, tag)
+	}
+	if match := ftsQuery(filter.Text); match != "" {
+		conditions = append(conditions, "e.id IN (SELECT rowid FROM expenses_fts WHERE expenses_fts MATCH ?)")
+		args = append(args, match)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// ftsQuery превращает текст в запрос FTS5: каждое слово ищется по префиксу
+func ftsQuery(text string) string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		word = strings.ReplaceAll(word, `"`, "")
+		if word != "" {
+			terms = append(terms, `"`+word+`"*`)
+		}
+	}
+
+	return strings.Join(terms, " ")
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func scanExpenses(rows *sql.Rows) ([]Expense, error) {
+	defer rows.Close()
+
+	var expenses []Expense
+	for rows.Next() {
+		var expense Expense
+		var dateMs int64
+		var tags sql.NullString
+
+		err := rows.Scan(&expense.ID, &expense.UserID, &expense.WalletID, &dateMs, &expense.Category, &expense.Amount, &expense.Note, &tags)
+		if err != nil {
+			return nil, err
+		}
+
+		expense.Date = time.UnixMilli(dateMs)
+		if tags.Valid {
+			expense.Tags = strings.Fields(tags.String)
+		}
+		expenses = append(expenses, expense)
+	}
+
+	return expenses, rows.Err()
+}
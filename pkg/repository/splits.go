@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+)
+
+var ErrUserNotFound = errors.New("пользователь не найден")
+
+// User зарегистрированный пользователь бота
+type User struct {
+	ID     int
+	Name   string
+	ChatID int64
+}
+
+// Debt долг участника перед тем, кто оплатил общий расход
+type Debt struct {
+	DebtorID int
+	Amount   float64
+}
+
+// Balance взаимный баланс с другим пользователем:
+// положительная сумма — он должен вам, отрицательная — вы должны ему
+type Balance struct {
+	User   User
+	Amount float64
+}
+
+// GetUserByName ищет зарегистрированного пользователя по имени в Telegram без учета регистра
+func (r *SQLiteExpenseRepository) GetUserByName(userName string) (User, error) {
+	var user User
+	err := r.db.QueryRow(`
+        SELECT user_id, COALESCE(user_name, ''), chat_id FROM users WHERE user_name = ? COLLATE NOCASE
+    `, userName).Scan(&user.ID, &user.Name, &user.ChatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrUserNotFound
+	}
+
+	return user, err
+}
+
+// AddSplitExpense добавляет долю плательщика как расход и долги участников перед ним.
+// Если на плательщика ничего не приходится, сохраняются только долги
+func (r *SQLiteExpenseRepository) AddSplitExpense(expense Expense, debts []Debt) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var expenseID int64
+	if expense.Amount > 0 {
+		if expenseID, err = insertExpense(tx, expense); err != nil {
+			return err
+		}
+	}
+
+	for _, debt := range debts {
+		_, err = tx.Exec(`
+            INSERT INTO splits (expense_id, creditor_id, debtor_id, amount, date_ms) VALUES (?, ?, ?, ?, ?)
+        `, expenseID, expense.UserID, debt.DebtorID, debt.Amount, expense.Date.UnixMilli())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// AddSettlement записывает возврат долга: fromID перевел toID сумму amount
+func (r *SQLiteExpenseRepository) AddSettlement(fromID, toID int, amount float64) error {
+	_, err := r.db.Exec(`
+        INSERT INTO settlements (from_id, to_id, amount, date_ms) VALUES (?, ?, ?, ?)
+    `, fromID, toID, amount, time.Now().UnixMilli())
+
+	return err
+}
+
+// GetBalances возвращает ненулевые балансы пользователя со всеми, с кем он делил расходы
+func (r *SQLiteExpenseRepository) GetBalances(userID int) ([]Balance, error) {
+	rows, err := r.db.Query(`
+        SELECT b.other_id, COALESCE(u.user_name, ''), COALESCE(u.chat_id, 0), SUM(b.amount) AS total
+        FROM (
+            SELECT debtor_id AS other_id, amount FROM splits WHERE creditor_id = ?
+            UNION ALL
+            SELECT creditor_id, -amount FROM splits WHERE debtor_id = ?
+            UNION ALL
+            SELECT to_id, amount FROM settlements WHERE from_id = ?
+            UNION ALL
+            SELECT from_id, -amount FROM settlements WHERE to_id = ?
+        ) b
+        LEFT JOIN users u ON u.user_id = b.other_id
+        GROUP BY b.other_id
+        ORDER BY total DESC
+    `, userID, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []Balance
+	for rows.Next() {
+		var balance Balance
+		if err = rows.Scan(&balance.User.ID, &balance.User.Name, &balance.User.ChatID, &balance.Amount); err != nil {
+			return nil, err
+		}
+
+		// Погашенные долги дают ноль с погрешностью округления
+		balance.Amount = math.Round(balance.Amount*100) / 100
+		if balance.Amount == 0 {
+			continue
+		}
+		balances = append(balances, balance)
+	}
+
+	return balances, rows.Err()
+}
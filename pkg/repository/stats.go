@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+)
+
+// Формат дня в таблице активности и в агрегатах по дням
+const dayFormat = "2006-01-02"
+
+// DayCount значение показателя за день
+type DayCount struct {
+	Day   time.Time
+	Count int
+}
+
+// CategoryCount сколько раз и на какую сумму записывались расходы категории
+type CategoryCount struct {
+	Category string
+	Count    int
+	Amount   float64
+}
+
+// Cohort пользователи, зарегистрированные за одну неделю.
+// Retained[i] — сколько из них пользовались ботом на i+1-й неделе после регистрации
+type Cohort struct {
+	Start    time.Time
+	Users    int
+	Retained []int
+}
+
+// migrateActivity заполняет активность по расходам и регистрациям, записанным до появления учета активности
+func (r *SQLiteExpenseRepository) migrateActivity() error {
+	var count int
+	if err := r.db.QueryRow(`SELECT count(*) FROM user_activity`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err := r.db.Exec(`
+        INSERT OR IGNORE INTO user_activity (user_id, day)
+        SELECT user_id, substr(registered, 1, 10) FROM users WHERE registered IS NOT NULL
+        UNION
+        SELECT DISTINCT user_id, date(date_ms / 1000, 'unixepoch', 'localtime') FROM expenses WHERE date_ms IS NOT NULL
+    `)
+	return err
+}
+
+// TouchUserActivity отмечает, что пользователь пользовался ботом в день day.
+// Пользователь, ранее заблокировавший бота, снова считается активным
+func (r *SQLiteExpenseRepository) TouchUserActivity(userID int, day time.Time) error {
+	_, err := r.db.Exec(`
+        INSERT OR IGNORE INTO user_activity (user_id, day) VALUES (?, ?)
+    `, userID, day.Format(dayFormat))
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(`UPDATE users SET active = 1 WHERE user_id = ? AND active = 0`, userID)
+	return err
+}
+
+// GetActiveUserCount возвращает количество пользователей, активных начиная с дня since
+func (r *SQLiteExpenseRepository) GetActiveUserCount(since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+        SELECT count(DISTINCT user_id) FROM user_activity WHERE day >= ?
+    `, since.Format(dayFormat)).Scan(&count)
+
+	return count, err
+}
+
+// GetRegistrationsByDay возвращает количество регистраций по дням начиная с since
+func (r *SQLiteExpenseRepository) GetRegistrationsByDay(since time.Time) ([]DayCount, error) {
+	return r.queryDayCounts(`
+        SELECT substr(registered, 1, 10) AS day, count(*)
+        FROM users
+        WHERE registered >= ?
+        GROUP BY day
+        ORDER BY day
+    `, since.Format(dayFormat))
+}
+
+// GetExpenseCountsByDay возвращает количество записанных расходов по дням начиная с since
+func (r *SQLiteExpenseRepository) GetExpenseCountsByDay(since time.Time) ([]DayCount, error) {
+	return r.queryDayCounts(`
+        SELECT date(date_ms / 1000, 'unixepoch', 'localtime') AS day, count(*)
+        FROM expenses
+        WHERE date_ms >= ?
+        GROUP BY day
+        ORDER BY day
+    `, since.UnixMilli())
+}
+
+func (r *SQLiteExpenseRepository) queryDayCounts(query string, args ...interface{}) ([]DayCount, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []DayCount
+	for rows.Next() {
+		var day string
+		var count DayCount
+		if err = rows.Scan(&day, &count.Count); err != nil {
+			return nil, err
+		}
+		if count.Day, err = time.ParseInLocation(dayFormat, day, time.Local); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// GetTopCategories возвращает самые используемые категории начиная с since
+func (r *SQLiteExpenseRepository) GetTopCategories(since time.Time, limit int) ([]CategoryCount, error) {
+	rows, err := r.db.Query(`
+        SELECT category, count(*) AS cnt, SUM(amount)
+        FROM expenses
+        WHERE date_ms >= ?
+        GROUP BY category
+        ORDER BY cnt DESC, category
+        LIMIT ?
+    `, since.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []CategoryCount
+	for rows.Next() {
+		var c CategoryCount
+		if err = rows.Scan(&c.Category, &c.Count, &c.Amount); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+
+	return categories, rows.Err()
+}
+
+// GetRetentionCohorts возвращает недельные когорты пользователей, зарегистрированных начиная с since,
+// с удержанием на протяжении weeks недель после регистрации
+func (r *SQLiteExpenseRepository) GetRetentionCohorts(since time.Time, weeks int) ([]Cohort, error) {
+	rows, err := r.db.Query(`
+        SELECT u.user_id, substr(u.registered, 1, 10), a.day
+        FROM users u
+        LEFT JOIN user_activity a ON a.user_id = u.user_id AND a.day > substr(u.registered, 1, 10)
+        WHERE u.registered >= ?
+    `, WeekStart(since).Format(dayFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cohorts := make(map[time.Time]*Cohort)
+	// Пользователи, уже учтенные в когорте и на каждой неделе удержания
+	seen := make(map[time.Time]map[int]bool)
+	retained := make(map[time.Time][]map[int]bool)
+
+	for rows.Next() {
+		var userID int
+		var registered string
+		var activeDay sql.NullString
+		if err = rows.Scan(&userID, &registered, &activeDay); err != nil {
+			return nil, err
+		}
+
+		regDay, err := time.ParseInLocation(dayFormat, registered, time.Local)
+		if err != nil {
+			continue
+		}
+
+		start := WeekStart(regDay)
+		cohort, ok := cohorts[start]
+		if !ok {
+			cohort = &Cohort{Start: start, Retained: make([]int, weeks)}
+			cohorts[start] = cohort
+			seen[start] = make(map[int]bool)
+			retained[start] = make([]map[int]bool, weeks)
+			for i := range retained[start] {
+				retained[start][i] = make(map[int]bool)
+			}
+		}
+		if !seen[start][userID] {
+			seen[start][userID] = true
+			cohort.Users++
+		}
+
+		if !activeDay.Valid {
+			continue
+		}
+		day, err := time.ParseInLocation(dayFormat, activeDay.String, time.Local)
+		if err != nil {
+			continue
+		}
+
+		week := int(math.Round(day.Sub(start).Hours()/24)) / 7
+		if week >= 1 && week <= weeks && !retained[start][week-1][userID] {
+			retained[start][week-1][userID] = true
+			cohort.Retained[week-1]++
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]Cohort, 0, len(cohorts))
+	for _, cohort := range cohorts {
+		result = append(result, *cohort)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+
+	return result, nil
+}
+
+// WeekStart возвращает начало недели (понедельник) дня t; по этим неделям считаются когорты
+func WeekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"database/sql"
+)
+
+func insertExpenseTags(tx *sql.Tx, userID int, expenseID int64, tags []string) error {
+	for _, tag := range tags {
+		_, err := tx.Exec(`
+            INSERT OR IGNORE INTO tags (user_id, name) VALUES (?, ?)
+        `, userID, tag)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+            INSERT OR IGNORE INTO expense_tags (expense_id, tag_id)
+            SELECT ?, id FROM tags WHERE user_id = ? AND name = ?
+        `, expenseID, userID, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTagTotalsUnix возвращает суммы расходов кошелька по тегам за период
+func (r *SQLiteExpenseRepository) GetTagTotalsUnix(walletID int64, startUnixMilli, endUnixMilli int64) (map[string]float64, error) {
+	rows, err := r.db.Query(`
+        SELECT t.name, SUM(e.amount) as total
+        FROM expenses e
+        JOIN expense_tags et ON et.expense_id = e.id
+        JOIN tags t ON t.id = et.tag_id
+        WHERE e.wallet_id = ? AND e.date_ms >= ? AND e.date_ms <= ?
+        GROUP BY t.name
+    `, walletID, startUnixMilli, endUnixMilli)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTotals(rows)
+}
+
+// GetExpensesByTagUnix возвращает суммы расходов кошелька с тегом по категориям за период
+func (r *SQLiteExpenseRepository) GetExpensesByTagUnix(walletID int64, tag string, startUnixMilli, endUnixMilli int64) (map[string]float64, error) {
+	rows, err := r.db.Query(`
+        SELECT e.category, SUM(e.amount) as total
+        FROM expenses e
+        JOIN expense_tags et ON et.expense_id = e.id
+        JOIN tags t ON t.id = et.tag_id
+        WHERE e.wallet_id = ? AND t.name = ? AND e.date_ms >= ? AND e.date_ms <= ?
+        GROUP BY e.category
+    `, walletID, tag, startUnixMilli, endUnixMilli)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTotals(rows)
+}
+
+// scanTotals читает пары (название, сумма) в мапу
+func scanTotals(rows *sql.Rows) (map[string]float64, error) {
+	defer rows.Close()
+
+	totals := make(map[string]float64)
+	var name string
+	var total float64
+
+	for rows.Next() {
+		if err := rows.Scan(&name, &total); err != nil {
+			return nil, err
+		}
+		totals[name] = total
+	}
+
+	return totals, rows.Err()
+}
@@ -0,0 +1,342 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Роли участников кошелька
+const (
+	RoleOwner  = "owner"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+var ErrInviteNotFound = errors.New("приглашение не найдено")
+
+var ErrChatWalletNotFound = errors.New("у чата нет кошелька")
+
+// Wallet кошелек, которому принадлежат расходы
+type Wallet struct {
+	ID       int64
+	Name     string
+	OwnerID  int
+	Personal bool
+	// Роль пользователя, для которого запрошен кошелек
+	Role    string
+	Members int
+}
+
+// CanEdit проверяет, может ли участник добавлять и изменять расходы
+func (w Wallet) CanEdit() bool {
+	return w.Role == RoleOwner || w.Role == RoleMember
+}
+
+const walletColumns = `
+    w.id, w.name, w.owner_id, w.personal, m.role,
+    (SELECT count(*) FROM wallet_members wm WHERE wm.wallet_id = w.id)`
+
+// migrateWallets создает личные кошельки пользователям, зарегистрированным до появления кошельков
+func (r *SQLiteExpenseRepository) migrateWallets() error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+        SELECT user_id FROM users
+        WHERE user_id NOT IN (SELECT owner_id FROM wallets WHERE personal = 1)
+    `)
+	if err != nil {
+		return err
+	}
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err = rows.Scan(&userID); err != nil {
+			rows.Close()
+			return err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+
+	for _, userID := range userIDs {
+		if _, err = createPersonalWallet(tx, userID); err != nil {
+			return err
+		}
+	}
+
+	// Расходы без кошелька переносим в личный кошелек автора
+	_, err = tx.Exec(`
+        UPDATE expenses SET wallet_id = (
+            SELECT w.id FROM wallets w WHERE w.owner_id = expenses.user_id AND w.personal = 1
+        )
+        WHERE wallet_id = 0 AND user_id IN (SELECT owner_id FROM wallets WHERE personal = 1)
+    `)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func createPersonalWallet(tx *sql.Tx, userID int) (int64, error) {
+	walletID, err := insertWallet(tx, userID, "", true)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`UPDATE users SET wallet_id = ? WHERE user_id = ? AND wallet_id = 0`, walletID, userID)
+	return walletID, err
+}
+
+func insertWallet(tx *sql.Tx, ownerID int, name string, personal bool) (int64, error) {
+	res, err := tx.Exec(`
+        INSERT INTO wallets (name, owner_id, personal, created) VALUES (?, ?, ?, ?)
+    `, name, ownerID, personal, time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return 0, err
+	}
+
+	walletID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO wallet_members (wallet_id, user_id, role) VALUES (?, ?, ?)
+    `, walletID, ownerID, RoleOwner)
+
+	return walletID, err
+}
+
+// GetCurrentWallet возвращает кошелек, выбранный пользователем
+func (r *SQLiteExpenseRepository) GetCurrentWallet(userID int) (Wallet, error) {
+	row := r.db.QueryRow(`
+        SELECT `+walletColumns+`
+        FROM users u
+        JOIN wallets w ON w.id = u.wallet_id
+        JOIN wallet_members m ON m.wallet_id = w.id AND m.user_id = u.user_id
+        WHERE u.user_id = ?
+    `, userID)
+
+	return scanWallet(row)
+}
+
+// GetUserWallets возвращает все кошельки, в которых состоит пользователь
+func (r *SQLiteExpenseRepository) GetUserWallets(userID int) ([]Wallet, error) {
+	rows, err := r.db.Query(`
+        SELECT `+walletColumns+`
+        FROM wallet_members m
+        JOIN wallets w ON w.id = m.wallet_id
+        WHERE m.user_id = ?
+        ORDER BY w.personal DESC, w.id
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var wallets []Wallet
+	for rows.Next() {
+		wallet, err := scanWallet(rows)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, wallet)
+	}
+
+	return wallets, rows.Err()
+}
+
+// SetCurrentWallet переключает пользователя на кошелек, в котором он состоит
+func (r *SQLiteExpenseRepository) SetCurrentWallet(userID int, walletID int64) error {
+	res, err := r.db.Exec(`
+        UPDATE users SET wallet_id = ?
+        WHERE user_id = ? AND EXISTS (SELECT 1 FROM wallet_members WHERE wallet_id = ? AND user_id = ?)
+    `, walletID, userID, walletID, userID)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// CreateWallet создает общий кошелек, владельцем которого становится ownerID
+func (r *SQLiteExpenseRepository) CreateWallet(ownerID int, name string) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	walletID, err := insertWallet(tx, ownerID, name, false)
+	if err != nil {
+		return 0, err
+	}
+
+	return walletID, tx.Commit()
+}
+
+// CreateWalletInvite сохраняет одноразовое приглашение в кошелек, действующее до expires
+func (r *SQLiteExpenseRepository) CreateWalletInvite(walletID int64, createdBy int, role string, token string, expires time.Time) error {
+	_, err := r.db.Exec(`
+        INSERT INTO wallet_invites (token, wallet_id, role, created_by, expires_ms, created) VALUES (?, ?, ?, ?, ?, ?)
+    `, token, walletID, role, createdBy, expires.UnixMilli(), time.Now().Format("2006-01-02 15:04:05"))
+
+	return err
+}
+
+// AcceptWalletInvite добавляет пользователя в кошелек по приглашению и делает кошелек текущим.
+// Для неизвестного или просроченного приглашения возвращается ErrInviteNotFound
+func (r *SQLiteExpenseRepository) AcceptWalletInvite(userID int, token string) (Wallet, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	var walletID int64
+	var role string
+	now := time.Now().UnixMilli()
+	err = tx.QueryRow(`
+        SELECT wallet_id, role FROM wallet_invites WHERE token = ? AND expires_ms > ?
+    `, token, now).Scan(&walletID, &role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Wallet{}, ErrInviteNotFound
+	}
+	if err != nil {
+		return Wallet{}, err
+	}
+
+	// Вместе с использованным удаляются просроченные приглашения
+	if _, err = tx.Exec(`DELETE FROM wallet_invites WHERE token = ? OR expires_ms <= ?`, token, now); err != nil {
+		return Wallet{}, err
+	}
+
+	// Уже состоящий в кошельке участник сохраняет свою роль
+	_, err = tx.Exec(`
+        INSERT OR IGNORE INTO wallet_members (wallet_id, user_id, role) VALUES (?, ?, ?)
+    `, walletID, userID, role)
+	if err != nil {
+		return Wallet{}, err
+	}
+
+	if _, err = tx.Exec(`UPDATE users SET wallet_id = ? WHERE user_id = ?`, walletID, userID); err != nil {
+		return Wallet{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Wallet{}, err
+	}
+
+	return r.GetCurrentWallet(userID)
+}
+
+// CreateChatWallet создает кошелек группового чата с владельцем ownerID.
+// Если кошелек у чата уже есть, ничего не меняется
+func (r *SQLiteExpenseRepository) CreateChatWallet(chatID int64, ownerID int, name string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err = tx.QueryRow(`SELECT count(*) FROM wallets WHERE chat_id = ?`, chatID).Scan(&count); err != nil || count > 0 {
+		return err
+	}
+
+	walletID, err := insertWallet(tx, ownerID, name, false)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`UPDATE wallets SET chat_id = ? WHERE id = ?`, chatID, walletID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetChatWallet возвращает кошелек группового чата; написавший в чат пользователь становится участником кошелька.
+// Если кошелька у чата еще нет, возвращается ErrChatWalletNotFound
+func (r *SQLiteExpenseRepository) GetChatWallet(chatID int64, userID int) (Wallet, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	var walletID int64
+	err = tx.QueryRow(`SELECT id FROM wallets WHERE chat_id = ?`, chatID).Scan(&walletID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Wallet{}, ErrChatWalletNotFound
+	}
+	if err != nil {
+		return Wallet{}, err
+	}
+
+	_, err = tx.Exec(`
+        INSERT OR IGNORE INTO wallet_members (wallet_id, user_id, role) VALUES (?, ?, ?)
+    `, walletID, userID, RoleMember)
+	if err != nil {
+		return Wallet{}, err
+	}
+
+	row := tx.QueryRow(`
+        SELECT `+walletColumns+`
+        FROM wallets w
+        JOIN wallet_members m ON m.wallet_id = w.id AND m.user_id = ?
+        WHERE w.id = ?
+    `, userID, walletID)
+
+	wallet, err := scanWallet(row)
+	if err != nil {
+		return Wallet{}, err
+	}
+
+	return wallet, tx.Commit()
+}
+
+// RenameWallet меняет название кошелька
+func (r *SQLiteExpenseRepository) RenameWallet(walletID int64, name string) error {
+	_, err := r.db.Exec(`UPDATE wallets SET name = ? WHERE id = ?`, name, walletID)
+
+	return err
+}
+
+// GetMemberTotalsUnix возвращает суммы расходов кошелька за период по участникам
+func (r *SQLiteExpenseRepository) GetMemberTotalsUnix(walletID int64, startUnixMilli, endUnixMilli int64) (map[string]float64, error) {
+	rows, err := r.db.Query(`
+        SELECT COALESCE(NULLIF(u.user_name, ''), CAST(e.user_id AS TEXT)), SUM(e.amount) as total
+        FROM expenses e
+        LEFT JOIN users u ON u.user_id = e.user_id
+        WHERE e.wallet_id = ? AND e.date_ms >= ? AND e.date_ms <= ?
+        GROUP BY e.user_id
+    `, walletID, startUnixMilli, endUnixMilli)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTotals(rows)
+}
+
+// rowScanner общий интерфейс для *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWallet(row rowScanner) (Wallet, error) {
+	var wallet Wallet
+	err := row.Scan(&wallet.ID, &wallet.Name, &wallet.OwnerID, &wallet.Personal, &wallet.Role, &wallet.Members)
+
+	return wallet, err
+}
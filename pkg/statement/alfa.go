@@ -0,0 +1,59 @@
+package statement
+
+import "regexp"
+
+var alfaMCCRx = regexp.MustCompile(`MCC(\d{4})`)
+
+// alfaParser разбирает выписку Альфа-Банка
+type alfaParser struct{}
+
+func (p *alfaParser) Name() string {
+	return "Альфа-Банк"
+}
+
+func (p *alfaParser) Detect(header []string) bool {
+	return hasColumns(header, "дата операции", "референс проводки", "описание операции", "приход", "расход")
+}
+
+func (p *alfaParser) Parse(header []string, records [][]string) ([]Transaction, error) {
+	dateIdx := columnIndex(header, "дата операции")
+	descriptionIdx := columnIndex(header, "описание операции")
+	debitIdx := columnIndex(header, "расход")
+
+	var transactions []Transaction
+	for _, record := range records {
+		rawAmount := field(record, debitIdx)
+		if rawAmount == "" {
+			continue
+		}
+
+		amount, err := parseAmount(rawAmount)
+		if err != nil {
+			return nil, err
+		}
+		if amount <= 0 {
+			continue
+		}
+
+		date, err := parseDate(field(record, dateIdx), "02.01.06", "02.01.2006")
+		if err != nil {
+			return nil, err
+		}
+
+		// MCC Альфа-Банк указывает внутри описания операции
+		description := field(record, descriptionIdx)
+		var mcc int
+		if match := alfaMCCRx.FindStringSubmatch(description); match != nil {
+			mcc = parseMCC(match[1])
+		}
+
+		transactions = append(transactions, Transaction{
+			Date:        date,
+			Amount:      amount,
+			MCC:         mcc,
+			Description: description,
+		})
+	}
+
+	return transactions, nil
+}
@@ -0,0 +1,112 @@
+package statement
+
+import "strings"
+
+// DefaultCategory категория для операций, не подошедших ни под одно правило
+const DefaultCategory = "btn_other"
+
+// MCCRange диапазон MCC кодов (включительно)
+type MCCRange struct {
+	From, To int
+}
+
+// Rule правило сопоставления операции с категорией бота
+type Rule struct {
+	Category       string
+	MCC            []MCCRange
+	BankCategories []string
+	Keywords       []string
+}
+
+// Rules таблица правил, проверяется по порядку: сначала MCC, затем категория банка, затем описание
+var Rules = []Rule{
+	{
+		Category:       "btn_groceries",
+		MCC:            []MCCRange{{5411, 5411}, {5422, 5422}, {5441, 5462}, {5499, 5499}},
+		BankCategories: []string{"супермаркеты", "продукты"},
+		Keywords:       []string{"пятерочка", "перекресток", "магнит", "ашан", "лента", "вкусвилл", "pyaterochka", "perekrestok", "magnit", "auchan"},
+	},
+	{
+		Category:       "btn_beauty",
+		MCC:            []MCCRange{{5977, 5977}, {7230, 7230}, {7298, 7298}},
+		BankCategories: []string{"красота", "косметика"},
+		Keywords:       []string{"салон", "барбершоп", "летуаль", "золотое яблоко"},
+	},
+	{
+		Category:       "btn_health",
+		MCC:            []MCCRange{{5912, 5912}, {7997, 7997}, {8011, 8099}},
+		BankCategories: []string{"аптеки", "медицина", "здоровье", "спорттовары"},
+		Keywords:       []string{"аптека", "apteka", "клиника", "фитнес"},
+	},
+	{
+		Category:       "btn_restaurants",
+		MCC:            []MCCRange{{5811, 5814}},
+		BankCategories: []string{"рестораны", "фастфуд", "рестораны и кафе"},
+		Keywords:       []string{"кафе", "ресторан", "кофе", "coffee", "cafe", "burger"},
+	},
+	{
+		Category:       "btn_entertainment",
+		MCC:            []MCCRange{{5815, 5818}, {7832, 7832}, {7922, 7929}, {7991, 7999}},
+		BankCategories: []string{"развлечения", "кино", "музыка", "цифровые товары"},
+		Keywords:       []string{"кино", "театр", "cinema", "steam"},
+	},
+	{
+		Category:       "btn_growth",
+		MCC:            []MCCRange{{5192, 5192}, {5942, 5942}, {8211, 8299}},
+		BankCategories: []string{"образование", "книги"},
+		Keywords:       []string{"курс", "школа", "книг"},
+	},
+	{
+		Category:       "btn_trips",
+		MCC:            []MCCRange{{3000, 3299}, {3351, 3441}, {3501, 3999}, {4411, 4411}, {4511, 4511}, {4722, 4722}, {7011, 7011}},
+		BankCategories: []string{"авиабилеты", "отели", "путешествия", "турагентства", "ж/д билеты"},
+		Keywords:       []string{"aviasales", "booking", "отель", "hotel"},
+	},
+	{
+		Category:       "btn_transport",
+		MCC:            []MCCRange{{4111, 4131}, {4784, 4784}, {5541, 5542}, {7523, 7523}},
+		BankCategories: []string{"транспорт", "такси", "топливо", "азс", "автоуслуги"},
+		Keywords:       []string{"такси", "taxi", "метро", "uber", "азс"},
+	},
+	{
+		Category:       "btn_business",
+		MCC:            []MCCRange{{4814, 4816}, {4899, 4900}, {6300, 6300}, {7311, 7311}},
+		BankCategories: []string{"связь", "коммунальные услуги", "жкх", "страхование"},
+		Keywords:       []string{"мтс", "билайн", "мегафон", "ростелеком"},
+	},
+}
+
+// Category определяет ключ категории бота для операции
+func Category(t Transaction) string {
+	if t.MCC != 0 {
+		for _, rule := range Rules {
+			for _, r := range rule.MCC {
+				if t.MCC >= r.From && t.MCC <= r.To {
+					return rule.Category
+				}
+			}
+		}
+	}
+
+	bankCategory := strings.ToLower(strings.TrimSpace(t.BankCategory))
+	if bankCategory != "" {
+		for _, rule := range Rules {
+			for _, c := range rule.BankCategories {
+				if bankCategory == c {
+					return rule.Category
+				}
+			}
+		}
+	}
+
+	description := strings.ReplaceAll(strings.ToLower(t.Description), "ё", "е")
+	for _, rule := range Rules {
+		for _, keyword := range rule.Keywords {
+			if strings.Contains(description, keyword) {
+				return rule.Category
+			}
+		}
+	}
+
+	return DefaultCategory
+}
@@ -0,0 +1,55 @@
+package statement
+
+import (
+	"math"
+	"strings"
+)
+
+// sberParser разбирает выписку Сбербанка
+type sberParser struct{}
+
+func (p *sberParser) Name() string {
+	return "Сбербанк"
+}
+
+func (p *sberParser) Detect(header []string) bool {
+	return hasColumns(header, "дата операции", "категория", "описание", "сумма в валюте счета")
+}
+
+func (p *sberParser) Parse(header []string, records [][]string) ([]Transaction, error) {
+	dateIdx := columnIndex(header, "дата операции")
+	categoryIdx := columnIndex(header, "категория")
+	descriptionIdx := columnIndex(header, "описание")
+	amountIdx := columnIndex(header, "сумма в валюте счета")
+
+	var transactions []Transaction
+	for _, record := range records {
+		rawAmount := field(record, amountIdx)
+		// Зачисления Сбербанк помечает знаком "+"
+		if rawAmount == "" || strings.HasPrefix(rawAmount, "+") {
+			continue
+		}
+
+		amount, err := parseAmount(rawAmount)
+		if err != nil {
+			return nil, err
+		}
+		if amount == 0 {
+			continue
+		}
+
+		date, err := parseDate(field(record, dateIdx), "02.01.2006 15:04", "02.01.2006")
+		if err != nil {
+			return nil, err
+		}
+
+		transactions = append(transactions, Transaction{
+			Date:         date,
+			Amount:       math.Abs(amount),
+			Description:  field(record, descriptionIdx),
+			BankCategory: field(record, categoryIdx),
+		})
+	}
+
+	return transactions, nil
+}
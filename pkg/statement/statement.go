@@ -0,0 +1,154 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrUnknownFormat = errors.New("неизвестный формат выписки")
+
+// Transaction операция из банковской выписки
+type Transaction struct {
+	Date         time.Time
+	Amount       float64
+	MCC          int
+	Description  string
+	BankCategory string
+}
+
+// Parser интерфейс для разбора выписки конкретного банка
+type Parser interface {
+	Name() string
+	Detect(header []string) bool
+	Parse(header []string, records [][]string) ([]Transaction, error)
+}
+
+// Parsers список поддерживаемых форматов выписок
+var Parsers = []Parser{
+	&tinkoffParser{},
+	&sberParser{},
+	&alfaParser{},
+}
+
+// Parse определяет формат выписки по заголовку и возвращает списания
+func Parse(data []byte) (string, []Transaction, error) {
+	records, err := readCSV(data)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(records) == 0 {
+		return "", nil, ErrUnknownFormat
+	}
+
+	header := normalizeHeader(records[0])
+	for _, p := range Parsers {
+		if !p.Detect(header) {
+			continue
+		}
+
+		transactions, err := p.Parse(header, records[1:])
+		return p.Name(), transactions, err
+	}
+
+	return "", nil, ErrUnknownFormat
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	// Банки часто выгружают выписки в Windows-1251
+	if !utf8.Valid(data) {
+		data = decodeWindows1251(data)
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	return reader.ReadAll()
+}
+
+func detectDelimiter(data []byte) rune {
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+
+	if bytes.Count(firstLine, []byte(";")) >= bytes.Count(firstLine, []byte(",")) {
+		return ';'
+	}
+
+	return ','
+}
+
+func normalizeHeader(header []string) []string {
+	normalized := make([]string, len(header))
+	for i, col := range header {
+		col = strings.ToLower(strings.TrimSpace(strings.Trim(col, "\"")))
+		normalized[i] = strings.ReplaceAll(col, "ё", "е")
+	}
+
+	return normalized
+}
+
+// columnIndex возвращает индекс колонки в заголовке или -1
+func columnIndex(header []string, name string) int {
+	for i, col := range header {
+		if col == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func hasColumns(header []string, names ...string) bool {
+	for _, name := range names {
+		if columnIndex(header, name) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func field(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+
+	return strings.TrimSpace(record[index])
+}
+
+// parseAmount разбирает сумму вида "-1 234,56"
+func parseAmount(s string) (float64, error) {
+	s = strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "", ",", ".").Replace(s)
+	return strconv.ParseFloat(s, 64)
+}
+
+func parseDate(s string, layouts ...string) (time.Time, error) {
+	var err error
+	for _, layout := range layouts {
+		var date time.Time
+		date, err = time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+func parseMCC(s string) int {
+	mcc, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+
+	return mcc
+}
@@ -0,0 +1,180 @@
+package statement
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseFiles(t *testing.T) {
+	tests := []struct {
+		file  string
+		bank  string
+		want  []Transaction
+		rules []string
+	}{
+		{
+			file: "tinkoff.csv",
+			bank: "Тинькофф",
+			want: []Transaction{
+				{Date: time.Date(2024, 3, 15, 18, 42, 10, 0, time.Local), Amount: 1234.56, MCC: 5411, Description: "Пятерочка", BankCategory: "Супермаркеты"},
+				{Date: time.Date(2024, 3, 12, 20, 15, 33, 0, time.Local), Amount: 350, Description: "Кафе Ромашка", BankCategory: "Рестораны"},
+			},
+			rules: []string{"btn_groceries", "btn_restaurants"},
+		},
+		{
+			file: "sber.csv",
+			bank: "Сбербанк",
+			want: []Transaction{
+				{Date: time.Date(2024, 3, 10, 14, 30, 0, 0, time.Local), Amount: 820.5, Description: "АПТЕКА 36,6", BankCategory: "Аптеки"},
+				{Date: time.Date(2024, 3, 8, 0, 0, 0, 0, time.Local), Amount: 450, Description: "Оплата услуг МТС", BankCategory: "Прочие расходы"},
+			},
+			rules: []string{"btn_health", "btn_business"},
+		},
+		{
+			file: "alfa.csv",
+			bank: "Альфа-Банк",
+			want: []Transaction{
+				{Date: time.Date(2024, 3, 5, 0, 0, 0, 0, time.Local), Amount: 2100, MCC: 5411, Description: "RU MOSCOW Lenta MCC5411"},
+				{Date: time.Date(2024, 3, 3, 0, 0, 0, 0, time.Local), Amount: 700, Description: "RU MOSCOW CINEMA PARK"},
+			},
+			rules: []string{"btn_groceries", "btn_entertainment"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			bank, transactions, err := Parse(data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if bank != tt.bank {
+				t.Errorf("банк = %q, ожидался %q", bank, tt.bank)
+			}
+			if len(transactions) != len(tt.want) {
+				t.Fatalf("операций %d, ожидалось %d: %+v", len(transactions), len(tt.want), transactions)
+			}
+			for i, want := range tt.want {
+				got := transactions[i]
+				if !got.Date.Equal(want.Date) || got.Amount != want.Amount || got.MCC != want.MCC ||
+					got.Description != want.Description || got.BankCategory != want.BankCategory {
+					t.Errorf("операция %d = %+v, ожидалась %+v", i, got, want)
+				}
+				if category := Category(got); category != tt.rules[i] {
+					t.Errorf("категория операции %d = %q, ожидалась %q", i, category, tt.rules[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	for _, data := range []string{"", "a;b;c\n1;2;3\n"} {
+		if _, _, err := Parse([]byte(data)); !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("Parse(%q) = %v, ожидалась ErrUnknownFormat", data, err)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		header string
+		bank   string
+	}{
+		{`"Дата операции";"Статус";"Сумма операции";"MCC";"Описание"`, "Тинькофф"},
+		{`Дата операции;Категория;Описание;Сумма в валюте счета`, "Сбербанк"},
+		{`Дата операции,Референс проводки,Описание операции,Приход,Расход`, "Альфа-Банк"},
+	}
+
+	for _, tt := range tests {
+		records, err := readCSV([]byte(tt.header + "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var detected []string
+		for _, p := range Parsers {
+			if p.Detect(normalizeHeader(records[0])) {
+				detected = append(detected, p.Name())
+			}
+		}
+		if len(detected) != 1 || detected[0] != tt.bank {
+			t.Errorf("заголовок %q определен как %v, ожидался %q", tt.header, detected, tt.bank)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"-1 234,56", -1234.56},
+		{"1 000,00", 1000},
+		{"2 500.5", 2500.5},
+		{"+5 000,00", 5000},
+		{"0", 0},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAmount(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseAmount(%q) = %v, %v, ожидалось %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	if _, err := parseAmount("abc"); err == nil {
+		t.Error("parseAmount(abc) должна вернуть ошибку")
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in      string
+		layouts []string
+		want    time.Time
+	}{
+		{"15.03.2024 18:42:10", []string{"02.01.2006 15:04:05", "02.01.2006"}, time.Date(2024, 3, 15, 18, 42, 10, 0, time.Local)},
+		{"15.03.2024", []string{"02.01.2006 15:04:05", "02.01.2006"}, time.Date(2024, 3, 15, 0, 0, 0, 0, time.Local)},
+		{"05.03.24", []string{"02.01.06", "02.01.2006"}, time.Date(2024, 3, 5, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDate(tt.in, tt.layouts...)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseDate(%q) = %v, %v, ожидалось %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	if _, err := parseDate("2024-03-15", "02.01.2006"); err == nil {
+		t.Error("parseDate в чужом формате должна вернуть ошибку")
+	}
+}
+
+func TestCategory(t *testing.T) {
+	tests := []struct {
+		name string
+		t    Transaction
+		want string
+	}{
+		{"MCC важнее категории банка", Transaction{MCC: 5812, BankCategory: "Супермаркеты"}, "btn_restaurants"},
+		{"MCC из диапазона", Transaction{MCC: 3100}, "btn_trips"},
+		{"категория банка без учета регистра", Transaction{BankCategory: " Такси "}, "btn_transport"},
+		{"ключевое слово в описании", Transaction{Description: "ПЕРЕКРЁСТОК 123"}, "btn_groceries"},
+		{"неизвестный MCC и описание", Transaction{MCC: 1, Description: "что-то"}, DefaultCategory},
+		{"пустая операция", Transaction{}, DefaultCategory},
+	}
+
+	for _, tt := range tests {
+		if got := Category(tt.t); got != tt.want {
+			t.Errorf("%s: Category = %q, ожидалась %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package statement
+
+import "math"
+
+// tinkoffParser разбирает выписку Тинькофф (Т-Банк)
+type tinkoffParser struct{}
+
+func (p *tinkoffParser) Name() string {
+	return "Тинькофф"
+}
+
+func (p *tinkoffParser) Detect(header []string) bool {
+	return hasColumns(header, "дата операции", "статус", "сумма операции", "mcc", "описание")
+}
+
+func (p *tinkoffParser) Parse(header []string, records [][]string) ([]Transaction, error) {
+	dateIdx := columnIndex(header, "дата операции")
+	statusIdx := columnIndex(header, "статус")
+	amountIdx := columnIndex(header, "сумма платежа")
+	if amountIdx < 0 {
+		amountIdx = columnIndex(header, "сумма операции")
+	}
+	categoryIdx := columnIndex(header, "категория")
+	mccIdx := columnIndex(header, "mcc")
+	descriptionIdx := columnIndex(header, "описание")
+
+	var transactions []Transaction
+	for _, record := range records {
+		// Пропускаем отклоненные операции
+		if field(record, statusIdx) != "OK" {
+			continue
+		}
+
+		amount, err := parseAmount(field(record, amountIdx))
+		if err != nil {
+			return nil, err
+		}
+		// Списания в выписке Тинькофф отрицательные
+		if amount >= 0 {
+			continue
+		}
+
+		date, err := parseDate(field(record, dateIdx), "02.01.2006 15:04:05", "02.01.2006")
+		if err != nil {
+			return nil, err
+		}
+
+		transactions = append(transactions, Transaction{
+			Date:         date,
+			Amount:       math.Abs(amount),
+			MCC:          parseMCC(field(record, mccIdx)),
+			Description:  field(record, descriptionIdx),
+			BankCategory: field(record, categoryIdx),
+		})
+	}
+
+	return transactions, nil
+}
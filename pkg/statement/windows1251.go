@@ -0,0 +1,31 @@
+package statement
+
+import "unicode/utf8"
+
+// Символы Windows-1251 в диапазоне 0x80-0xBF
+var windows1251High = [64]rune{
+	'Ђ', 'Ѓ', '‚', 'ѓ', '„', '…', '†', '‡', '€', '‰', 'Љ', '‹', 'Њ', 'Ќ', 'Ћ', 'Џ',
+	'ђ', '‘', '’', '“', '”', '•', '–', '—', '\ufffd', '™', 'љ', '›', 'њ', 'ќ', 'ћ', 'џ',
+	'\u00a0', 'Ў', 'ў', 'Ј', '¤', 'Ґ', '¦', '§', 'Ё', '©', 'Є', '«', '¬', '\u00ad', '®', 'Ї',
+	'°', '±', 'І', 'і', 'ґ', 'µ', '¶', '·', 'ё', '№', 'є', '»', 'ј', 'Ѕ', 'ѕ', 'ї',
+}
+
+// decodeWindows1251 перекодирует текст из Windows-1251 в UTF-8
+func decodeWindows1251(data []byte) []byte {
+	result := make([]byte, 0, len(data)*2)
+	for _, b := range data {
+		var r rune
+		switch {
+		case b < 0x80:
+			r = rune(b)
+		case b < 0xC0:
+			r = windows1251High[b-0x80]
+		default:
+			// А-я идут подряд начиная с 0xC0
+			r = 'А' + rune(b-0xC0)
+		}
+		result = utf8.AppendRune(result, r)
+	}
+
+	return result
+}